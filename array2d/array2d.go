@@ -2,8 +2,11 @@
 package array2d
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+
+	g "github.com/zyedidia/generic"
 )
 
 // New initializes a 2-dimensional array with all zero values.
@@ -64,6 +67,39 @@ func (a Array2D[T]) String() string {
 	return sb.String()
 }
 
+// MarshalJSON encodes the array as a JSON array of rows, where each row is a
+// JSON array of values. The width is only recorded by the length of the rows,
+// so an array with a height of zero encodes as [] whatever its width, and
+// decodes as a 0x0 array.
+func (a Array2D[T]) MarshalJSON() ([]byte, error) {
+	rows := make([][]T, a.height)
+	for y := range rows {
+		rows[y] = a.Row(y)
+	}
+	return json.Marshal(rows)
+}
+
+// UnmarshalJSON decodes a JSON array of rows, as produced by MarshalJSON, into
+// the array. The dimensions of the array are taken from the data. An error is
+// returned if the rows do not all have the same length.
+func (a *Array2D[T]) UnmarshalJSON(data []byte) error {
+	var rows [][]T
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return err
+	}
+	width := 0
+	if len(rows) > 0 {
+		width = len(rows[0])
+	}
+	for y, row := range rows {
+		if len(row) != width {
+			return fmt.Errorf("array2d: row %d has length %d, expected %d", y, len(row), width)
+		}
+	}
+	*a = OfJagged(width, len(rows), rows)
+	return nil
+}
+
 // Get returns a value from the array.
 //
 // The function will panic on out-of-bounds access.
@@ -77,8 +113,18 @@ func (a Array2D[T]) Get(x, y int) T {
 	return a.getUnchecked(x, y)
 }
 
+// TryGet returns a value from the array, or false if the coordinates are out
+// of bounds.
+func (a Array2D[T]) TryGet(x, y int) (T, bool) {
+	if !a.inBounds(x, y) {
+		var v T
+		return v, false
+	}
+	return a.getUnchecked(x, y), true
+}
+
 func (a Array2D[T]) getUnchecked(x, y int) T {
-	return a.slice[x+y*a.height]
+	return a.slice[x+y*a.width]
 }
 
 // Set sets a value in the array.
@@ -94,8 +140,43 @@ func (a Array2D[T]) Set(x, y int, value T) {
 	a.setUnchecked(x, y, value)
 }
 
+// TrySet sets a value in the array, or returns false and leaves the array
+// unchanged if the coordinates are out of bounds.
+func (a Array2D[T]) TrySet(x, y int, value T) bool {
+	if !a.inBounds(x, y) {
+		return false
+	}
+	a.setUnchecked(x, y, value)
+	return true
+}
+
 func (a Array2D[T]) setUnchecked(x, y int, value T) {
-	a.slice[x+y*a.height] = value
+	a.slice[x+y*a.width] = value
+}
+
+// GetWrap returns a value from the array, treating it as a torus: the
+// coordinates wrap around modulo the width and height, so that x = -1 is the
+// rightmost column and x = Width() is the leftmost.
+//
+// The function will panic if the array is empty.
+func (a Array2D[T]) GetWrap(x, y int) T {
+	x, y = a.wrap(x, y)
+	return a.getUnchecked(x, y)
+}
+
+// SetWrap sets a value in the array, wrapping the coordinates like GetWrap.
+//
+// The function will panic if the array is empty.
+func (a Array2D[T]) SetWrap(x, y int, value T) {
+	x, y = a.wrap(x, y)
+	a.setUnchecked(x, y, value)
+}
+
+func (a Array2D[T]) wrap(x, y int) (int, int) {
+	if a.width == 0 || a.height == 0 {
+		panic(fmt.Sprintf("array2d: wrapped access to empty array with width %d and height %d", a.width, a.height))
+	}
+	return g.WrapMod(x, a.width), g.WrapMod(y, a.height)
 }
 
 // Width returns the width of this array. The maximum x value is Width()-1.
@@ -134,7 +215,7 @@ func (a Array2D[T]) RowSpan(x1, x2, y int) []T {
 	if x2 < x1 {
 		x1, x2 = x2, x1
 	}
-	return a.slice[x1+y*a.height : 1+x2+y*a.height]
+	return a.slice[x1+y*a.width : 1+x2+y*a.width]
 }
 
 // Row returns a mutable slice for an entire row. Changing values in this slice
@@ -143,7 +224,29 @@ func (a Array2D[T]) Row(y int) []T {
 	if y < 0 || y >= a.height {
 		panic(fmt.Sprintf("array2d: y index out of range [%d] with height %d", y, a.height))
 	}
-	return a.slice[y*a.height : a.width+y*a.height]
+	return a.slice[y*a.width : a.width+y*a.width]
+}
+
+// TryRowSpan is like RowSpan, but returns false instead of panicking if any of
+// the coordinates are out of bounds.
+func (a Array2D[T]) TryRowSpan(x1, x2, y int) ([]T, bool) {
+	if !a.inBounds(x1, y) || !a.inBounds(x2, y) {
+		return nil, false
+	}
+	return a.RowSpan(x1, x2, y), true
+}
+
+// TryRow is like Row, but returns false instead of panicking if 'y' is out of
+// bounds.
+func (a Array2D[T]) TryRow(y int) ([]T, bool) {
+	if y < 0 || y >= a.height {
+		return nil, false
+	}
+	return a.Row(y), true
+}
+
+func (a Array2D[T]) inBounds(x, y int) bool {
+	return x >= 0 && x < a.width && y >= 0 && y < a.height
 }
 
 // Fill will assign all values inside the region to the specified value.
@@ -171,10 +274,10 @@ func (a Array2D[T]) Fill(x1, y1, x2, y2 int, value T) {
 	if y2 < y1 {
 		y1, y2 = y2, y1
 	}
-	firstRow := a.slice[x1+y1*a.height : 1+x2+y1*a.height]
+	firstRow := a.slice[x1+y1*a.width : 1+x2+y1*a.width]
 	fill(firstRow, value)
 	for y := y1 + 1; y <= y2; y++ {
-		copy(a.slice[x1+y*a.height:1+x2+y*a.height], firstRow)
+		copy(a.slice[x1+y*a.width:1+x2+y*a.width], firstRow)
 	}
 }
 
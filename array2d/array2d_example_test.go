@@ -69,3 +69,25 @@ func ExampleArray2D() {
 	// |       |   8   |   7 9 |
 	// +-------+-------+-------+
 }
+
+func ExampleArray2D_TryGet() {
+	arr := array2d.New[int](2, 2)
+	arr.Set(1, 1, 5)
+
+	// Get panics on out-of-bounds access, which suits code where such an
+	// access is a bug.
+	fmt.Println(arr.Get(1, 1))
+
+	// TryGet reports it instead, for coordinates that come from user input.
+	for _, x := range []int{1, 2} {
+		if v, ok := arr.TryGet(x, 1); ok {
+			fmt.Println(v)
+		} else {
+			fmt.Println("out of bounds:", x)
+		}
+	}
+	// Output:
+	// 5
+	// 5
+	// out of bounds: 2
+}
@@ -1,6 +1,7 @@
 package array2d
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -93,3 +94,209 @@ func assertLen[E any](t *testing.T, want int, slice []E) {
 		t.Errorf("want len %d, got len %d", want, len(slice))
 	}
 }
+
+func TestArray2D_nonSquare(t *testing.T) {
+	arr := New[int](3, 2)
+	arr.Set(2, 0, 1)
+	arr.Set(0, 1, 2)
+	got := arr.String()
+	want := "[[0 0 1] [2 0 0]]"
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestArray2D_rowStride(t *testing.T) {
+	for _, dims := range [][2]int{{5, 2}, {2, 5}} {
+		w, h := dims[0], dims[1]
+		arr := New[int](w, h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				arr.Set(x, y, 10*y+x)
+			}
+		}
+		for y := 0; y < h; y++ {
+			row := arr.Row(y)
+			assertLen(t, w, row)
+			for x, v := range row {
+				if v != 10*y+x || arr.Get(x, y) != v {
+					t.Errorf("%dx%d: x=%d, y=%d: want %d, got %d in row, %d from Get", w, h, x, y, 10*y+x, v, arr.Get(x, y))
+				}
+			}
+			if span := arr.RowSpan(1, w-1, y); span[0] != 10*y+1 || span[len(span)-1] != 10*y+w-1 {
+				t.Errorf("%dx%d: RowSpan(1, %d, %d) = %v", w, h, w-1, y, span)
+			}
+		}
+
+		arr.Fill(0, 1, w-1, h-1, -1)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				want := 10*y + x
+				if y >= 1 {
+					want = -1
+				}
+				if got := arr.Get(x, y); got != want {
+					t.Errorf("%dx%d after Fill: x=%d, y=%d: want %d, got %d", w, h, x, y, want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestArray2D_json(t *testing.T) {
+	arr := OfJagged(3, 2, [][]int{{1, 2, 3}, {4, 5, 6}})
+	data, err := json.Marshal(arr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[[1,2,3],[4,5,6]]"; string(data) != want {
+		t.Fatalf("want %s, got %s", want, data)
+	}
+
+	var decoded Array2D[int]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Width() != 3 || decoded.Height() != 2 {
+		t.Fatalf("want 3x2, got %dx%d", decoded.Width(), decoded.Height())
+	}
+	if decoded.String() != arr.String() {
+		t.Fatalf("want %s, got %s", arr, decoded)
+	}
+
+	var empty Array2D[int]
+	data, _ = json.Marshal(New[int](0, 2))
+	if err := json.Unmarshal(data, &empty); err != nil || empty.Width() != 0 || empty.Height() != 2 {
+		t.Fatalf("empty decode of %s: %v %dx%d", data, err, empty.Width(), empty.Height())
+	}
+
+	// A zero height leaves no rows to carry the width.
+	var flat Array2D[int]
+	data, _ = json.Marshal(New[int](3, 0))
+	if string(data) != "[]" {
+		t.Fatalf("want [], got %s", data)
+	}
+	if err := json.Unmarshal(data, &flat); err != nil || flat.Width() != 0 || flat.Height() != 0 {
+		t.Fatalf("decode of %s: %v %dx%d, want 0x0", data, err, flat.Width(), flat.Height())
+	}
+}
+
+func TestArray2D_jsonRagged(t *testing.T) {
+	for _, data := range []string{"[[1,2],[3]]", "[[1],[2,3]]", `[[1,"x"]]`} {
+		var arr Array2D[int]
+		if err := json.Unmarshal([]byte(data), &arr); err == nil {
+			t.Errorf("decoding %s should fail", data)
+		}
+	}
+}
+
+func TestArray2D_wrap(t *testing.T) {
+	arr := New[int](4, 3)
+	for x := 0; x < arr.Width(); x++ {
+		for y := 0; y < arr.Height(); y++ {
+			arr.Set(x, y, 10*x+y)
+		}
+	}
+	for x := 0; x < arr.Width(); x++ {
+		for y := 0; y < arr.Height(); y++ {
+			if got, want := arr.GetWrap(x, y), arr.Get(x, y); got != want {
+				t.Errorf("x=%d, y=%d: in bounds want %d, got %d", x, y, want, got)
+			}
+		}
+	}
+
+	tests := []struct {
+		x, y, wantX, wantY int
+	}{
+		{-1, 0, 3, 0},
+		{4, 0, 0, 0},
+		{0, -1, 0, 2},
+		{0, 3, 0, 0},
+		{-1, -1, 3, 2},
+		{9, -4, 1, 2},
+	}
+	for _, tt := range tests {
+		if got, want := arr.GetWrap(tt.x, tt.y), arr.Get(tt.wantX, tt.wantY); got != want {
+			t.Errorf("GetWrap(%d, %d): want %d, got %d", tt.x, tt.y, want, got)
+		}
+		arr.SetWrap(tt.x, tt.y, -1)
+		if got := arr.Get(tt.wantX, tt.wantY); got != -1 {
+			t.Errorf("SetWrap(%d, %d) did not set (%d, %d)", tt.x, tt.y, tt.wantX, tt.wantY)
+		}
+		arr.Set(tt.wantX, tt.wantY, 10*tt.wantX+tt.wantY)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("GetWrap on an empty array did not panic")
+		}
+	}()
+	New[int](0, 3).GetWrap(0, 0)
+}
+
+func TestArray2D_try(t *testing.T) {
+	arr := New[int](4, 3)
+	for x := 0; x < arr.Width(); x++ {
+		for y := 0; y < arr.Height(); y++ {
+			arr.Set(x, y, 10*x+y)
+		}
+	}
+
+	coords := []struct {
+		x, y int
+		ok   bool
+	}{
+		{0, 0, true},
+		{3, 2, true},
+		{-1, 0, false},
+		{4, 0, false},
+		{0, -1, false},
+		{0, 3, false},
+	}
+	for _, c := range coords {
+		v, ok := arr.TryGet(c.x, c.y)
+		if ok != c.ok || (ok && v != 10*c.x+c.y) || (!ok && v != 0) {
+			t.Errorf("TryGet(%d, %d) = %d, %v", c.x, c.y, v, ok)
+		}
+		if ok := arr.TrySet(c.x, c.y, -1); ok != c.ok {
+			t.Errorf("TrySet(%d, %d) = %v", c.x, c.y, ok)
+		} else if ok && arr.Get(c.x, c.y) != -1 {
+			t.Errorf("TrySet(%d, %d) did not set the value", c.x, c.y)
+		}
+	}
+
+	for y, ok := range map[int]bool{-1: false, 0: true, 2: true, 3: false} {
+		row, got := arr.TryRow(y)
+		if got != ok {
+			t.Errorf("TryRow(%d) ok = %v", y, got)
+		} else if ok {
+			assertLen(t, 4, row)
+		} else if row != nil {
+			t.Errorf("TryRow(%d) = %v", y, row)
+		}
+	}
+
+	spans := []struct {
+		x1, x2, y, len int
+	}{
+		{0, 3, 0, 4},
+		{3, 1, 2, 3},
+		{2, 2, 1, 1},
+		{-1, 2, 0, -1},
+		{0, 4, 0, -1},
+		{0, 3, 3, -1},
+		{0, 3, -1, -1},
+	}
+	for _, s := range spans {
+		span, ok := arr.TryRowSpan(s.x1, s.x2, s.y)
+		if ok != (s.len >= 0) {
+			t.Errorf("TryRowSpan(%d, %d, %d) ok = %v", s.x1, s.x2, s.y, ok)
+		} else if ok {
+			assertLen(t, s.len, span)
+		}
+	}
+
+	if _, ok := New[int](0, 0).TryGet(0, 0); ok {
+		t.Error("TryGet on an empty array returned true")
+	}
+}
@@ -0,0 +1,182 @@
+package avl
+
+import (
+	g "github.com/zyedidia/generic"
+)
+
+// A NodeView is a read-only view of a node of a Tree, for walking the
+// structure of the tree, for example to implement a search the tree does not
+// provide. A nil *NodeView stands for an empty subtree, and its methods
+// return zero values.
+type NodeView[K, V any] node[K, V]
+
+// Root returns a view of the root node of the tree, or nil if the tree is
+// empty. The view must not be used after the tree is modified.
+func (t *Tree[K, V]) Root() *NodeView[K, V] {
+	return (*NodeView[K, V])(t.root)
+}
+
+// Key returns the key stored in the node.
+func (v *NodeView[K, V]) Key() K {
+	if v == nil {
+		var k K
+		return k
+	}
+	return v.key
+}
+
+// Val returns the value stored in the node.
+func (v *NodeView[K, V]) Val() V {
+	if v == nil {
+		var val V
+		return val
+	}
+	return v.value
+}
+
+// Left returns the left child of the node, whose keys are all less than the
+// node's key, or nil if there is none.
+func (v *NodeView[K, V]) Left() *NodeView[K, V] {
+	if v == nil {
+		return nil
+	}
+	return (*NodeView[K, V])(v.left)
+}
+
+// Right returns the right child of the node, whose keys are all greater than
+// the node's key, or nil if there is none.
+func (v *NodeView[K, V]) Right() *NodeView[K, V] {
+	if v == nil {
+		return nil
+	}
+	return (*NodeView[K, V])(v.right)
+}
+
+// Height returns the height of the subtree rooted at the node, which is 1 for
+// a leaf and 0 for an empty subtree.
+func (v *NodeView[K, V]) Height() int {
+	return (*node[K, V])(v).getHeight()
+}
+
+// Size returns the number of nodes in the subtree rooted at the node.
+func (v *NodeView[K, V]) Size() int {
+	return (*node[K, V])(v).getSize()
+}
+
+type augValue[V, A any] struct {
+	val V
+	aug A
+}
+
+// Augmented is an AVL tree that also maintains a user-defined augmentation of
+// type A in each node, computed from the node's key and value and the
+// augmentations of its children. Augmentations such as subtree counts, sums
+// or maximums make it possible to build order-statistic trees, interval trees
+// and similar structures on top of the tree by walking it with Root.
+type Augmented[K, V, A any] struct {
+	t *Tree[K, augValue[V, A]]
+}
+
+// NewAugmented returns an empty augmented AVL tree. Whenever a node is added
+// or changed, or its children change because of a rotation, 'recompute' is
+// called on it, bottom-up, to set '*a' from the node's key and value and the
+// augmentations of its left and right children, which are nil if the node
+// has no such child. On entry '*a' holds the node's previous augmentation,
+// or the zero value for a new node or one whose value was replaced by Put, so
+// 'recompute' must overwrite it entirely.
+func NewAugmented[K, V, A any](less g.LessFn[K], recompute func(a *A, key K, val V, left, right *A)) *Augmented[K, V, A] {
+	t := New[K, augValue[V, A]](less)
+	t.augment = func(n *node[K, augValue[V, A]]) {
+		var left, right *A
+		if n.left != nil {
+			left = &n.left.value.aug
+		}
+		if n.right != nil {
+			right = &n.right.value.aug
+		}
+		recompute(&n.value.aug, n.key, n.value.val, left, right)
+	}
+	return &Augmented[K, V, A]{t}
+}
+
+// Put associates 'key' with 'value'.
+func (t *Augmented[K, V, A]) Put(key K, value V) {
+	t.t.Put(key, augValue[V, A]{val: value})
+}
+
+// Remove removes the value associated with 'key'.
+func (t *Augmented[K, V, A]) Remove(key K) {
+	t.t.Remove(key)
+}
+
+// Get returns the value associated with 'key'.
+func (t *Augmented[K, V, A]) Get(key K) (V, bool) {
+	v, ok := t.t.Get(key)
+	return v.val, ok
+}
+
+// Each calls 'fn' on every node in the tree in order.
+func (t *Augmented[K, V, A]) Each(fn func(key K, val V)) {
+	t.t.Each(func(key K, v augValue[V, A]) {
+		fn(key, v.val)
+	})
+}
+
+// Height returns the height of the tree.
+func (t *Augmented[K, V, A]) Height() int {
+	return t.t.Height()
+}
+
+// Size returns the number of elements in the tree.
+func (t *Augmented[K, V, A]) Size() int {
+	return t.t.Size()
+}
+
+// Root returns a view of the root node of the tree, or nil if the tree is
+// empty. The view must not be used after the tree is modified.
+func (t *Augmented[K, V, A]) Root() *AugmentedNode[K, V, A] {
+	return (*AugmentedNode[K, V, A])(t.t.root)
+}
+
+// An AugmentedNode is a read-only view of a node of an Augmented tree. Like a
+// NodeView, a nil *AugmentedNode stands for an empty subtree.
+type AugmentedNode[K, V, A any] node[K, augValue[V, A]]
+
+func (v *AugmentedNode[K, V, A]) view() *NodeView[K, augValue[V, A]] {
+	return (*NodeView[K, augValue[V, A]])(v)
+}
+
+// Key returns the key stored in the node.
+func (v *AugmentedNode[K, V, A]) Key() K {
+	return v.view().Key()
+}
+
+// Val returns the value stored in the node.
+func (v *AugmentedNode[K, V, A]) Val() V {
+	return v.view().Val().val
+}
+
+// Aug returns the augmentation of the node.
+func (v *AugmentedNode[K, V, A]) Aug() A {
+	return v.view().Val().aug
+}
+
+// Left returns the left child of the node, or nil if there is none.
+func (v *AugmentedNode[K, V, A]) Left() *AugmentedNode[K, V, A] {
+	return (*AugmentedNode[K, V, A])(v.view().Left())
+}
+
+// Right returns the right child of the node, or nil if there is none.
+func (v *AugmentedNode[K, V, A]) Right() *AugmentedNode[K, V, A] {
+	return (*AugmentedNode[K, V, A])(v.view().Right())
+}
+
+// Height returns the height of the subtree rooted at the node.
+func (v *AugmentedNode[K, V, A]) Height() int {
+	return v.view().Height()
+}
+
+// Size returns the number of nodes in the subtree rooted at the node.
+func (v *AugmentedNode[K, V, A]) Size() int {
+	return v.view().Size()
+}
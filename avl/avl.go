@@ -5,13 +5,36 @@
 package avl
 
 import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"unsafe"
+
 	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/dump"
+	"golang.org/x/exp/slices"
 )
 
 // Tree implements an AVL tree.
 type Tree[K, V any] struct {
 	root *node[K, V]
 	less g.LessFn[K]
+
+	frozen bool
+	// gen identifies the nodes owned by this tree, which may be modified in
+	// place. Nodes with a different gen may be shared with a snapshot, and
+	// are copied before being modified.
+	gen uint64
+	// augment recomputes the augmentation of a node from its children, for
+	// trees made by NewAugmented. It is nil otherwise.
+	augment func(n *node[K, V])
+}
+
+// lastGen is the last generation handed out by nextGen.
+var lastGen uint64
+
+func nextGen() uint64 {
+	return atomic.AddUint64(&lastGen, 1)
 }
 
 // New returns an empty AVL tree.
@@ -21,14 +44,90 @@ func New[K, V any](less g.LessFn[K]) *Tree[K, V] {
 	}
 }
 
+// NewOrdered returns an empty AVL tree ordered by the keys' CompareTo method.
+func NewOrdered[K g.Comparable[K], V any]() *Tree[K, V] {
+	return New[K, V](g.LessComparable[K])
+}
+
+// FromPairs returns a tree containing the given key-value pairs. If a key
+// appears more than once, its last value is kept. If the keys are already in
+// increasing order the tree is built directly in O(n) time, and otherwise the
+// pairs are sorted first, in O(n lg n) time; 'pairs' is not modified.
+func FromPairs[K, V any](pairs []g.KV[K, V], less g.LessFn[K]) *Tree[K, V] {
+	keys, vals := g.Unzip(pairs)
+	if !increasing(keys, less) {
+		keys, vals = g.Unzip(sortPairs(slices.Clone(pairs), less))
+	}
+	return fromSorted(keys, vals, less)
+}
+
+// FromSlices is like FromPairs, but takes the keys and values as parallel
+// slices, mapping keys[i] to vals[i]. It panics if they have different
+// lengths.
+func FromSlices[K, V any](keys []K, vals []V, less g.LessFn[K]) *Tree[K, V] {
+	if len(keys) != len(vals) {
+		panic("avl: keys and values have different lengths")
+	}
+	if !increasing(keys, less) {
+		keys, vals = g.Unzip(sortPairs(g.Zip(keys, vals), less))
+	}
+	return fromSorted(keys, vals, less)
+}
+
 // Put associates 'key' with 'value'.
 func (t *Tree[K, V]) Put(key K, value V) {
-	t.root = t.root.add(key, value, t.less)
+	t.checkFrozen("Put")
+	t.root = t.root.add(key, value, t.less, t.gen, t.augment)
 }
 
 // Remove removes the value associated with 'key'.
 func (t *Tree[K, V]) Remove(key K) {
-	t.root = t.root.remove(key, t.less)
+	t.checkFrozen("Remove")
+	t.root = t.root.remove(key, t.less, t.gen, t.augment)
+}
+
+// Freeze makes the tree immutable: any later call to a method that would
+// modify it panics. Reads do not modify the tree, so a frozen tree may
+// be read from multiple goroutines concurrently.
+func (t *Tree[K, V]) Freeze() {
+	t.frozen = true
+}
+
+// Frozen returns true if Freeze has been called on the tree.
+func (t *Tree[K, V]) Frozen() bool {
+	return t.frozen
+}
+
+func (t *Tree[K, V]) checkFrozen(op string) {
+	if t.frozen {
+		panic("avl: " + op + " called on a frozen avl.Tree")
+	}
+}
+
+// Snapshot returns a frozen copy of the tree that shares all of its nodes
+// with the tree, in O(1) time. Later writes to the tree copy the nodes along
+// the path they modify instead of modifying shared nodes, so the snapshot
+// keeps its contents, and may be read from other goroutines while the tree
+// continues to be written. On a tree that is not frozen, Snapshot counts as a
+// write, since the tree gives up ownership of its nodes; taking a snapshot of
+// a frozen tree does not change it.
+func (t *Tree[K, V]) Snapshot() *Tree[K, V] {
+	t.share()
+	return &Tree[K, V]{
+		root:    t.root,
+		less:    t.less,
+		frozen:  true,
+		augment: t.augment,
+	}
+}
+
+// share makes 't' give up ownership of its nodes, so that it copies them
+// before modifying them. A frozen tree is never modified, so it is left as is,
+// which keeps it safe to read from several goroutines.
+func (t *Tree[K, V]) share() {
+	if !t.frozen {
+		t.gen = nextGen()
+	}
 }
 
 // Get returns the value associated with 'key'.
@@ -46,6 +145,19 @@ func (t *Tree[K, V]) Each(fn func(key K, val V)) {
 	t.root.each(fn)
 }
 
+// EachUntil calls 'fn' on every node in the tree in order, until 'fn' returns
+// false.
+func (t *Tree[K, V]) EachUntil(fn func(key K, val V) bool) {
+	t.root.eachUntil(fn)
+}
+
+// DumpNDJSON writes every key-value pair in the tree to 'w' in order, as a JSON
+// object per line. At most 'limit' pairs are written; if 'limit' is zero or
+// negative all pairs are written.
+func (t *Tree[K, V]) DumpNDJSON(w io.Writer, limit int) error {
+	return dump.DumpMap(w, t.EachUntil, dump.JSONPair[K, V], limit)
+}
+
 // Height returns the height of the tree.
 func (t *Tree[K, V]) Height() int {
 	return t.root.getHeight()
@@ -53,7 +165,133 @@ func (t *Tree[K, V]) Height() int {
 
 // Size returns the number of elements in the tree.
 func (t *Tree[K, V]) Size() int {
-	return t.root.size()
+	return t.root.getSize()
+}
+
+// Memory returns an estimate of the number of bytes used by the tree and its
+// nodes. Memory referenced by pointers in the keys or values is not counted;
+// use MemoryFunc to include it.
+func (t *Tree[K, V]) Memory() int64 {
+	return int64(unsafe.Sizeof(*t)) + int64(t.Size())*int64(unsafe.Sizeof(node[K, V]{}))
+}
+
+// MemoryFunc is like Memory, but also adds 'sizeOf' for every key-value pair,
+// to account for memory referenced by the keys and values.
+func (t *Tree[K, V]) MemoryFunc(sizeOf func(key K, val V) int64) int64 {
+	total := t.Memory()
+	t.Each(func(key K, val V) {
+		total += sizeOf(key, val)
+	})
+	return total
+}
+
+// CountRange returns the number of keys in the range [lo, hi). If 'lo' is not
+// less than 'hi', the range is empty and 0 is returned. Complexity: O(lg n).
+func (t *Tree[K, V]) CountRange(lo, hi K) int {
+	if !t.less(lo, hi) {
+		return 0
+	}
+	return t.root.rank(hi, t.less) - t.root.rank(lo, t.less)
+}
+
+// CountLess returns the number of keys strictly less than 'key'. Complexity:
+// O(lg n).
+func (t *Tree[K, V]) CountLess(key K) int {
+	return t.root.rank(key, t.less)
+}
+
+// CountAtLeast returns the number of keys greater than or equal to 'key'.
+// Complexity: O(lg n).
+func (t *Tree[K, V]) CountAtLeast(key K) int {
+	return t.root.getSize() - t.root.rank(key, t.less)
+}
+
+// EachRange calls 'fn' on every key in the range [lo, hi) in order, along
+// with its value. Complexity: O(lg n + m), where m is the number of keys in
+// the range.
+func (t *Tree[K, V]) EachRange(lo, hi K, fn func(key K, val V)) {
+	t.root.eachRange(lo, hi, t.less, fn)
+}
+
+// Min returns the smallest key in the tree and its value, or false if the
+// tree is empty.
+func (t *Tree[K, V]) Min() (K, V, bool) {
+	n := t.root
+	if n == nil {
+		var k K
+		var v V
+		return k, v, false
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return n.key, n.value, true
+}
+
+// Max returns the largest key in the tree and its value, or false if the tree
+// is empty.
+func (t *Tree[K, V]) Max() (K, V, bool) {
+	n := t.root
+	if n == nil {
+		var k K
+		var v V
+		return k, v, false
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return n.key, n.value, true
+}
+
+// Split returns two trees: one with the keys of 't' that are less than 'key',
+// and one with the rest. The trees share their nodes with 't', which keeps its
+// contents. As with Snapshot, Split counts as a write to 't' unless it is
+// frozen. Complexity: O(lg n).
+func (t *Tree[K, V]) Split(key K) (*Tree[K, V], *Tree[K, V]) {
+	t.share()
+	// The two halves have no node in common, so they can share a generation.
+	gen := nextGen()
+	l, r := t.root.split(key, t.less, gen, t.augment)
+	return &Tree[K, V]{root: l, less: t.less, gen: gen, augment: t.augment},
+		&Tree[K, V]{root: r, less: t.less, gen: gen, augment: t.augment}
+}
+
+// ErrOverlap is returned by Join if the keys of the two trees interleave.
+var ErrOverlap = errors.New("avl: the keys of the trees to join overlap")
+
+// Join adds the keys of 'other' to 't', where either all the keys of 'other'
+// are greater than those of 't', or all of them are less. Otherwise it
+// returns ErrOverlap, and neither tree is changed. The trees share the nodes of
+// 'other' afterward, but either may be modified without affecting the other.
+// Both trees must be ordered by the same less function. Complexity: O(lg n).
+func (t *Tree[K, V]) Join(other *Tree[K, V]) error {
+	t.checkFrozen("Join")
+	l, r := t.root, other.root
+	if l != nil && r != nil && !t.less(l.findLargest().key, r.findSmallest().key) {
+		if !t.less(r.findLargest().key, l.findSmallest().key) {
+			return ErrOverlap
+		}
+		l, r = r, l
+	}
+	// neither tree may modify the nodes they now share in place
+	t.gen = nextGen()
+	other.share()
+	t.root = join2(l, r, t.gen, t.augment)
+	return nil
+}
+
+// RemoveRange removes the keys in the range [lo, hi), and returns how many
+// were removed. Rather than removing the keys one at a time, it splits the
+// tree at 'lo' and 'hi' and joins the outer parts. Complexity: O(lg n).
+func (t *Tree[K, V]) RemoveRange(lo, hi K) int {
+	t.checkFrozen("RemoveRange")
+	if !t.less(lo, hi) {
+		return 0
+	}
+	l, r := t.root.split(lo, t.less, t.gen, t.augment)
+	m, r := r.split(hi, t.less, t.gen, t.augment)
+	t.root = join2(l, r, t.gen, t.augment)
+	return m.getSize()
 }
 
 type node[K, V any] struct {
@@ -63,43 +301,67 @@ type node[K, V any] struct {
 	height int
 	left   *node[K, V]
 	right  *node[K, V]
+
+	// size is the number of nodes in the subtree rooted at this node.
+	size int
+
+	gen uint64
+}
+
+// mut returns a node that can be modified by the tree with generation 'gen':
+// 'n' itself if the tree owns it, or a copy of it otherwise.
+func (n *node[K, V]) mut(gen uint64) *node[K, V] {
+	if n.gen == gen {
+		return n
+	}
+	c := *n
+	c.gen = gen
+	return &c
 }
 
-func (n *node[K, V]) add(key K, value V, less g.LessFn[K]) *node[K, V] {
+func (n *node[K, V]) add(key K, value V, less g.LessFn[K], gen uint64, aug func(n *node[K, V])) *node[K, V] {
 	if n == nil {
-		return &node[K, V]{
+		n = &node[K, V]{
 			key:    key,
 			value:  value,
 			height: 1,
+			size:   1,
 			left:   nil,
 			right:  nil,
+			gen:    gen,
 		}
+		if aug != nil {
+			aug(n)
+		}
+		return n
 	}
 
+	n = n.mut(gen)
 	if g.Compare(key, n.key, less) < 0 {
-		n.left = n.left.add(key, value, less)
+		n.left = n.left.add(key, value, less, gen, aug)
 	} else if g.Compare(key, n.key, less) > 0 {
-		n.right = n.right.add(key, value, less)
+		n.right = n.right.add(key, value, less, gen, aug)
 	} else {
 		n.value = value
 	}
-	return n.rebalanceTree()
+	return n.rebalanceTree(gen, aug)
 }
 
-func (n *node[K, V]) remove(key K, less g.LessFn[K]) *node[K, V] {
+func (n *node[K, V]) remove(key K, less g.LessFn[K], gen uint64, aug func(n *node[K, V])) *node[K, V] {
 	if n == nil {
 		return nil
 	}
+	n = n.mut(gen)
 	if g.Compare(key, n.key, less) < 0 {
-		n.left = n.left.remove(key, less)
+		n.left = n.left.remove(key, less, gen, aug)
 	} else if g.Compare(key, n.key, less) > 0 {
-		n.right = n.right.remove(key, less)
+		n.right = n.right.remove(key, less, gen, aug)
 	} else {
 		if n.left != nil && n.right != nil {
 			rightMinNode := n.right.findSmallest()
 			n.key = rightMinNode.key
 			n.value = rightMinNode.value
-			n.right = n.right.remove(rightMinNode.key, less)
+			n.right = n.right.remove(rightMinNode.key, less, gen, aug)
 		} else if n.left != nil {
 			n = n.left
 		} else if n.right != nil {
@@ -110,7 +372,7 @@ func (n *node[K, V]) remove(key K, less g.LessFn[K]) *node[K, V] {
 		}
 
 	}
-	return n.rebalanceTree()
+	return n.rebalanceTree(gen, aug)
 }
 
 func (n *node[K, V]) search(key K, less g.LessFn[K]) *node[K, V] {
@@ -135,6 +397,30 @@ func (n *node[K, V]) each(fn func(key K, val V)) {
 	n.right.each(fn)
 }
 
+func (n *node[K, V]) eachRange(lo, hi K, less g.LessFn[K], fn func(key K, val V)) {
+	if n == nil {
+		return
+	}
+	// only descend into subtrees that may hold keys in the range
+	inLo, inHi := !less(n.key, lo), less(n.key, hi)
+	if inLo {
+		n.left.eachRange(lo, hi, less, fn)
+	}
+	if inLo && inHi {
+		fn(n.key, n.value)
+	}
+	if inHi {
+		n.right.eachRange(lo, hi, less, fn)
+	}
+}
+
+func (n *node[K, V]) eachUntil(fn func(key K, val V) bool) bool {
+	if n == nil {
+		return true
+	}
+	return n.left.eachUntil(fn) && fn(n.key, n.value) && n.right.eachUntil(fn)
+}
+
 func (n *node[K, V]) getHeight() int {
 	if n == nil {
 		return 0
@@ -146,44 +432,82 @@ func (n *node[K, V]) recalculateHeight() {
 	n.height = 1 + g.Max(n.left.getHeight(), n.right.getHeight())
 }
 
-func (n *node[K, V]) rebalanceTree() *node[K, V] {
+func (n *node[K, V]) getSize() int {
 	if n == nil {
-		return n
+		return 0
 	}
+	return n.size
+}
+
+func (n *node[K, V]) updateSize() {
+	n.size = 1 + n.left.getSize() + n.right.getSize()
+}
+
+// update recalculates the height and size of 'n' from its children, and its
+// augmentation if 'aug' is not nil.
+func (n *node[K, V]) update(aug func(n *node[K, V])) {
 	n.recalculateHeight()
+	n.updateSize()
+	if aug != nil {
+		aug(n)
+	}
+}
+
+// rank returns the number of keys in the subtree that are less than 'key'.
+func (n *node[K, V]) rank(key K, less g.LessFn[K]) int {
+	r := 0
+	for n != nil {
+		if less(n.key, key) {
+			r += n.left.getSize() + 1
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return r
+}
+
+func (n *node[K, V]) rebalanceTree(gen uint64, aug func(n *node[K, V])) *node[K, V] {
+	if n == nil {
+		return n
+	}
+	n = n.mut(gen)
+	n.update(aug)
 
 	balanceFactor := n.left.getHeight() - n.right.getHeight()
 	if balanceFactor <= -2 {
 		if n.right.left.getHeight() > n.right.right.getHeight() {
-			n.right = n.right.rotateRight()
+			n.right = n.right.rotateRight(gen, aug)
 		}
-		return n.rotateLeft()
+		return n.rotateLeft(gen, aug)
 	} else if balanceFactor >= 2 {
 		if n.left.right.getHeight() > n.left.left.getHeight() {
-			n.left = n.left.rotateLeft()
+			n.left = n.left.rotateLeft(gen, aug)
 		}
-		return n.rotateRight()
+		return n.rotateRight(gen, aug)
 	}
 	return n
 }
 
-func (n *node[K, V]) rotateLeft() *node[K, V] {
-	newRoot := n.right
+func (n *node[K, V]) rotateLeft(gen uint64, aug func(n *node[K, V])) *node[K, V] {
+	n = n.mut(gen)
+	newRoot := n.right.mut(gen)
 	n.right = newRoot.left
 	newRoot.left = n
 
-	n.recalculateHeight()
-	newRoot.recalculateHeight()
+	n.update(aug)
+	newRoot.update(aug)
 	return newRoot
 }
 
-func (n *node[K, V]) rotateRight() *node[K, V] {
-	newRoot := n.left
+func (n *node[K, V]) rotateRight(gen uint64, aug func(n *node[K, V])) *node[K, V] {
+	n = n.mut(gen)
+	newRoot := n.left.mut(gen)
 	n.left = newRoot.right
 	newRoot.right = n
 
-	n.recalculateHeight()
-	newRoot.recalculateHeight()
+	n.update(aug)
+	newRoot.update(aug)
 	return newRoot
 }
 
@@ -195,9 +519,207 @@ func (n *node[K, V]) findSmallest() *node[K, V] {
 	}
 }
 
-func (n *node[K, V]) size() int {
+func (n *node[K, V]) findLargest() *node[K, V] {
+	for n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// split returns the subtrees of the keys in 'n' that are less than 'key', and
+// of the rest.
+func (n *node[K, V]) split(key K, less g.LessFn[K], gen uint64, aug func(n *node[K, V])) (*node[K, V], *node[K, V]) {
 	if n == nil {
-		return 0
+		return nil, nil
+	}
+	if less(n.key, key) {
+		l, r := n.right.split(key, less, gen, aug)
+		return join(n.left, n, l, gen, aug), r
+	}
+	l, r := n.left.split(key, less, gen, aug)
+	return l, join(r, n, n.right, gen, aug)
+}
+
+// join returns a balanced tree of the keys in 'l', the key of 'm', and the
+// keys in 'r', which must be in increasing order. The children of 'm' are
+// ignored. It descends the taller tree to a subtree of about the height of
+// the other, so the complexity is O(|height(l) - height(r)|).
+func join[K, V any](l, m, r *node[K, V], gen uint64, aug func(n *node[K, V])) *node[K, V] {
+	hl, hr := l.getHeight(), r.getHeight()
+	if hl > hr+1 {
+		l = l.mut(gen)
+		l.right = join(l.right, m, r, gen, aug)
+		return l.rebalanceTree(gen, aug)
+	}
+	if hr > hl+1 {
+		r = r.mut(gen)
+		r.left = join(l, m, r.left, gen, aug)
+		return r.rebalanceTree(gen, aug)
 	}
-	return 1 + n.left.size() + n.right.size()
+	m = m.mut(gen)
+	m.left, m.right = l, r
+	m.update(aug)
+	return m
+}
+
+// join2 is like join without a middle key: it uses the smallest key of 'r'.
+func join2[K, V any](l, r *node[K, V], gen uint64, aug func(n *node[K, V])) *node[K, V] {
+	if l == nil {
+		return r
+	} else if r == nil {
+		return l
+	}
+	r, m := r.removeMin(gen, aug)
+	return join(l, m, r, gen, aug)
+}
+
+// removeMin removes the smallest key from the subtree, and returns the new
+// subtree and the node of the removed key.
+func (n *node[K, V]) removeMin(gen uint64, aug func(n *node[K, V])) (*node[K, V], *node[K, V]) {
+	if n.left == nil {
+		return n.right, n
+	}
+	n = n.mut(gen)
+	var m *node[K, V]
+	n.left, m = n.left.removeMin(gen, aug)
+	return n.rebalanceTree(gen, aug), m
+}
+
+// Intersect returns a new tree containing the keys of 'a' that are also in
+// 'b', with their values from 'a'. Both trees must be ordered by 'less'. The
+// trees are merged in a single in-order pass, so the complexity is O(n + m).
+func Intersect[K, V, W any](a *Tree[K, V], b *Tree[K, W], less g.LessFn[K]) *Tree[K, V] {
+	ak, av := a.sorted()
+	bk, _ := b.sorted()
+	var keys []K
+	var vals []V
+	for i, j := 0, 0; i < len(ak) && j < len(bk); {
+		switch {
+		case less(ak[i], bk[j]):
+			i++
+		case less(bk[j], ak[i]):
+			j++
+		default:
+			keys = append(keys, ak[i])
+			vals = append(vals, av[i])
+			i++
+			j++
+		}
+	}
+	return fromSorted(keys, vals, less)
+}
+
+// Subtract returns a new tree containing the keys of 'a' that are not in 'b',
+// with their values from 'a'. Both trees must be ordered by 'less'. The trees
+// are merged in a single in-order pass, so the complexity is O(n + m).
+func Subtract[K, V, W any](a *Tree[K, V], b *Tree[K, W], less g.LessFn[K]) *Tree[K, V] {
+	ak, av := a.sorted()
+	bk, _ := b.sorted()
+	var keys []K
+	var vals []V
+	j := 0
+	for i := range ak {
+		for j < len(bk) && less(bk[j], ak[i]) {
+			j++
+		}
+		if j < len(bk) && !less(ak[i], bk[j]) {
+			continue
+		}
+		keys = append(keys, ak[i])
+		vals = append(vals, av[i])
+	}
+	return fromSorted(keys, vals, less)
+}
+
+// Merge returns a new tree containing the keys of both 'a' and 'b'. If a key
+// is in both trees, its value is 'combine' called with the key and the values
+// from 'a' and 'b'. Both trees must be ordered by the less function of 'a'.
+// The trees are merged in a single in-order pass, so the complexity is
+// O(n + m).
+func Merge[K, V any](a, b *Tree[K, V], combine func(key K, va, vb V) V) *Tree[K, V] {
+	less := a.less
+	ak, av := a.sorted()
+	bk, bv := b.sorted()
+	keys := make([]K, 0, len(ak)+len(bk))
+	vals := make([]V, 0, len(ak)+len(bk))
+	i, j := 0, 0
+	for i < len(ak) && j < len(bk) {
+		switch {
+		case less(ak[i], bk[j]):
+			keys, vals = append(keys, ak[i]), append(vals, av[i])
+			i++
+		case less(bk[j], ak[i]):
+			keys, vals = append(keys, bk[j]), append(vals, bv[j])
+			j++
+		default:
+			keys, vals = append(keys, ak[i]), append(vals, combine(ak[i], av[i], bv[j]))
+			i++
+			j++
+		}
+	}
+	keys, vals = append(keys, ak[i:]...), append(vals, av[i:]...)
+	keys, vals = append(keys, bk[j:]...), append(vals, bv[j:]...)
+	return fromSorted(keys, vals, less)
+}
+
+// sorted returns the keys and values of the tree in order.
+func (t *Tree[K, V]) sorted() ([]K, []V) {
+	keys := make([]K, 0, t.Size())
+	vals := make([]V, 0, t.Size())
+	t.Each(func(key K, val V) {
+		keys = append(keys, key)
+		vals = append(vals, val)
+	})
+	return keys, vals
+}
+
+// fromSorted builds a balanced tree from keys in strictly increasing order, in
+// O(n) time.
+func fromSorted[K, V any](keys []K, vals []V, less g.LessFn[K]) *Tree[K, V] {
+	return &Tree[K, V]{
+		root: build(keys, vals),
+		less: less,
+	}
+}
+
+// increasing returns whether 'keys' are in strictly increasing order.
+func increasing[K any](keys []K, less g.LessFn[K]) bool {
+	for i := 1; i < len(keys); i++ {
+		if !less(keys[i-1], keys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortPairs sorts 'pairs' by key in place and removes pairs with duplicate
+// keys, keeping the last of each, and returns the result.
+func sortPairs[K, V any](pairs []g.KV[K, V], less g.LessFn[K]) []g.KV[K, V] {
+	slices.SortStableFunc(pairs, func(a, b g.KV[K, V]) bool {
+		return less(a.Key, b.Key)
+	})
+	out := pairs[:0]
+	for i, p := range pairs {
+		if i+1 < len(pairs) && !less(p.Key, pairs[i+1].Key) {
+			// a later pair has the same key
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func build[K, V any](keys []K, vals []V) *node[K, V] {
+	if len(keys) == 0 {
+		return nil
+	}
+	mid := len(keys) / 2
+	n := &node[K, V]{
+		key:   keys[mid],
+		value: vals[mid],
+		left:  build(keys[:mid], vals[:mid]),
+		right: build(keys[mid+1:], vals[mid+1:]),
+	}
+	n.update(nil)
+	return n
 }
@@ -2,11 +2,17 @@ package avl_test
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"unsafe"
 
 	g "github.com/zyedidia/generic"
 	"github.com/zyedidia/generic/avl"
+	"github.com/zyedidia/generic/internal/testutil"
 )
 
 func checkeq[K any, V comparable](cm *avl.Tree[K, V], n int, get func(k K) (V, bool), t *testing.T) {
@@ -55,6 +61,335 @@ func TestCrossCheck(t *testing.T) {
 	}
 }
 
+func countRange(tree *avl.Tree[int, int], lo, hi int) int {
+	n := 0
+	tree.Each(func(key, val int) {
+		if key >= lo && key < hi {
+			n++
+		}
+	})
+	return n
+}
+
+func TestCountRange(t *testing.T) {
+	tree := avl.New[int, int](g.Less[int])
+
+	const nops = 1000
+	for i := 0; i < nops; i++ {
+		key := rand.Intn(500)
+		if rand.Intn(3) == 0 {
+			tree.Remove(key)
+		} else {
+			tree.Put(key, i)
+		}
+
+		lo, hi := rand.Intn(520)-10, rand.Intn(520)-10
+		if got, want := tree.CountRange(lo, hi), countRange(tree, lo, hi); got != want {
+			t.Fatalf("CountRange(%d, %d) = %d, expected %d", lo, hi, got, want)
+		}
+		if got, want := tree.CountLess(lo), countRange(tree, -1, lo); got != want {
+			t.Fatalf("CountLess(%d) = %d, expected %d", lo, got, want)
+		}
+		if got, want := tree.CountAtLeast(hi), countRange(tree, hi, 500); got != want {
+			t.Fatalf("CountAtLeast(%d) = %d, expected %d", hi, got, want)
+		}
+		if got, want := tree.CountRange(-1, 500), tree.Size(); got != want {
+			t.Fatalf("CountRange over all keys = %d, expected %d", got, want)
+		}
+	}
+}
+
+func BenchmarkCountRange(b *testing.B) {
+	tree := avl.New[int, int](g.Less[int])
+	const n = 1000000
+	for i := 0; i < n; i++ {
+		tree.Put(i, i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lo := rand.Intn(n)
+		tree.CountRange(lo, lo+n/2)
+	}
+}
+
+type version struct {
+	major, minor int
+}
+
+func (v version) CompareTo(o version) int {
+	if v.major != o.major {
+		return v.major - o.major
+	}
+	return v.minor - o.minor
+}
+
+func TestNewOrdered(t *testing.T) {
+	tree := avl.NewOrdered[version, string]()
+	tree.Put(version{1, 10}, "1.10")
+	tree.Put(version{2, 0}, "2.0")
+	tree.Put(version{1, 2}, "1.2")
+	tree.Put(version{1, 2}, "1.2 again")
+
+	var got []string
+	tree.Each(func(key version, val string) {
+		got = append(got, val)
+	})
+	if fmt.Sprint(got) != "[1.2 again 1.10 2.0]" {
+		t.Fatalf("wrong order: %v", got)
+	}
+	if v, ok := tree.Get(version{1, 10}); !ok || v != "1.10" {
+		t.Fatalf("Get(1.10) = %q, %v", v, ok)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	tree := avl.New[int, int](g.Less[int])
+	for i := 0; i < 100; i++ {
+		tree.Put(i, i)
+	}
+	tree.Freeze()
+	if !tree.Frozen() {
+		t.Fatal("tree should be frozen")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				if v, ok := tree.Get(k); !ok || v != k {
+					t.Errorf("Get(%d) = %d, %v", k, v, ok)
+				}
+			}
+			if n := tree.CountRange(10, 20); n != 10 {
+				t.Errorf("CountRange = %d", n)
+			}
+			tree.Each(func(key, val int) {})
+		}()
+	}
+	wg.Wait()
+
+	testutil.MustPanic(t, "avl.Tree", func() { tree.Put(1, 2) })
+	testutil.MustPanic(t, "avl.Tree", func() { tree.Remove(1) })
+}
+
+func buildTree(n int) *avl.Tree[int, int] {
+	tree := avl.New[int, int](g.Less[int])
+	for i := 0; i < n; i++ {
+		tree.Put(i, i)
+	}
+	return tree
+}
+
+func TestMemory(t *testing.T) {
+	for _, n := range []int{0, 1, 1000, 100000} {
+		tree := buildTree(n)
+		want := int64(unsafe.Sizeof(*tree)) + int64(n)*avl.NodeSize[int, int]()
+		if got := tree.Memory(); got != want {
+			t.Fatalf("Memory() = %d for %d keys, want %d", got, n, want)
+		}
+	}
+	tree := buildTree(100)
+	tree.RemoveRange(0, 50)
+	if got, want := tree.Memory(), int64(unsafe.Sizeof(*tree))+50*avl.NodeSize[int, int](); got != want {
+		t.Fatalf("Memory() = %d after removals, want %d", got, want)
+	}
+}
+
+func BenchmarkMemory(b *testing.B) {
+	testutil.ReportMemory(b, func() *avl.Tree[int, int] {
+		return buildTree(100000)
+	}, (*avl.Tree[int, int]).Memory)
+}
+
+func contents(tree *avl.Tree[int, int]) string {
+	var sb strings.Builder
+	tree.Each(func(key, val int) {
+		fmt.Fprintf(&sb, "%d:%d ", key, val)
+	})
+	return sb.String()
+}
+
+func TestSnapshot(t *testing.T) {
+	tree := avl.New[int, int](g.Less[int])
+	ref := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		k := rand.Intn(2000)
+		tree.Put(k, i)
+		ref[k] = i
+	}
+
+	snap := tree.Snapshot()
+	want := contents(snap)
+	wantSize := snap.Size()
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if got := contents(snap); got != want {
+					t.Errorf("snapshot changed while the tree was written")
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 5000; i++ {
+		k := rand.Intn(2000)
+		if rand.Intn(2) == 0 {
+			tree.Put(k, -i)
+			ref[k] = -i
+		} else {
+			tree.Remove(k)
+			delete(ref, k)
+		}
+		if i == 2500 {
+			// a second snapshot does not disturb the first
+			tree.Snapshot()
+		}
+	}
+	wg.Wait()
+
+	if got := contents(snap); got != want || snap.Size() != wantSize {
+		t.Fatal("snapshot changed after the tree was written")
+	}
+	checkeq(tree, len(ref), func(k int) (int, bool) {
+		v, ok := ref[k]
+		return v, ok
+	}, t)
+	testutil.MustPanic(t, "avl.Tree", func() { snap.Put(1, 1) })
+}
+
+func TestSnapshotFrozen(t *testing.T) {
+	tree := randomTree(1000, 5000)
+	tree.Freeze()
+	want := contents(tree)
+
+	// snapshots of a frozen tree do not write to it, so they may be taken
+	// concurrently
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if got := contents(tree.Snapshot()); got != want {
+					t.Errorf("snapshot of a frozen tree differs from it")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func randomTree(n, keyspace int) *avl.Tree[int, int] {
+	tree := avl.New[int, int](g.Less[int])
+	for tree.Size() < n {
+		tree.Put(rand.Intn(keyspace), rand.Int())
+	}
+	return tree
+}
+
+// The probe-based implementations that Intersect, Subtract and Merge replace.
+
+func probeIntersect(a, b *avl.Tree[int, int]) *avl.Tree[int, int] {
+	r := avl.New[int, int](g.Less[int])
+	a.Each(func(key, val int) {
+		if _, ok := b.Get(key); ok {
+			r.Put(key, val)
+		}
+	})
+	return r
+}
+
+func probeSubtract(a, b *avl.Tree[int, int]) *avl.Tree[int, int] {
+	r := avl.New[int, int](g.Less[int])
+	a.Each(func(key, val int) {
+		if _, ok := b.Get(key); !ok {
+			r.Put(key, val)
+		}
+	})
+	return r
+}
+
+func probeMerge(a, b *avl.Tree[int, int], combine func(key, va, vb int) int) *avl.Tree[int, int] {
+	r := avl.New[int, int](g.Less[int])
+	a.Each(r.Put)
+	b.Each(func(key, val int) {
+		if va, ok := a.Get(key); ok {
+			val = combine(key, va, val)
+		}
+		r.Put(key, val)
+	})
+	return r
+}
+
+func checkBalanced(t *testing.T, tree *avl.Tree[int, int]) {
+	t.Helper()
+	// an AVL tree with n nodes has height less than 1.45 lg(n+2)
+	if max := 1.45 * math.Log2(float64(tree.Size()+2)); float64(tree.Height()) > max {
+		t.Fatalf("height %d for %d keys", tree.Height(), tree.Size())
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	sum := func(key, va, vb int) int { return key + va - vb }
+	sizes := [][2]int{{0, 0}, {0, 100}, {100, 0}, {10, 5000}, {5000, 10}, {1000, 1000}, {3000, 2000}}
+	for _, sz := range sizes {
+		for _, keyspace := range []int{2 * (sz[0] + sz[1] + 1), 10000} {
+			a, b := randomTree(sz[0], keyspace), randomTree(sz[1], keyspace)
+			results := []struct {
+				name      string
+				got, want *avl.Tree[int, int]
+			}{
+				{"Intersect", avl.Intersect(a, b, g.Less[int]), probeIntersect(a, b)},
+				{"Subtract", avl.Subtract(a, b, g.Less[int]), probeSubtract(a, b)},
+				{"Merge", avl.Merge(a, b, sum), probeMerge(a, b, sum)},
+			}
+			for _, r := range results {
+				if contents(r.got) != contents(r.want) || r.got.Size() != r.want.Size() {
+					t.Fatalf("%s of %d and %d keys: got %d keys, want %d", r.name, sz[0], sz[1], r.got.Size(), r.want.Size())
+				}
+				checkBalanced(t, r.got)
+
+				// the result is an ordinary tree that can be modified
+				r.got.Put(-1, 1)
+				r.got.Remove(-1)
+				if contents(r.got) != contents(r.want) {
+					t.Fatalf("%s result corrupted by modification", r.name)
+				}
+			}
+			if a.Size() != sz[0] || b.Size() != sz[1] {
+				t.Fatal("operands modified")
+			}
+		}
+	}
+}
+
+func BenchmarkIntersect(b *testing.B) {
+	sizes := [][2]int{{100000, 100000}, {100000, 100}}
+	for _, sz := range sizes {
+		x, y := randomTree(sz[0], 4*sz[0]), randomTree(sz[1], 4*sz[0])
+		name := fmt.Sprintf("%d-%d", sz[0], sz[1])
+		b.Run("merge-"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				avl.Intersect(x, y, g.Less[int])
+			}
+		})
+		b.Run("probe-"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				probeIntersect(x, y)
+			}
+		})
+	}
+}
+
 func Example() {
 	tree := avl.New[int, string](g.Less[int])
 
@@ -73,3 +408,405 @@ func Example() {
 	// 0 baz
 	// 42 foo
 }
+
+func TestFromPairs(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 100, 1000} {
+		sorted := make([]int, n)
+		for i := range sorted {
+			sorted[i] = 2 * i
+		}
+		shuffled := rand.Perm(n)
+		withDups := make([]int, 2*n)
+		for i := range withDups {
+			withDups[i] = rand.Intn(n + 1)
+		}
+
+		for _, keys := range [][]int{sorted, shuffled, withDups} {
+			vals := make([]int, len(keys))
+			for i := range keys {
+				vals[i] = i
+			}
+			pairs := g.Zip(keys, vals)
+			orig := append([]g.KV[int, int](nil), pairs...)
+
+			for _, tree := range []*avl.Tree[int, int]{
+				avl.FromPairs(pairs, g.Less[int]),
+				avl.FromSlices(keys, vals, g.Less[int]),
+			} {
+				want := avl.New[int, int](g.Less[int])
+				for i, k := range keys {
+					want.Put(k, i)
+				}
+				if contents(tree) != contents(want) {
+					t.Fatalf("n=%d: got %s, want %s", n, contents(tree), contents(want))
+				}
+				checkBalanced(t, tree)
+				tree.Put(-1, -1)
+				tree.Remove(n / 2)
+				want.Put(-1, -1)
+				want.Remove(n / 2)
+				if contents(tree) != contents(want) {
+					t.Fatalf("n=%d: after modification got %s, want %s", n, contents(tree), contents(want))
+				}
+			}
+			for i := range pairs {
+				if pairs[i] != orig[i] {
+					t.Fatal("FromPairs modified its argument")
+				}
+			}
+		}
+	}
+
+	testutil.MustPanic(t, "different lengths", func() {
+		avl.FromSlices([]int{1, 2}, []int{1}, g.Less[int])
+	})
+}
+
+// stats is an augmentation holding the number of keys and the sum of the
+// values in a subtree.
+type stats struct {
+	count, sum int
+}
+
+func recomputeStats(a *stats, key, val int, left, right *stats) {
+	*a = stats{1, val}
+	for _, c := range []*stats{left, right} {
+		if c != nil {
+			a.count += c.count
+			a.sum += c.sum
+		}
+	}
+}
+
+func count(n *avl.AugmentedNode[int, int, stats]) int {
+	return n.Aug().count
+}
+
+// rank returns the number of keys less than 'key'.
+func rank(n *avl.AugmentedNode[int, int, stats], key int) int {
+	r := 0
+	for n != nil {
+		if n.Key() < key {
+			r += count(n.Left()) + 1
+			n = n.Right()
+		} else {
+			n = n.Left()
+		}
+	}
+	return r
+}
+
+// selectKey returns the i-th smallest key.
+func selectKey(n *avl.AugmentedNode[int, int, stats], i int) int {
+	for {
+		switch l := count(n.Left()); {
+		case i < l:
+			n = n.Left()
+		case i > l:
+			i -= l + 1
+			n = n.Right()
+		default:
+			return n.Key()
+		}
+	}
+}
+
+// checkStats recomputes the augmentation of every node from scratch, and
+// returns the augmentation of the root of the subtree.
+func checkStats(t *testing.T, n *avl.AugmentedNode[int, int, stats]) stats {
+	t.Helper()
+	if n == nil {
+		return stats{}
+	}
+	l, r := checkStats(t, n.Left()), checkStats(t, n.Right())
+	want := stats{l.count + r.count + 1, l.sum + r.sum + n.Val()}
+	if n.Aug() != want || n.Size() != want.count {
+		t.Fatalf("node %d: augmentation %v, size %d, want %v", n.Key(), n.Aug(), n.Size(), want)
+	}
+	return want
+}
+
+func TestAugmented(t *testing.T) {
+	tree := avl.NewAugmented[int, int](g.Less[int], recomputeStats)
+	ref := make(map[int]int)
+	for i := 0; i < 3000; i++ {
+		k := rand.Intn(500)
+		if rand.Intn(3) == 0 {
+			tree.Remove(k)
+			delete(ref, k)
+		} else {
+			tree.Put(k, i)
+			ref[k] = i
+		}
+		if i%50 != 0 {
+			continue
+		}
+
+		root := checkStats(t, tree.Root())
+		keys := make([]int, 0, len(ref))
+		sum := 0
+		for k, v := range ref {
+			keys = append(keys, k)
+			sum += v
+		}
+		sort.Ints(keys)
+		if root.count != len(keys) || root.sum != sum || tree.Size() != len(keys) {
+			t.Fatalf("root augmentation %v, size %d, want %d keys summing to %d", root, tree.Size(), len(keys), sum)
+		}
+		for j, k := range keys {
+			if r := rank(tree.Root(), k); r != j {
+				t.Fatalf("rank(%d) = %d, want %d", k, r, j)
+			}
+			if s := selectKey(tree.Root(), j); s != k {
+				t.Fatalf("select(%d) = %d, want %d", j, s, k)
+			}
+		}
+	}
+}
+
+func TestNodeView(t *testing.T) {
+	tree := randomTree(200, 1000)
+	var walk func(v *avl.NodeView[int, int]) int
+	var keys []string
+	walk = func(v *avl.NodeView[int, int]) int {
+		if v == nil {
+			if v.Height() != 0 || v.Size() != 0 || v.Left() != nil {
+				t.Fatal("nil view should be an empty subtree")
+			}
+			return 0
+		}
+		l := walk(v.Left())
+		keys = append(keys, fmt.Sprintf("%d:%d ", v.Key(), v.Val()))
+		r := walk(v.Right())
+		if h := 1 + g.Max(l, r); v.Height() != h {
+			t.Fatalf("node %d: height %d, want %d", v.Key(), v.Height(), h)
+		}
+		return v.Height()
+	}
+	if walk(tree.Root()) != tree.Height() {
+		t.Fatal("root height differs from tree height")
+	}
+	if strings.Join(keys, "") != contents(tree) {
+		t.Fatal("in-order walk of the views differs from Each")
+	}
+	if avl.New[int, int](g.Less[int]).Root() != nil {
+		t.Fatal("empty tree should have a nil root")
+	}
+}
+
+func TestEachRange(t *testing.T) {
+	tree := avl.New[int, int](g.Less[int])
+	if _, _, ok := tree.Min(); ok {
+		t.Fatal("Min of an empty tree")
+	}
+	if _, _, ok := tree.Max(); ok {
+		t.Fatal("Max of an empty tree")
+	}
+	for i := 0; i < 100; i++ {
+		tree.Put(2*i, i)
+	}
+	for lo := -3; lo < 203; lo += 7 {
+		for hi := lo - 5; hi < 210; hi += 11 {
+			var got []int
+			tree.EachRange(lo, hi, func(key, val int) {
+				if val != key/2 {
+					t.Fatalf("key %d has value %d", key, val)
+				}
+				got = append(got, key)
+			})
+			var want []int
+			for k := 0; k < 200; k += 2 {
+				if k >= lo && k < hi {
+					want = append(want, k)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Fatalf("EachRange(%d, %d) = %v, want %v", lo, hi, got, want)
+			}
+		}
+	}
+	if k, v, ok := tree.Min(); !ok || k != 0 || v != 0 {
+		t.Fatalf("Min() = %d, %d, %v", k, v, ok)
+	}
+	if k, v, ok := tree.Max(); !ok || k != 198 || v != 99 {
+		t.Fatalf("Max() = %d, %d, %v", k, v, ok)
+	}
+}
+
+func TestSplitFrozen(t *testing.T) {
+	tree := avl.New[int, int](g.Less[int])
+	var keys []int
+	for k := 0; k < 500; k++ {
+		tree.Put(k, -k)
+		keys = append(keys, k)
+	}
+	tree.Freeze()
+
+	// splitting a frozen tree, or joining it into another, leaves it as is,
+	// so it may be done concurrently; run with -race
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(pivot int) {
+			defer wg.Done()
+			l, r := tree.Split(pivot)
+			l.Put(-1, 1)
+			r.Remove(pivot)
+			other := avl.New[int, int](g.Less[int])
+			other.Put(-2, 2)
+			if err := other.Join(tree); err != nil {
+				t.Error(err)
+			}
+			other.Remove(pivot)
+		}(100 * r)
+	}
+	wg.Wait()
+	checkTree(t, tree, keys)
+}
+
+func checkTree(t *testing.T, tree *avl.Tree[int, int], want []int) {
+	t.Helper()
+	if err := avl.Check(tree); err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	tree.Each(func(key, val int) {
+		if val != -key {
+			t.Fatalf("key %d has value %d", key, val)
+		}
+		got = append(got, key)
+	})
+	if fmt.Sprint(got) != fmt.Sprint(want) || tree.Size() != len(want) {
+		t.Fatalf("tree has %v (size %d), want %v", got, tree.Size(), want)
+	}
+}
+
+func TestSplitJoin(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		n := rand.Intn(300)
+		tree := avl.New[int, int](g.Less[int])
+		var keys []int
+		for _, k := range rand.Perm(2 * n)[:n] {
+			tree.Put(k, -k)
+		}
+		tree.Each(func(key, val int) {
+			keys = append(keys, key)
+		})
+
+		pivot := rand.Intn(2*n + 1)
+		at := sort.SearchInts(keys, pivot)
+		l, r := tree.Split(pivot)
+		checkTree(t, l, keys[:at])
+		checkTree(t, r, keys[at:])
+		checkTree(t, tree, keys)
+
+		// the halves and the original are independent
+		l.Put(-1, 1)
+		r.Remove(pivot)
+		tree.Put(2*n, -2*n)
+		l.Remove(-1)
+		tree.Remove(2 * n)
+		checkTree(t, tree, keys)
+
+		if rand.Intn(2) == 0 {
+			l, r = r, l // Join accepts the trees in either order
+		}
+		if err := l.Join(r); err != nil {
+			t.Fatal(err)
+		}
+		var want []int
+		for _, k := range keys {
+			if k != pivot {
+				want = append(want, k)
+			}
+		}
+		checkTree(t, l, want)
+		r.Put(pivot, -pivot)
+		checkTree(t, l, want)
+	}
+
+	a := avl.New[int, int](g.Less[int])
+	b := avl.New[int, int](g.Less[int])
+	for i := 0; i < 10; i++ {
+		a.Put(2*i, -2*i)
+		b.Put(2*i+1, -2*i-1)
+	}
+	if err := a.Join(b); err != avl.ErrOverlap {
+		t.Fatalf("Join of interleaved trees returned %v", err)
+	}
+	b.Put(0, 0)
+	if a.Size() != 10 || b.Size() != 11 {
+		t.Fatal("failed Join changed a tree")
+	}
+	if err := a.Join(avl.New[int, int](g.Less[int])); err != nil || a.Size() != 10 {
+		t.Fatalf("Join of an empty tree: %v, size %d", err, a.Size())
+	}
+}
+
+func TestRemoveRange(t *testing.T) {
+	tree := avl.New[int, int](g.Less[int])
+	naive := avl.New[int, int](g.Less[int])
+	for _, k := range rand.Perm(5000) {
+		tree.Put(k, -k)
+		naive.Put(k, -k)
+	}
+	snap := tree.Snapshot()
+
+	for i := 0; i < 300; i++ {
+		lo := rand.Intn(5100) - 50
+		hi := lo + rand.Intn(200) - 20
+		var remove []int
+		naive.EachRange(lo, hi, func(key, val int) {
+			remove = append(remove, key)
+		})
+		for _, k := range remove {
+			naive.Remove(k)
+		}
+		if n := tree.RemoveRange(lo, hi); n != len(remove) {
+			t.Fatalf("RemoveRange(%d, %d) = %d, want %d", lo, hi, n, len(remove))
+		}
+		if i%2 == 0 {
+			k := rand.Intn(5000)
+			tree.Put(k, -k)
+			naive.Put(k, -k)
+		}
+
+		var want []int
+		naive.Each(func(key, val int) {
+			want = append(want, key)
+		})
+		checkTree(t, tree, want)
+	}
+	if snap.Size() != 5000 {
+		t.Fatalf("RemoveRange changed a snapshot: size %d", snap.Size())
+	}
+}
+
+func BenchmarkRemoveRange(b *testing.B) {
+	const n = 1000000
+	tree := avl.New[int, int](g.Less[int])
+	for i := 0; i < n; i++ {
+		tree.Put(i, i)
+	}
+	// Split at a key past the end returns a copy that shares the nodes of
+	// the tree, so each iteration removes from the full tree
+	span := func(i int) (int, int) {
+		lo := (i * 7919) % (n - n/10)
+		return lo, lo + n/10
+	}
+	b.Run("RemoveRange", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			t, _ := tree.Split(n)
+			t.RemoveRange(span(i))
+		}
+	})
+	b.Run("Remove", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			t, _ := tree.Split(n)
+			lo, hi := span(i)
+			for k := lo; k < hi; k++ {
+				t.Remove(k)
+			}
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package avl
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// NodeSize returns the size of a node of a Tree[K, V].
+func NodeSize[K, V any]() int64 {
+	return int64(unsafe.Sizeof(node[K, V]{}))
+}
+
+// Check verifies that the tree is a balanced binary search tree with correct
+// heights and sizes.
+func Check[K, V any](t *Tree[K, V]) error {
+	_, err := check(t.root, t.less, nil, nil)
+	return err
+}
+
+func check[K, V any](n *node[K, V], less func(a, b K) bool, lo, hi *K) (int, error) {
+	if n == nil {
+		return 0, nil
+	}
+	if (lo != nil && !less(*lo, n.key)) || (hi != nil && !less(n.key, *hi)) {
+		return 0, fmt.Errorf("key %v out of order", n.key)
+	}
+	hl, err := check(n.left, less, lo, &n.key)
+	if err != nil {
+		return 0, err
+	}
+	hr, err := check(n.right, less, &n.key, hi)
+	if err != nil {
+		return 0, err
+	}
+	if hl-hr > 1 || hr-hl > 1 {
+		return 0, fmt.Errorf("node %v is unbalanced: heights %d and %d", n.key, hl, hr)
+	}
+	h := 1 + hl
+	if hr > hl {
+		h = 1 + hr
+	}
+	if n.height != h {
+		return 0, fmt.Errorf("node %v has height %d, want %d", n.key, n.height, h)
+	}
+	if size := 1 + n.left.getSize() + n.right.getSize(); n.size != size {
+		return 0, fmt.Errorf("node %v has size %d, want %d", n.key, n.size, size)
+	}
+	return h, nil
+}
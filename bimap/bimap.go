@@ -3,8 +3,21 @@
 // It is implemented by using two Go maps, which keeps the lookup speed
 // identical for both forward and reverse lookups, however it also doubles the
 // memory usage of the map.
+//
+// The [Ordered] variant uses two AVL trees instead, so that pairs can be
+// scanned in order of their keys or their values.
 package bimap
 
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/list"
+)
+
 // Of returns a new [Bimap] initiated with the keys and values
 // from the given map.
 func Of[K, V comparable](m map[K]V) Bimap[K, V] {
@@ -15,12 +28,39 @@ func Of[K, V comparable](m map[K]V) Bimap[K, V] {
 	return bm
 }
 
+// NewBounded returns a new, empty [Bimap] that holds at most 'maxLen'
+// key-value pairs. When adding a pair would exceed this, the pair that was
+// added least recently is evicted first, and 'onEvict' is called with it if
+// it is not nil.
+func NewBounded[K, V comparable](maxLen int, onEvict func(key K, value V)) Bimap[K, V] {
+	return Bimap[K, V]{
+		forward: make(map[K]V),
+		reverse: make(map[V]K),
+		bound: &bound[K, V]{
+			maxLen:  maxLen,
+			onEvict: onEvict,
+			nodes:   make(map[K]*list.Node[K]),
+		},
+	}
+}
+
 // Bimap is a bi-directional map where both the keys and values are indexed
 // against each other, allowing performant lookup on both keys and values,
 // at the cost of double the memory usage.
 type Bimap[K, V comparable] struct {
 	forward map[K]V
 	reverse map[V]K
+
+	// bound tracks insertion order for a bimap created with NewBounded, and
+	// is nil otherwise.
+	bound *bound[K, V]
+}
+
+type bound[K, V comparable] struct {
+	maxLen  int
+	onEvict func(key K, value V)
+	order   list.List[K]
+	nodes   map[K]*list.Node[K]
 }
 
 // Len returns the number of key-value pairs in this map.
@@ -41,6 +81,7 @@ func (b *Bimap[K, V]) Add(key K, value V) {
 	}
 	if oldKey, ok := b.GetReverse(value); ok {
 		delete(b.forward, oldKey)
+		b.untrack(oldKey)
 	}
 	if b.forward == nil {
 		b.forward = make(map[K]V)
@@ -48,6 +89,112 @@ func (b *Bimap[K, V]) Add(key K, value V) {
 	}
 	b.forward[key] = value
 	b.reverse[value] = key
+	b.track(key)
+}
+
+// AddAll adds every key-value pair of 'pairs' to this map with the overwrite
+// semantics of Add. If several keys of 'pairs' have the same value, only one
+// of them is kept, and which one is unspecified. It always returns nil.
+func (b *Bimap[K, V]) AddAll(pairs map[K]V) error {
+	for k, v := range pairs {
+		b.Add(k, v)
+	}
+	return nil
+}
+
+// A Conflict is a key-value pair that TryAddAll could not add.
+type Conflict[K, V comparable] struct {
+	Key   K
+	Value V
+	// Reason describes what the pair conflicts with.
+	Reason string
+}
+
+// A ConflictError is returned by TryAddAll when some pairs of the batch
+// conflict with the map or with each other.
+type ConflictError[K, V comparable] struct {
+	Conflicts []Conflict[K, V]
+}
+
+func (e *ConflictError[K, V]) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "bimap: %d conflicting pairs:", len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		fmt.Fprintf(&sb, " (%v, %v): %s;", c.Key, c.Value, c.Reason)
+	}
+	return strings.TrimSuffix(sb.String(), ";")
+}
+
+// TryAddAll adds every key-value pair of 'pairs' to this map without
+// overwriting anything, or adds none of them. A pair conflicts if its key is
+// already mapped to a different value, if its value is already mapped from a
+// different key, or if another pair of the batch has the same value. Pairs
+// already in the map are not conflicts. If there is any conflict, the map is
+// left unchanged and a *ConflictError listing every conflicting pair, sorted
+// by the string form of their keys, is returned.
+func (b *Bimap[K, V]) TryAddAll(pairs map[K]V) error {
+	// check the whole batch before modifying anything
+	var conflicts []Conflict[K, V]
+	byValue := make(map[V][]K, len(pairs))
+	for k, v := range pairs {
+		byValue[v] = append(byValue[v], k)
+	}
+	for k, v := range pairs {
+		if old, ok := b.forward[k]; ok && old != v {
+			conflicts = append(conflicts, Conflict[K, V]{k, v, fmt.Sprintf("key is mapped to %v", old)})
+		} else if old, ok := b.reverse[v]; ok && old != k {
+			conflicts = append(conflicts, Conflict[K, V]{k, v, fmt.Sprintf("value is mapped from %v", old)})
+		} else if len(byValue[v]) > 1 {
+			conflicts = append(conflicts, Conflict[K, V]{k, v, "value appears more than once in the batch"})
+		}
+	}
+	if len(conflicts) > 0 {
+		slices.SortFunc(conflicts, func(a, b Conflict[K, V]) bool {
+			return fmt.Sprint(a.Key) < fmt.Sprint(b.Key)
+		})
+		return &ConflictError[K, V]{conflicts}
+	}
+
+	for k, v := range pairs {
+		b.Add(k, v)
+	}
+	return nil
+}
+
+// track marks 'key' as the most recently added key, and evicts the least
+// recently added pairs if the bimap is over its bound.
+func (b *Bimap[K, V]) track(key K) {
+	if b.bound == nil {
+		return
+	}
+	if n, ok := b.bound.nodes[key]; ok {
+		b.bound.order.Remove(n)
+		b.bound.order.PushBackNode(n)
+	} else {
+		n = &list.Node[K]{
+			Value: key,
+		}
+		b.bound.order.PushBackNode(n)
+		b.bound.nodes[key] = n
+	}
+	for len(b.forward) > b.bound.maxLen && b.bound.order.Front != nil {
+		oldKey := b.bound.order.Front.Value
+		oldVal := b.forward[oldKey]
+		b.RemoveForward(oldKey)
+		if b.bound.onEvict != nil {
+			b.bound.onEvict(oldKey, oldVal)
+		}
+	}
+}
+
+func (b *Bimap[K, V]) untrack(key K) {
+	if b.bound == nil {
+		return
+	}
+	if n, ok := b.bound.nodes[key]; ok {
+		b.bound.order.Remove(n)
+		delete(b.bound.nodes, key)
+	}
 }
 
 // RemoveForward removes a key-value pair from this map based on the key.
@@ -55,6 +202,7 @@ func (b *Bimap[K, V]) RemoveForward(key K) {
 	if value, ok := b.forward[key]; ok {
 		delete(b.reverse, value)
 		delete(b.forward, key)
+		b.untrack(key)
 	}
 }
 
@@ -63,7 +211,22 @@ func (b *Bimap[K, V]) RemoveReverse(value V) {
 	if key, ok := b.reverse[value]; ok {
 		delete(b.reverse, value)
 		delete(b.forward, key)
+		b.untrack(key)
+	}
+}
+
+// RemoveWhere removes every key-value pair for which 'pred' returns true, and
+// returns the number of pairs removed.
+func (b *Bimap[K, V]) RemoveWhere(pred func(key K, value V) bool) int {
+	removed := 0
+	// deleting from a map while ranging over it is safe
+	for k, v := range b.forward {
+		if pred(k, v) {
+			b.RemoveForward(k)
+			removed++
+		}
 	}
+	return removed
 }
 
 // Each loops over all the values in this map.
@@ -73,6 +236,32 @@ func (b *Bimap[K, V]) Each(f func(key K, value V)) {
 	}
 }
 
+// EachSorted loops over all the values in this map in ascending order of
+// their keys. The keys are copied and sorted before iteration begins.
+func (b *Bimap[K, V]) EachSorted(keyLess g.LessFn[K], f func(key K, value V)) {
+	keys := make([]K, 0, len(b.forward))
+	for k := range b.forward {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, keyLess)
+	for _, k := range keys {
+		f(k, b.forward[k])
+	}
+}
+
+// EachSortedByValue loops over all the values in this map in ascending order
+// of the values. The values are copied and sorted before iteration begins.
+func (b *Bimap[K, V]) EachSortedByValue(valueLess g.LessFn[V], f func(key K, value V)) {
+	values := make([]V, 0, len(b.reverse))
+	for v := range b.reverse {
+		values = append(values, v)
+	}
+	slices.SortFunc(values, valueLess)
+	for _, v := range values {
+		f(b.reverse[v], v)
+	}
+}
+
 // ContainsForward checks if the given key exists.
 func (b *Bimap[K, V]) ContainsForward(key K) bool {
 	_, ok := b.forward[key]
@@ -101,14 +290,34 @@ func (b *Bimap[K, V]) GetReverse(value V) (K, bool) {
 func (b *Bimap[K, V]) Clear() {
 	clear(b.forward)
 	clear(b.reverse)
+	if b.bound != nil {
+		b.bound.order = list.List[K]{}
+		clear(b.bound.nodes)
+	}
 }
 
-// Copy creates a shallow copy of this bidirectional map.
+// Copy creates a shallow copy of this bidirectional map. A copy of a bounded
+// map has the same bound, eviction callback and insertion order.
 func (b *Bimap[K, V]) Copy() Bimap[K, V] {
-	return Bimap[K, V]{
+	c := Bimap[K, V]{
 		forward: shallowCopy(b.forward),
 		reverse: shallowCopy(b.reverse),
 	}
+	if b.bound != nil {
+		c.bound = &bound[K, V]{
+			maxLen:  b.bound.maxLen,
+			onEvict: b.bound.onEvict,
+			nodes:   make(map[K]*list.Node[K], len(b.bound.nodes)),
+		}
+		b.bound.order.Front.Each(func(key K) {
+			n := &list.Node[K]{
+				Value: key,
+			}
+			c.bound.order.PushBackNode(n)
+			c.bound.nodes[key] = n
+		})
+	}
+	return c
 }
 
 func clear[M ~map[K]V, K comparable, V any](m M) {
@@ -1,6 +1,11 @@
 package bimap
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
 
 func assertEqual[T comparable](t *testing.T, want, got T, msg string) {
 	if want != got {
@@ -32,3 +37,169 @@ func TestWrite(t *testing.T) {
 	m.RemoveForward(1)
 	assertEqual(t, false, m.ContainsForward(1), "contains after remove?")
 }
+
+func checkInSync[K, V comparable](t *testing.T, m *Bimap[K, V]) {
+	t.Helper()
+	assertEqual(t, len(m.forward), len(m.reverse), "forward and reverse length")
+	m.Each(func(key K, value V) {
+		k, ok := m.GetReverse(value)
+		assertEqual(t, true, ok, "reverse lookup")
+		assertEqual(t, key, k, "reverse lookup")
+	})
+	if m.bound != nil {
+		assertEqual(t, len(m.forward), len(m.bound.nodes), "tracked keys")
+	}
+}
+
+func TestRemoveWhere(t *testing.T) {
+	var m Bimap[int, int]
+	const n = 10000
+	for i := 0; i < n; i++ {
+		m.Add(i, -i)
+	}
+
+	removed := m.RemoveWhere(func(key, value int) bool {
+		return key%2 == 0
+	})
+	assertEqual(t, n/2, removed, "removed count")
+	assertEqual(t, n/2, m.Len(), "length after remove")
+	checkInSync(t, &m)
+	m.Each(func(key, value int) {
+		assertEqual(t, 1, key%2, "remaining key")
+	})
+}
+
+func TestBounded(t *testing.T) {
+	var evicted []int
+	m := NewBounded(3, func(key int, value string) {
+		evicted = append(evicted, key)
+	})
+
+	m.Add(1, "a")
+	m.Add(2, "b")
+	m.Add(3, "c")
+	assertEqual(t, 0, len(evicted), "no eviction under the bound")
+
+	m.Add(4, "d") // evicts 1
+	m.Add(2, "B") // re-adding 2 makes it the most recent
+	m.Add(5, "e") // evicts 3
+	m.Add(6, "B") // value collision removes 2 without eviction
+	m.Add(7, "f") // evicts 4
+	checkInSync(t, &m)
+
+	assertEqual(t, 3, m.Len(), "length")
+	assertEqual(t, "[1 3 4]", fmt.Sprint(evicted), "evicted keys")
+	for _, k := range []int{5, 6, 7} {
+		assertEqual(t, true, m.ContainsForward(k), "remaining key")
+	}
+
+	c := m.Copy()
+	c.Add(8, "g") // evicts 5 from the copy only
+	assertEqual(t, "[1 3 4 5]", fmt.Sprint(evicted), "evicted keys")
+	assertEqual(t, true, m.ContainsForward(5), "original unaffected by copy")
+	checkInSync(t, &c)
+
+	m.RemoveReverse("e")
+	m.Clear()
+	m.Add(9, "h")
+	checkInSync(t, &m)
+	assertEqual(t, "[1 3 4 5]", fmt.Sprint(evicted), "evicted keys")
+}
+
+func TestEachSorted(t *testing.T) {
+	m := Of(map[string]int{
+		"c": 1,
+		"a": 3,
+		"d": 2,
+		"b": 4,
+	})
+
+	var byKey, byValue string
+	m.EachSorted(func(a, b string) bool { return a < b }, func(key string, value int) {
+		byKey += fmt.Sprintf("%s=%d ", key, value)
+	})
+	m.EachSortedByValue(func(a, b int) bool { return a < b }, func(key string, value int) {
+		byValue += fmt.Sprintf("%s=%d ", key, value)
+	})
+	assertEqual(t, "a=3 b=4 c=1 d=2 ", byKey, "sorted by key")
+	assertEqual(t, "c=1 d=2 a=3 b=4 ", byValue, "sorted by value")
+}
+
+func TestAddAll(t *testing.T) {
+	var m Bimap[int, string]
+	m.Add(1, "one")
+	m.Add(2, "two")
+	if err := m.AddAll(map[int]string{1: "uno", 3: "two", 4: "four"}); err != nil {
+		t.Fatal(err)
+	}
+	checkInSync(t, &m)
+	want := map[int]string{1: "uno", 3: "two", 4: "four"}
+	assertEqual(t, len(want), m.Len(), "length")
+	for k, v := range want {
+		got, _ := m.GetForward(k)
+		assertEqual(t, v, got, "value")
+	}
+}
+
+func TestTryAddAll(t *testing.T) {
+	base := func() Bimap[int, string] {
+		m := Bimap[int, string]{}
+		m.Add(1, "one")
+		m.Add(2, "two")
+		return m
+	}
+	contents := func(m *Bimap[int, string]) string {
+		var s []string
+		m.EachSorted(func(a, b int) bool { return a < b }, func(k int, v string) {
+			s = append(s, fmt.Sprintf("%d:%s", k, v))
+		})
+		return strings.Join(s, " ")
+	}
+
+	tests := []struct {
+		name  string
+		pairs map[int]string
+		want  []int // keys of the conflicting pairs
+	}{
+		{"clean", map[int]string{3: "three", 4: "four"}, nil},
+		{"already present", map[int]string{1: "one", 3: "three"}, nil},
+		{"existing key", map[int]string{1: "uno", 3: "three"}, []int{1}},
+		{"existing value", map[int]string{3: "two", 4: "four"}, []int{3}},
+		{"duplicate values", map[int]string{3: "x", 4: "x", 5: "five"}, []int{3, 4}},
+		{"several", map[int]string{1: "uno", 3: "one", 4: "y", 5: "y"}, []int{1, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := base()
+			before := contents(&m)
+			err := m.TryAddAll(tt.pairs)
+			checkInSync(t, &m)
+
+			if tt.want == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				for k, v := range tt.pairs {
+					got, _ := m.GetForward(k)
+					assertEqual(t, v, got, "added value")
+				}
+				return
+			}
+
+			var cerr *ConflictError[int, string]
+			if !errors.As(err, &cerr) {
+				t.Fatalf("got error %v, want a ConflictError", err)
+			}
+			var keys []int
+			for _, c := range cerr.Conflicts {
+				keys = append(keys, c.Key)
+			}
+			assertEqual(t, fmt.Sprint(tt.want), fmt.Sprint(keys), "conflicting keys")
+			assertEqual(t, before, contents(&m), "map after failed TryAddAll")
+		})
+	}
+
+	m := base()
+	err := m.TryAddAll(map[int]string{1: "uno", 3: "two"})
+	assertEqual(t, "bimap: 2 conflicting pairs: (1, uno): key is mapped to one; (3, two): value is mapped from 2", fmt.Sprint(err), "error message")
+}
@@ -0,0 +1,128 @@
+package bimap
+
+import (
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/avl"
+)
+
+// Ordered is a bi-directional map that keeps both its keys and its values
+// sorted, using an AVL tree for each direction. Lookups take O(lg n) time
+// rather than the constant time of a Bimap, but the pairs can be scanned in
+// order of either keys or values, and over a range of either.
+type Ordered[K, V any] struct {
+	forward *avl.Tree[K, V]
+	reverse *avl.Tree[V, K]
+}
+
+// NewOrdered returns a new, empty ordered bimap, with keys ordered by 'lessK'
+// and values ordered by 'lessV'.
+func NewOrdered[K, V any](lessK g.LessFn[K], lessV g.LessFn[V]) *Ordered[K, V] {
+	return &Ordered[K, V]{
+		forward: avl.New[K, V](lessK),
+		reverse: avl.New[V, K](lessV),
+	}
+}
+
+// Len returns the number of key-value pairs in this map.
+func (b *Ordered[K, V]) Len() int {
+	return b.forward.Size()
+}
+
+// Add another key-value pair to be indexed inside this map.
+//
+// On collisions, the old pairs are removed, as with Bimap: if 'key' was
+// mapped to another value, that value is removed, and if 'value' was mapped
+// from another key, that key is removed.
+func (b *Ordered[K, V]) Add(key K, value V) {
+	if oldVal, ok := b.forward.Get(key); ok {
+		b.reverse.Remove(oldVal)
+	}
+	if oldKey, ok := b.reverse.Get(value); ok {
+		b.forward.Remove(oldKey)
+	}
+	b.forward.Put(key, value)
+	b.reverse.Put(value, key)
+}
+
+// GetForward performs a lookup on the key to get the value.
+func (b *Ordered[K, V]) GetForward(key K) (V, bool) {
+	return b.forward.Get(key)
+}
+
+// GetReverse performs a lookup on the value to get the key.
+func (b *Ordered[K, V]) GetReverse(value V) (K, bool) {
+	return b.reverse.Get(value)
+}
+
+// ContainsForward checks if the given key exists.
+func (b *Ordered[K, V]) ContainsForward(key K) bool {
+	_, ok := b.forward.Get(key)
+	return ok
+}
+
+// ContainsReverse checks if the given value exists.
+func (b *Ordered[K, V]) ContainsReverse(value V) bool {
+	_, ok := b.reverse.Get(value)
+	return ok
+}
+
+// RemoveForward removes a key-value pair from this map based on the key.
+func (b *Ordered[K, V]) RemoveForward(key K) {
+	if value, ok := b.forward.Get(key); ok {
+		b.forward.Remove(key)
+		b.reverse.Remove(value)
+	}
+}
+
+// RemoveReverse removes a key-value pair from this map based on the value.
+func (b *Ordered[K, V]) RemoveReverse(value V) {
+	if key, ok := b.reverse.Get(value); ok {
+		b.reverse.Remove(value)
+		b.forward.Remove(key)
+	}
+}
+
+// Each loops over all the pairs in this map in ascending order of their keys.
+func (b *Ordered[K, V]) Each(f func(key K, value V)) {
+	b.forward.Each(f)
+}
+
+// EachForwardRange loops over the pairs with keys in the range [loK, hiK), in
+// ascending order of their keys.
+func (b *Ordered[K, V]) EachForwardRange(loK, hiK K, f func(key K, value V)) {
+	b.forward.EachRange(loK, hiK, f)
+}
+
+// EachReverseRange loops over the pairs with values in the range [loV, hiV),
+// in ascending order of their values.
+func (b *Ordered[K, V]) EachReverseRange(loV, hiV V, f func(key K, value V)) {
+	b.reverse.EachRange(loV, hiV, func(value V, key K) {
+		f(key, value)
+	})
+}
+
+// MinForward returns the pair with the smallest key, or false if the map is
+// empty.
+func (b *Ordered[K, V]) MinForward() (K, V, bool) {
+	return b.forward.Min()
+}
+
+// MaxForward returns the pair with the largest key, or false if the map is
+// empty.
+func (b *Ordered[K, V]) MaxForward() (K, V, bool) {
+	return b.forward.Max()
+}
+
+// MinReverse returns the pair with the smallest value, or false if the map is
+// empty.
+func (b *Ordered[K, V]) MinReverse() (K, V, bool) {
+	v, k, ok := b.reverse.Min()
+	return k, v, ok
+}
+
+// MaxReverse returns the pair with the largest value, or false if the map is
+// empty.
+func (b *Ordered[K, V]) MaxReverse() (K, V, bool) {
+	v, k, ok := b.reverse.Max()
+	return k, v, ok
+}
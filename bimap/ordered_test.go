@@ -0,0 +1,152 @@
+package bimap
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	g "github.com/zyedidia/generic"
+)
+
+type pair struct {
+	key   int
+	value int
+}
+
+func checkOrdered(t *testing.T, m *Ordered[int, int], ref map[int]int) {
+	t.Helper()
+	assertEqual(t, len(ref), m.Len(), "length")
+	assertEqual(t, m.forward.Size(), m.reverse.Size(), "forward and reverse length")
+	for k, v := range ref {
+		got, ok := m.GetForward(k)
+		assertEqual(t, true, ok, "forward lookup")
+		assertEqual(t, v, got, "forward lookup")
+		key, ok := m.GetReverse(v)
+		assertEqual(t, true, ok, "reverse lookup")
+		assertEqual(t, k, key, "reverse lookup")
+	}
+}
+
+// sortedPairs returns the pairs of 'ref' with lo <= by(pair) < hi, sorted by
+// by(pair).
+func sortedPairs(ref map[int]int, lo, hi int, by func(p pair) int) []pair {
+	var ps []pair
+	for k, v := range ref {
+		if p := (pair{k, v}); by(p) >= lo && by(p) < hi {
+			ps = append(ps, p)
+		}
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		return by(ps[i]) < by(ps[j])
+	})
+	return ps
+}
+
+func TestOrdered(t *testing.T) {
+	m := NewOrdered[int, int](g.Less[int], g.Less[int])
+	ref := make(map[int]int)
+	byKey := func(p pair) int { return p.key }
+	byValue := func(p pair) int { return p.value }
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		k, v := r.Intn(500), r.Intn(500)
+		switch r.Intn(4) {
+		case 0:
+			m.RemoveForward(k)
+			delete(ref, k)
+		case 1:
+			m.RemoveReverse(v)
+			for rk, rv := range ref {
+				if rv == v {
+					delete(ref, rk)
+				}
+			}
+		default:
+			m.Add(k, v)
+			for rk, rv := range ref {
+				if rv == v {
+					delete(ref, rk)
+				}
+			}
+			ref[k] = v
+		}
+
+		if i%100 != 0 {
+			continue
+		}
+		checkOrdered(t, m, ref)
+
+		lo := r.Intn(500)
+		hi := lo + r.Intn(200)
+		var got []pair
+		m.EachForwardRange(lo, hi, func(key, value int) {
+			got = append(got, pair{key, value})
+		})
+		want := sortedPairs(ref, lo, hi, byKey)
+		assertEqual(t, len(want), len(got), "forward range length")
+		for j := range want {
+			assertEqual(t, want[j], got[j], "forward range")
+		}
+
+		got = got[:0]
+		m.EachReverseRange(lo, hi, func(key, value int) {
+			got = append(got, pair{key, value})
+		})
+		want = sortedPairs(ref, lo, hi, byValue)
+		assertEqual(t, len(want), len(got), "reverse range length")
+		for j := range want {
+			assertEqual(t, want[j], got[j], "reverse range")
+		}
+
+		all := sortedPairs(ref, 0, 500, byKey)
+		k, v, ok := m.MinForward()
+		assertEqual(t, len(all) > 0, ok, "min forward")
+		if ok {
+			assertEqual(t, all[0], pair{k, v}, "min forward")
+			k, v, _ = m.MaxForward()
+			assertEqual(t, all[len(all)-1], pair{k, v}, "max forward")
+		}
+		all = sortedPairs(ref, 0, 500, byValue)
+		k, v, ok = m.MinReverse()
+		assertEqual(t, len(all) > 0, ok, "min reverse")
+		if ok {
+			assertEqual(t, all[0], pair{k, v}, "min reverse")
+			k, v, _ = m.MaxReverse()
+			assertEqual(t, all[len(all)-1], pair{k, v}, "max reverse")
+		}
+	}
+}
+
+func TestOrderedCollisions(t *testing.T) {
+	m := NewOrdered[int, string](g.Less[int], g.Less[string])
+
+	// a new value for an existing key replaces the old value
+	m.Add(1, "a")
+	m.Add(1, "b")
+	assertEqual(t, 1, m.Len(), "length after replacing value")
+	assertEqual(t, false, m.ContainsReverse("a"), "old value removed")
+
+	// an existing value for a new key replaces the old key
+	m.Add(2, "b")
+	assertEqual(t, 1, m.Len(), "length after replacing key")
+	assertEqual(t, false, m.ContainsForward(1), "old key removed")
+
+	// a pair that collides on both sides displaces two pairs
+	m.Add(3, "c")
+	m.Add(2, "c")
+	assertEqual(t, 1, m.Len(), "length after double collision")
+	v, _ := m.GetForward(2)
+	assertEqual(t, "c", v, "forward lookup")
+	k, _ := m.GetReverse("c")
+	assertEqual(t, 2, k, "reverse lookup")
+	assertEqual(t, false, m.ContainsForward(3), "displaced key removed")
+	assertEqual(t, false, m.ContainsReverse("b"), "displaced value removed")
+
+	m.RemoveReverse("c")
+	assertEqual(t, 0, m.Len(), "length after remove")
+	_, _, ok := m.MinForward()
+	assertEqual(t, false, ok, "min of empty map")
+	_, _, ok = m.MaxReverse()
+	assertEqual(t, false, ok, "max of empty map")
+}
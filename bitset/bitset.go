@@ -0,0 +1,181 @@
+// Package bitset provides an implementation of a set of non-negative integers
+// backed by a slice of 64-bit words. A bitset is efficient for dense sets of
+// small integers, where each element takes only a single bit.
+package bitset
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const wordSize = 64
+
+// BitSet implements a set of non-negative integers. The set grows
+// automatically to fit the largest element.
+type BitSet struct {
+	words []uint64
+}
+
+// New returns an empty bitset with space preallocated for the integers in the
+// range [0, n).
+func New(n int) *BitSet {
+	return &BitSet{
+		words: make([]uint64, 0, (n+wordSize-1)/wordSize),
+	}
+}
+
+// Of returns a new bitset initialized with the given 'vals'.
+func Of(vals ...int) *BitSet {
+	b := New(0)
+	for _, val := range vals {
+		b.Set(val)
+	}
+	return b
+}
+
+func checkIndex(i int) {
+	if i < 0 {
+		panic(fmt.Sprintf("bitset: negative index %d", i))
+	}
+}
+
+// Set adds 'i' to the set.
+//
+// A panic occurs if 'i' is negative.
+func (b *BitSet) Set(i int) {
+	checkIndex(i)
+	w := i / wordSize
+	if w >= len(b.words) {
+		b.grow(w + 1)
+	}
+	b.words[w] |= 1 << (uint(i) % wordSize)
+}
+
+// Clear removes 'i' from the set.
+//
+// A panic occurs if 'i' is negative.
+func (b *BitSet) Clear(i int) {
+	checkIndex(i)
+	w := i / wordSize
+	if w >= len(b.words) {
+		return
+	}
+	b.words[w] &^= 1 << (uint(i) % wordSize)
+}
+
+// Test returns true only if 'i' is in the set.
+//
+// A panic occurs if 'i' is negative.
+func (b *BitSet) Test(i int) bool {
+	checkIndex(i)
+	w := i / wordSize
+	if w >= len(b.words) {
+		return false
+	}
+	return b.words[w]&(1<<(uint(i)%wordSize)) != 0
+}
+
+// Count returns the number of elements in the set.
+func (b *BitSet) Count() int {
+	n := 0
+	for _, w := range b.words {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// NextSet returns the smallest element in the set that is greater than or
+// equal to 'i', or false if there is no such element. All the elements of a
+// set can be visited with:
+//
+//	for i, ok := b.NextSet(0); ok; i, ok = b.NextSet(i + 1) {
+//		...
+//	}
+func (b *BitSet) NextSet(i int) (int, bool) {
+	if i < 0 {
+		i = 0
+	}
+	w := i / wordSize
+	if w >= len(b.words) {
+		return 0, false
+	}
+	word := b.words[w] >> (uint(i) % wordSize)
+	if word != 0 {
+		return i + bits.TrailingZeros64(word), true
+	}
+	for w++; w < len(b.words); w++ {
+		if b.words[w] != 0 {
+			return w*wordSize + bits.TrailingZeros64(b.words[w]), true
+		}
+	}
+	return 0, false
+}
+
+// Each calls 'fn' on every element in the set in increasing order.
+func (b *BitSet) Each(fn func(i int)) {
+	for w, word := range b.words {
+		for word != 0 {
+			fn(w*wordSize + bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+}
+
+// Union returns a new set containing the elements that are in either 'b' or
+// 'other'.
+func (b *BitSet) Union(other *BitSet) *BitSet {
+	u := b.Copy()
+	if len(other.words) > len(u.words) {
+		u.grow(len(other.words))
+	}
+	for i, w := range other.words {
+		u.words[i] |= w
+	}
+	return u
+}
+
+// Intersection returns a new set containing the elements that are in both 'b'
+// and 'other'.
+func (b *BitSet) Intersection(other *BitSet) *BitSet {
+	n := len(b.words)
+	if len(other.words) < n {
+		n = len(other.words)
+	}
+	in := &BitSet{
+		words: make([]uint64, n),
+	}
+	for i := range in.words {
+		in.words[i] = b.words[i] & other.words[i]
+	}
+	return in
+}
+
+// Difference returns a new set containing the elements that are in 'b' but
+// not in 'other'.
+func (b *BitSet) Difference(other *BitSet) *BitSet {
+	d := b.Copy()
+	for i := 0; i < len(d.words) && i < len(other.words); i++ {
+		d.words[i] &^= other.words[i]
+	}
+	return d
+}
+
+// Copy returns a copy of this set.
+func (b *BitSet) Copy() *BitSet {
+	words := make([]uint64, len(b.words))
+	copy(words, b.words)
+	return &BitSet{
+		words: words,
+	}
+}
+
+// grow extends the set to hold 'n' words.
+func (b *BitSet) grow(n int) {
+	if n <= cap(b.words) {
+		b.words = b.words[:n]
+		return
+	}
+	words := make([]uint64, n, 2*n)
+	copy(words, b.words)
+	b.words = words
+}
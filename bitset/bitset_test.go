@@ -0,0 +1,147 @@
+package bitset_test
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/zyedidia/generic/bitset"
+)
+
+func checkeq(b *bitset.BitSet, m map[int]bool, t *testing.T) {
+	if b.Count() != len(m) {
+		t.Fatalf("count mismatch: %d != %d", b.Count(), len(m))
+	}
+	for i := range m {
+		if !b.Test(i) {
+			t.Fatalf("%d should exist", i)
+		}
+	}
+
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	var got []int
+	for i, ok := b.NextSet(0); ok; i, ok = b.NextSet(i + 1) {
+		got = append(got, i)
+	}
+	if len(got) != len(keys) {
+		t.Fatalf("NextSet visited %v, expected %v", got, keys)
+	}
+	for i := range keys {
+		if keys[i] != got[i] {
+			t.Fatalf("NextSet visited %v, expected %v", got, keys)
+		}
+	}
+}
+
+func random(n, max int) (*bitset.BitSet, map[int]bool) {
+	b := bitset.New(0)
+	m := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		v := rand.Intn(max)
+		b.Set(v)
+		m[v] = true
+	}
+	return b, m
+}
+
+func TestCrossCheck(t *testing.T) {
+	b := bitset.New(64)
+	m := make(map[int]bool)
+
+	const nops = 1000
+	for i := 0; i < nops; i++ {
+		v := rand.Intn(300)
+		switch rand.Intn(2) {
+		case 0:
+			b.Set(v)
+			m[v] = true
+		case 1:
+			b.Clear(v)
+			delete(m, v)
+		}
+		checkeq(b, m, t)
+	}
+}
+
+func TestWordBoundaries(t *testing.T) {
+	b := bitset.New(0)
+	for _, i := range []int{0, 63, 64, 127, 128, 1000} {
+		if b.Test(i) {
+			t.Fatalf("%d should not exist", i)
+		}
+		b.Set(i)
+		if !b.Test(i) {
+			t.Fatalf("%d should exist", i)
+		}
+	}
+	if b.Test(62) || b.Test(65) || b.Test(129) {
+		t.Fatal("neighbors of set bits should not exist")
+	}
+	if c := b.Count(); c != 6 {
+		t.Fatalf("count mismatch: %d != 6", c)
+	}
+	b.Clear(64)
+	b.Clear(5000) // no effect
+	if b.Test(64) || !b.Test(63) {
+		t.Fatal("clear affected the wrong bit")
+	}
+	if i, ok := b.NextSet(64); !ok || i != 127 {
+		t.Fatalf("NextSet(64) = %d, %v, expected 127", i, ok)
+	}
+	if _, ok := b.NextSet(1001); ok {
+		t.Fatal("NextSet past the last element should fail")
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		b1, m1 := random(rand.Intn(100), 400)
+		b2, m2 := random(rand.Intn(100), 400)
+
+		union := make(map[int]bool)
+		inter := make(map[int]bool)
+		diff := make(map[int]bool)
+		for k := range m1 {
+			union[k] = true
+			if m2[k] {
+				inter[k] = true
+			} else {
+				diff[k] = true
+			}
+		}
+		for k := range m2 {
+			union[k] = true
+		}
+
+		checkeq(b1.Union(b2), union, t)
+		checkeq(b1.Intersection(b2), inter, t)
+		checkeq(b1.Difference(b2), diff, t)
+		// the operands should be unmodified
+		checkeq(b1, m1, t)
+		checkeq(b2, m2, t)
+	}
+}
+
+func Example() {
+	b := bitset.Of(1, 5, 100)
+	b.Set(64)
+	b.Clear(5)
+
+	fmt.Println(b.Test(64), b.Test(5))
+	fmt.Println(b.Count())
+	b.Each(func(i int) {
+		fmt.Println(i)
+	})
+	// Output:
+	// true false
+	// 3
+	// 1
+	// 64
+	// 100
+}
@@ -5,7 +5,10 @@
 package btree
 
 import (
+	"unsafe"
+
 	g "github.com/zyedidia/generic"
+	"golang.org/x/exp/slices"
 )
 
 const maxChildren = 64 // must be even and > 2
@@ -21,11 +24,18 @@ type Tree[K, V any] struct {
 	n      int
 
 	less g.LessFn[K]
+
+	frozen bool
+	// gen is the generation that writes are recorded in. See NewGeneration.
+	gen uint64
 }
 
 type node[K, V any] struct {
 	m        int
 	children [maxChildren]entry[K, V]
+	// size is the number of valid entries stored in the subtree rooted at
+	// this node.
+	size int
 }
 
 type entry[K, V any] struct {
@@ -33,6 +43,12 @@ type entry[K, V any] struct {
 	val   V
 	valid bool
 	next  *node[K, V]
+
+	// ins is the generation in which the key was last put or removed, and
+	// prev holds the versions of the key that were replaced in earlier
+	// generations, newest first, for IterAt.
+	ins  uint64
+	prev *version[V]
 }
 
 // New returns an empty B-tree.
@@ -43,6 +59,126 @@ func New[K, V any](less g.LessFn[K]) *Tree[K, V] {
 	}
 }
 
+// NewOrdered returns an empty B-tree ordered by the keys' CompareTo method.
+func NewOrdered[K g.Comparable[K], V any]() *Tree[K, V] {
+	return New[K, V](g.LessComparable[K])
+}
+
+// FromPairs returns a tree containing the given key-value pairs. If a key
+// appears more than once, its last value is kept. The tree is bulk loaded
+// bottom-up with full nodes in O(n) time if the keys are already in increasing
+// order, and otherwise the pairs are sorted first, in O(n lg n) time; 'pairs'
+// is not modified.
+func FromPairs[K, V any](pairs []g.KV[K, V], less g.LessFn[K]) *Tree[K, V] {
+	keys, vals := g.Unzip(pairs)
+	if !increasing(keys, less) {
+		keys, vals = g.Unzip(sortPairs(slices.Clone(pairs), less))
+	}
+	return fromSorted(keys, vals, less)
+}
+
+// FromSlices is like FromPairs, but takes the keys and values as parallel
+// slices, mapping keys[i] to vals[i]. It panics if they have different
+// lengths.
+func FromSlices[K, V any](keys []K, vals []V, less g.LessFn[K]) *Tree[K, V] {
+	if len(keys) != len(vals) {
+		panic("btree: keys and values have different lengths")
+	}
+	if !increasing(keys, less) {
+		keys, vals = g.Unzip(sortPairs(g.Zip(keys, vals), less))
+	}
+	return fromSorted(keys, vals, less)
+}
+
+// fromSorted builds a tree from keys in strictly increasing order, filling
+// each node with maxChildren-1 entries (the most it holds without splitting)
+// and then building each level above from the first keys of the level below.
+func fromSorted[K, V any](keys []K, vals []V, less g.LessFn[K]) *Tree[K, V] {
+	t := New[K, V](less)
+	if len(keys) == 0 {
+		return t
+	}
+	var level []*node[K, V]
+	for i := range keys {
+		if i%(maxChildren-1) == 0 {
+			level = append(level, &node[K, V]{})
+		}
+		h := level[len(level)-1]
+		h.children[h.m] = entry[K, V]{
+			key:   keys[i],
+			val:   vals[i],
+			valid: true,
+		}
+		h.m++
+		h.size++
+	}
+	for len(level) > 1 {
+		var up []*node[K, V]
+		for i, child := range level {
+			if i%(maxChildren-1) == 0 {
+				up = append(up, &node[K, V]{})
+			}
+			h := up[len(up)-1]
+			h.children[h.m] = entry[K, V]{
+				key:  child.children[0].key,
+				next: child,
+			}
+			h.m++
+			h.size += child.size
+		}
+		level = up
+		t.height++
+	}
+	t.root = level[0]
+	t.n = len(keys)
+	return t
+}
+
+// increasing returns whether 'keys' are in strictly increasing order.
+func increasing[K any](keys []K, less g.LessFn[K]) bool {
+	for i := 1; i < len(keys); i++ {
+		if !less(keys[i-1], keys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortPairs sorts 'pairs' by key in place and removes pairs with duplicate
+// keys, keeping the last of each, and returns the result.
+func sortPairs[K, V any](pairs []g.KV[K, V], less g.LessFn[K]) []g.KV[K, V] {
+	slices.SortStableFunc(pairs, func(a, b g.KV[K, V]) bool {
+		return less(a.Key, b.Key)
+	})
+	out := pairs[:0]
+	for i, p := range pairs {
+		if i+1 < len(pairs) && !less(p.Key, pairs[i+1].Key) {
+			// a later pair has the same key
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// Freeze makes the tree immutable: any later call to a method that would
+// modify it panics. Reads do not modify the tree, so a frozen tree may
+// be read from multiple goroutines concurrently.
+func (t *Tree[K, V]) Freeze() {
+	t.frozen = true
+}
+
+// Frozen returns true if Freeze has been called on the tree.
+func (t *Tree[K, V]) Frozen() bool {
+	return t.frozen
+}
+
+func (t *Tree[K, V]) checkFrozen(op string) {
+	if t.frozen {
+		panic("btree: " + op + " called on a frozen btree.Tree")
+	}
+}
+
 // Size returns the number of elements in the tree.
 func (t *Tree[K, V]) Size() int {
 	return t.n
@@ -77,14 +213,16 @@ func (t *Tree[K, V]) search(x *node[K, V], key K, height int) (V, bool) {
 
 // Put associates 'key' with 'val'.
 func (t *Tree[K, V]) Put(key K, val V) {
-	u := t.insert(t.root, key, val, t.height, true)
-	t.n++
+	t.checkFrozen("Put")
+	u, delta := t.insert(t.root, key, val, t.height, true)
+	t.n += delta
 	if u == nil {
 		return
 	}
 
 	n := &node[K, V]{
-		m: 2,
+		m:    2,
+		size: t.root.size + u.size,
 	}
 	n.children[0] = entry[K, V]{
 		key:  t.root.children[0].key,
@@ -100,31 +238,38 @@ func (t *Tree[K, V]) Put(key K, val V) {
 
 // Remove removes the value associated with 'key'.
 func (t *Tree[K, V]) Remove(key K) {
+	t.checkFrozen("Remove")
 	_, ok := t.Get(key)
 	if !ok {
 		return
 	}
 	var v V
 	// insert a tombstone to remove an existing value
-	t.insert(t.root, key, v, t.height, false)
-	t.n--
+	_, delta := t.insert(t.root, key, v, t.height, false)
+	t.n += delta
 }
 
-func (t *Tree[K, V]) insert(h *node[K, V], key K, val V, height int, valid bool) *node[K, V] {
+// insert adds the entry to the subtree rooted at 'h'. It returns the new
+// sibling of 'h' if 'h' was split, and the change in the number of valid
+// entries in the tree.
+func (t *Tree[K, V]) insert(h *node[K, V], key K, val V, height int, valid bool) (*node[K, V], int) {
 	ent := entry[K, V]{
 		key:   key,
 		val:   val,
 		valid: valid,
+		ins:   t.gen,
 	}
 
 	var j int
+	var delta int
 	if height == 0 {
 		// leaf node
 		for j = 0; j < h.m; j++ {
 			if g.Compare(key, h.children[j].key, t.less) == 0 {
-				h.children[j].val = val
-				h.children[j].valid = valid
-				return nil
+				delta = btoi(valid) - btoi(h.children[j].valid)
+				t.replace(&h.children[j], val, valid)
+				h.size += delta
+				return nil, delta
 			} else if g.Compare(key, h.children[j].key, t.less) < 0 {
 				break
 			}
@@ -133,9 +278,11 @@ func (t *Tree[K, V]) insert(h *node[K, V], key K, val V, height int, valid bool)
 		// internal node
 		for j = 0; j < h.m; j++ {
 			if (j+1 == h.m) || g.Compare(key, h.children[j+1].key, t.less) < 0 {
-				u := t.insert(h.children[j].next, key, val, height-1, valid)
+				var u *node[K, V]
+				u, delta = t.insert(h.children[j].next, key, val, height-1, valid)
+				h.size += delta
 				if u == nil {
-					return nil
+					return nil, delta
 				}
 				j++
 				ent.key = u.children[0].key
@@ -146,28 +293,127 @@ func (t *Tree[K, V]) insert(h *node[K, V], key K, val V, height int, valid bool)
 		}
 	}
 
+	if height == 0 {
+		delta = btoi(valid)
+		h.size += delta
+	}
+
 	for i := h.m; i > j; i-- {
 		h.children[i] = h.children[i-1]
 	}
 	h.children[j] = ent
 	h.m++
 	if h.m < maxChildren {
-		return nil
+		return nil, delta
 	}
-	return t.split(h)
+	return t.split(h, height), delta
 }
 
-func (t *Tree[K, V]) split(h *node[K, V]) *node[K, V] {
+func (t *Tree[K, V]) split(h *node[K, V], height int) *node[K, V] {
 	n := &node[K, V]{
 		m: maxChildren / 2,
 	}
 	h.m = maxChildren / 2
 	for j := 0; j < maxChildren/2; j++ {
 		n.children[j] = h.children[maxChildren/2+j]
+		// clear the moved entry, so that 'h' does not keep its value alive
+		h.children[maxChildren/2+j] = entry[K, V]{}
+	}
+	n.size = n.count(height)
+	h.size -= n.size
+	return n
+}
+
+// count returns the number of valid entries in the subtree rooted at 'h' by
+// summing its immediate children.
+func (h *node[K, V]) count(height int) int {
+	c := 0
+	for j := 0; j < h.m; j++ {
+		if height == 0 {
+			c += btoi(h.children[j].valid)
+		} else {
+			c += h.children[j].next.size
+		}
+	}
+	return c
+}
+
+// Memory returns an estimate of the number of bytes used by the tree and its
+// nodes. Since removed keys are kept as tombstones until Compact, they are
+// counted too, but the older versions kept for IterAt are not.
+// Memory referenced by pointers in the keys or values is not counted; use
+// MemoryFunc to include it.
+func (t *Tree[K, V]) Memory() int64 {
+	return int64(unsafe.Sizeof(*t)) + int64(t.root.nodes(t.height))*int64(unsafe.Sizeof(node[K, V]{}))
+}
+
+// MemoryFunc is like Memory, but also adds 'sizeOf' for every key-value pair,
+// to account for memory referenced by the keys and values.
+func (t *Tree[K, V]) MemoryFunc(sizeOf func(key K, val V) int64) int64 {
+	total := t.Memory()
+	t.Each(func(key K, val V) {
+		total += sizeOf(key, val)
+	})
+	return total
+}
+
+// nodes returns the number of nodes in the subtree rooted at 'h'.
+func (h *node[K, V]) nodes(height int) int {
+	n := 1
+	if height > 0 {
+		for j := 0; j < h.m; j++ {
+			n += h.children[j].next.nodes(height - 1)
+		}
 	}
 	return n
 }
 
+// CountRange returns the number of keys in the range [lo, hi). If 'lo' is not
+// less than 'hi', the range is empty and 0 is returned. Complexity: O(lg n).
+func (t *Tree[K, V]) CountRange(lo, hi K) int {
+	if !t.less(lo, hi) {
+		return 0
+	}
+	return t.rank(t.root, hi, t.height) - t.rank(t.root, lo, t.height)
+}
+
+// CountLess returns the number of keys strictly less than 'key'. Complexity:
+// O(lg n).
+func (t *Tree[K, V]) CountLess(key K) int {
+	return t.rank(t.root, key, t.height)
+}
+
+// CountAtLeast returns the number of keys greater than or equal to 'key'.
+// Complexity: O(lg n).
+func (t *Tree[K, V]) CountAtLeast(key K) int {
+	return t.n - t.rank(t.root, key, t.height)
+}
+
+// rank returns the number of valid entries in the subtree rooted at 'x' with
+// keys less than 'key'.
+func (t *Tree[K, V]) rank(x *node[K, V], key K, height int) int {
+	r := 0
+	for height > 0 {
+		j := 0
+		for ; j+1 < x.m && !t.less(key, x.children[j+1].key); j++ {
+			r += x.children[j].next.size
+		}
+		x = x.children[j].next
+		height--
+	}
+	for j := 0; j < x.m && t.less(x.children[j].key, key); j++ {
+		r += btoi(x.children[j].valid)
+	}
+	return r
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 // Each calls 'fn' on every node in the tree in order.
 func (t *Tree[K, V]) Each(fn func(key K, val V)) {
 	t.each(t.root, t.height, fn)
@@ -187,3 +433,116 @@ func (t *Tree[K, V]) each(n *node[K, V], height int, fn func(key K, val V)) {
 		}
 	}
 }
+
+// The following methods mirror the iteration API of github.com/google/btree,
+// with the same bounds, so that code using it can be migrated by changing
+// only the types. In all of them 'fn' is called on each key-value pair in the
+// range, and iteration stops early if 'fn' returns false. Removed keys are
+// skipped, and subtrees outside the range are not visited, so the complexity
+// is O(lg n + m), where 'm' is the number of pairs visited.
+
+// Ascend calls 'fn' on every key-value pair in ascending order.
+func (t *Tree[K, V]) Ascend(fn func(key K, val V) bool) {
+	t.ascend(t.root, t.height, nil, nil, fn)
+}
+
+// AscendRange calls 'fn' on every key-value pair in the range
+// [greaterOrEqual, lessThan) in ascending order.
+func (t *Tree[K, V]) AscendRange(greaterOrEqual, lessThan K, fn func(key K, val V) bool) {
+	t.ascend(t.root, t.height, &greaterOrEqual, &lessThan, fn)
+}
+
+// AscendGreaterOrEqual calls 'fn' on every key-value pair in the range
+// [pivot, last] in ascending order.
+func (t *Tree[K, V]) AscendGreaterOrEqual(pivot K, fn func(key K, val V) bool) {
+	t.ascend(t.root, t.height, &pivot, nil, fn)
+}
+
+// Descend calls 'fn' on every key-value pair in descending order.
+func (t *Tree[K, V]) Descend(fn func(key K, val V) bool) {
+	t.descend(t.root, t.height, nil, nil, fn)
+}
+
+// DescendRange calls 'fn' on every key-value pair in the range
+// [lessOrEqual, greaterThan) in descending order, that is, on the keys k with
+// greaterThan < k <= lessOrEqual.
+func (t *Tree[K, V]) DescendRange(lessOrEqual, greaterThan K, fn func(key K, val V) bool) {
+	t.descend(t.root, t.height, &lessOrEqual, &greaterThan, fn)
+}
+
+// DescendLessOrEqual calls 'fn' on every key-value pair in the range
+// [pivot, first] in descending order.
+func (t *Tree[K, V]) DescendLessOrEqual(pivot K, fn func(key K, val V) bool) {
+	t.descend(t.root, t.height, &pivot, nil, fn)
+}
+
+// ascend visits the keys of the subtree rooted at 'h' that are at least 'lo'
+// and less than 'hi' in ascending order, where a nil bound is unbounded. It
+// returns false if the iteration should stop.
+//
+// In an internal node, the key of child j, for j > 0, is a lower bound on the
+// keys in that child and a strict upper bound on the keys in child j-1. The
+// key of child 0 may be stale, so it bounds nothing.
+func (t *Tree[K, V]) ascend(h *node[K, V], height int, lo, hi *K, fn func(key K, val V) bool) bool {
+	for j := 0; j < h.m; j++ {
+		ent := &h.children[j]
+		if height == 0 {
+			if lo != nil && t.less(ent.key, *lo) {
+				continue
+			}
+			if hi != nil && !t.less(ent.key, *hi) {
+				return false
+			}
+			if ent.valid && !fn(ent.key, ent.val) {
+				return false
+			}
+			continue
+		}
+
+		if lo != nil && j+1 < h.m && !t.less(*lo, h.children[j+1].key) {
+			// every key in this child is less than lo
+			continue
+		}
+		if hi != nil && j > 0 && !t.less(ent.key, *hi) {
+			return false
+		}
+		if !t.ascend(ent.next, height-1, lo, hi, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// descend visits the keys of the subtree rooted at 'h' that are at most 'hi'
+// and greater than 'lo' in descending order, where a nil bound is unbounded.
+// It returns false if the iteration should stop.
+func (t *Tree[K, V]) descend(h *node[K, V], height int, hi, lo *K, fn func(key K, val V) bool) bool {
+	for j := h.m - 1; j >= 0; j-- {
+		ent := &h.children[j]
+		if height == 0 {
+			if hi != nil && t.less(*hi, ent.key) {
+				continue
+			}
+			if lo != nil && !t.less(*lo, ent.key) {
+				return false
+			}
+			if ent.valid && !fn(ent.key, ent.val) {
+				return false
+			}
+			continue
+		}
+
+		if hi != nil && j > 0 && t.less(*hi, ent.key) {
+			// every key in this child is greater than hi
+			continue
+		}
+		if lo != nil && j+1 < h.m && !t.less(*lo, h.children[j+1].key) {
+			// every key in this child and the ones before it is at most lo
+			return false
+		}
+		if !t.descend(ent.next, height-1, hi, lo, fn) {
+			return false
+		}
+	}
+	return true
+}
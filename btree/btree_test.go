@@ -3,10 +3,15 @@ package btree_test
 import (
 	"fmt"
 	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"unsafe"
 
 	g "github.com/zyedidia/generic"
 	"github.com/zyedidia/generic/btree"
+	"github.com/zyedidia/generic/internal/testutil"
 )
 
 func checkeq[K any, V comparable](cm *btree.Tree[K, V], get func(k K) (V, bool), t *testing.T) {
@@ -51,6 +56,259 @@ func TestCrossCheck(t *testing.T) {
 	}
 }
 
+func countRange(tree *btree.Tree[int, int], lo, hi int) int {
+	n := 0
+	tree.Each(func(key, val int) {
+		if key >= lo && key < hi {
+			n++
+		}
+	})
+	return n
+}
+
+func TestCountRange(t *testing.T) {
+	tree := btree.New[int, int](g.Less[int])
+
+	const nops = 1000
+	for i := 0; i < nops; i++ {
+		key := rand.Intn(500)
+		if rand.Intn(3) == 0 {
+			tree.Remove(key)
+		} else {
+			tree.Put(key, i)
+		}
+
+		lo, hi := rand.Intn(520)-10, rand.Intn(520)-10
+		if got, want := tree.CountRange(lo, hi), countRange(tree, lo, hi); got != want {
+			t.Fatalf("CountRange(%d, %d) = %d, expected %d", lo, hi, got, want)
+		}
+		if got, want := tree.CountLess(lo), countRange(tree, -1, lo); got != want {
+			t.Fatalf("CountLess(%d) = %d, expected %d", lo, got, want)
+		}
+		if got, want := tree.CountAtLeast(hi), countRange(tree, hi, 500); got != want {
+			t.Fatalf("CountAtLeast(%d) = %d, expected %d", hi, got, want)
+		}
+		if got, want := tree.CountRange(-1, 500), tree.Size(); got != want {
+			t.Fatalf("CountRange over all keys = %d, expected %d", got, want)
+		}
+	}
+}
+
+func BenchmarkCountRange(b *testing.B) {
+	tree := btree.New[int, int](g.Less[int])
+	const n = 1000000
+	for i := 0; i < n; i++ {
+		tree.Put(i, i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lo := rand.Intn(n)
+		tree.CountRange(lo, lo+n/2)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	tree := btree.New[int, int](g.Less[int])
+	for i := 0; i < 100; i++ {
+		tree.Put(i, i)
+	}
+	tree.Freeze()
+	if !tree.Frozen() {
+		t.Fatal("tree should be frozen")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				if v, ok := tree.Get(k); !ok || v != k {
+					t.Errorf("Get(%d) = %d, %v", k, v, ok)
+				}
+			}
+			if n := tree.CountRange(10, 20); n != 10 {
+				t.Errorf("CountRange = %d", n)
+			}
+			tree.Each(func(key, val int) {})
+		}()
+	}
+	wg.Wait()
+
+	testutil.MustPanic(t, "btree.Tree", func() { tree.Put(1, 2) })
+	testutil.MustPanic(t, "btree.Tree", func() { tree.Remove(1) })
+}
+
+func buildTree(n int) *btree.Tree[int, int] {
+	tree := btree.New[int, int](g.Less[int])
+	for i := 0; i < n; i++ {
+		tree.Put(i, i)
+	}
+	return tree
+}
+
+func TestMemory(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		tree := buildTree(n)
+		nodes := btree.Nodes(tree)
+		if nodes < 1 || nodes > 1+n/2 {
+			t.Fatalf("%d nodes for %d keys", nodes, n)
+		}
+		want := int64(unsafe.Sizeof(*tree)) + int64(nodes)*btree.NodeSize[int, int]()
+		if got := tree.Memory(); got != want {
+			t.Fatalf("Memory() = %d for %d keys in %d nodes, want %d", got, n, nodes, want)
+		}
+	}
+}
+
+func BenchmarkMemory(b *testing.B) {
+	testutil.ReportMemory(b, func() *btree.Tree[int, int] {
+		return buildTree(100000)
+	}, (*btree.Tree[int, int]).Memory)
+}
+
+// collect returns the keys visited by an iteration, stopping after 'limit'
+// keys if it is positive.
+func collect(iter func(fn func(key, val int) bool), limit int) []int {
+	keys := []int{}
+	iter(func(key, val int) bool {
+		keys = append(keys, key)
+		return limit <= 0 || len(keys) < limit
+	})
+	return keys
+}
+
+// filter returns the keys in 'keys' for which 'in' returns true, reversed if
+// 'desc' is set.
+func filter(keys []int, desc bool, in func(k int) bool) []int {
+	r := []int{}
+	for _, k := range keys {
+		if in(k) {
+			r = append(r, k)
+		}
+	}
+	if desc {
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+	}
+	return r
+}
+
+func TestAscendDescend(t *testing.T) {
+	tree := btree.New[int, int](g.Less[int])
+	// even keys from 0 to 9998, with a run of removed keys in the middle
+	var keys []int
+	for i := 0; i < 10000; i += 2 {
+		tree.Put(i, i)
+	}
+	for i := 4000; i < 6000; i += 2 {
+		tree.Remove(i)
+	}
+	tree.Each(func(key, val int) { keys = append(keys, key) })
+
+	all := func(int) bool { return true }
+	if got := collect(tree.Ascend, 0); fmt.Sprint(got) != fmt.Sprint(keys) {
+		t.Fatal("Ascend visited the wrong keys")
+	}
+	if got, want := collect(tree.Descend, 0), filter(keys, true, all); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatal("Descend visited the wrong keys")
+	}
+
+	// pivots present, absent, removed, below the minimum and above the
+	// maximum
+	pivots := []int{0, 2, 101, 500, 4000, 4001, 5998, 6000, 9998, -5, 10001}
+	for _, a := range pivots {
+		a := a
+		check := func(name string, iter func(fn func(key, val int) bool), want []int) {
+			t.Helper()
+			for _, limit := range []int{0, 3} {
+				if limit > 0 && len(want) > limit {
+					want = want[:limit]
+				}
+				if got := collect(iter, limit); fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("%s(%d) with limit %d: got %v, want %v", name, a, limit, got, want)
+				}
+			}
+		}
+		check("AscendGreaterOrEqual", func(fn func(key, val int) bool) {
+			tree.AscendGreaterOrEqual(a, fn)
+		}, filter(keys, false, func(k int) bool { return k >= a }))
+		check("DescendLessOrEqual", func(fn func(key, val int) bool) {
+			tree.DescendLessOrEqual(a, fn)
+		}, filter(keys, true, func(k int) bool { return k <= a }))
+
+		for _, b := range pivots {
+			b := b
+			check(fmt.Sprintf("AscendRange(_, %d)", b), func(fn func(key, val int) bool) {
+				tree.AscendRange(a, b, fn)
+			}, filter(keys, false, func(k int) bool { return k >= a && k < b }))
+			check(fmt.Sprintf("DescendRange(_, %d)", b), func(fn func(key, val int) bool) {
+				tree.DescendRange(a, b, fn)
+			}, filter(keys, true, func(k int) bool { return k <= a && k > b }))
+		}
+	}
+}
+
+func TestAscendRangeBounded(t *testing.T) {
+	ncmp := 0
+	tree := btree.New[int, int](func(a, b int) bool {
+		ncmp++
+		return a < b
+	})
+	for i := 0; i < 100000; i++ {
+		tree.Put(i, i)
+	}
+
+	ncmp = 0
+	if n := len(collect(func(fn func(key, val int) bool) { tree.AscendRange(50000, 50010, fn) }, 0)); n != 10 {
+		t.Fatalf("visited %d keys, want 10", n)
+	}
+	if ncmp > 1000 {
+		t.Fatalf("AscendRange made %d comparisons", ncmp)
+	}
+	ncmp = 0
+	collect(func(fn func(key, val int) bool) { tree.DescendRange(50010, 50000, fn) }, 0)
+	if ncmp > 1000 {
+		t.Fatalf("DescendRange made %d comparisons", ncmp)
+	}
+}
+
+// Code using github.com/google/btree keeps its iteration calls and their
+// bounds when migrating; only the construction of the tree, the insertions and
+// the signature of the callbacks change:
+//
+//	-tree := btree.NewOrderedG[int](32)
+//	-tree.ReplaceOrInsert(42)
+//	-tree.AscendGreaterOrEqual(10, func(item int) bool {
+//	+tree := btree.New[int, struct{}](g.Less[int])
+//	+tree.Put(42, struct{}{})
+//	+tree.AscendGreaterOrEqual(10, func(item int, _ struct{}) bool {
+func ExampleTree_AscendGreaterOrEqual() {
+	tree := btree.New[int, string](g.Less[int])
+	for i, name := range []string{"zero", "one", "two", "three", "four", "five"} {
+		tree.Put(i, name)
+	}
+
+	tree.AscendGreaterOrEqual(2, func(key int, val string) bool {
+		fmt.Println(key, val)
+		return key < 4
+	})
+	tree.DescendRange(5, 2, func(key int, val string) bool {
+		fmt.Println(key, val)
+		return true
+	})
+
+	// Output:
+	// 2 two
+	// 3 three
+	// 4 four
+	// 5 five
+	// 4 four
+	// 3 three
+}
+
 func Example() {
 	tree := btree.New[int, string](g.Less[int])
 
@@ -67,3 +325,241 @@ func Example() {
 	// 0 baz
 	// 42 foo
 }
+
+func contents(tree *btree.Tree[int, int]) string {
+	var sb strings.Builder
+	tree.Each(func(key, val int) {
+		fmt.Fprintf(&sb, "%d:%d ", key, val)
+	})
+	return sb.String()
+}
+
+func TestFromPairs(t *testing.T) {
+	for _, n := range []int{0, 1, 63, 64, 1000, 5000} {
+		sorted := make([]int, n)
+		for i := range sorted {
+			sorted[i] = 2 * i
+		}
+		shuffled := rand.Perm(n)
+		withDups := make([]int, 2*n)
+		for i := range withDups {
+			withDups[i] = rand.Intn(n + 1)
+		}
+
+		for _, keys := range [][]int{sorted, shuffled, withDups} {
+			vals := make([]int, len(keys))
+			for i := range keys {
+				vals[i] = i
+			}
+			pairs := g.Zip(keys, vals)
+			orig := append([]g.KV[int, int](nil), pairs...)
+
+			for _, tree := range []*btree.Tree[int, int]{
+				btree.FromPairs(pairs, g.Less[int]),
+				btree.FromSlices(keys, vals, g.Less[int]),
+			} {
+				want := btree.New[int, int](g.Less[int])
+				for i, k := range keys {
+					want.Put(k, i)
+				}
+				if tree.Size() != want.Size() || contents(tree) != contents(want) {
+					t.Fatalf("n=%d: got %s, want %s", n, contents(tree), contents(want))
+				}
+				if got, want := tree.CountRange(n/4, n/2), countRange(want, n/4, n/2); got != want {
+					t.Fatalf("n=%d: CountRange = %d, want %d", n, got, want)
+				}
+
+				// the bulk-loaded tree must stay valid under modification
+				for i := 0; i < 2*n; i++ {
+					k := rand.Intn(2*n + 1)
+					if i%3 == 0 {
+						tree.Remove(k)
+						want.Remove(k)
+					} else {
+						tree.Put(k, i)
+						want.Put(k, i)
+					}
+				}
+				checkeq(tree, want.Get, t)
+				if tree.Size() != want.Size() || contents(tree) != contents(want) {
+					t.Fatalf("n=%d: after modification got %s, want %s", n, contents(tree), contents(want))
+				}
+				if got := collect(tree.Ascend, -1); len(got) != tree.Size() {
+					t.Fatalf("n=%d: Ascend visited %d keys, want %d", n, len(got), tree.Size())
+				}
+			}
+			for i := range pairs {
+				if pairs[i] != orig[i] {
+					t.Fatal("FromPairs modified its argument")
+				}
+			}
+		}
+	}
+
+	testutil.MustPanic(t, "different lengths", func() {
+		btree.FromSlices([]int{1, 2}, []int{1}, g.Less[int])
+	})
+}
+
+// snapshot returns the keys and values in 'm' in key order, formatted like
+// contents.
+func snapshot(m map[int]int) string {
+	tree := btree.New[int, int](g.Less[int])
+	for k, v := range m {
+		tree.Put(k, v)
+	}
+	return contents(tree)
+}
+
+type genIter struct {
+	gen  uint64
+	next func() (int, int, bool)
+	seen strings.Builder
+}
+
+// step advances the iterator by up to 'n' pairs, and returns false once it
+// is exhausted.
+func (it *genIter) step(n int) bool {
+	for i := 0; i < n; i++ {
+		k, v, ok := it.next()
+		if !ok {
+			return false
+		}
+		fmt.Fprintf(&it.seen, "%d:%d ", k, v)
+	}
+	return true
+}
+
+func TestGenerations(t *testing.T) {
+	tree := btree.New[int, int](g.Less[int])
+	cur := make(map[int]int)
+	// want holds the contents of the tree at the end of each generation
+	var want []string
+	var iters []*genIter
+
+	mutate := func(n int) {
+		for i := 0; i < n; i++ {
+			k := rand.Intn(2000)
+			if rand.Intn(3) == 0 {
+				tree.Remove(k)
+				delete(cur, k)
+			} else {
+				v := rand.Intn(1000)
+				tree.Put(k, v)
+				cur[k] = v
+			}
+		}
+	}
+
+	for gen := 0; gen < 30; gen++ {
+		mutate(300)
+		if got := tree.NewGeneration(); got != uint64(gen) {
+			t.Fatalf("NewGeneration() = %d, want %d", got, gen)
+		}
+		want = append(want, snapshot(cur))
+
+		// open an iterator at a random finished generation, and advance the
+		// open ones while the writer continues
+		old := uint64(rand.Intn(len(want)))
+		iters = append(iters, &genIter{gen: old, next: tree.IterAt(old)})
+		for _, it := range iters {
+			it.step(rand.Intn(100))
+		}
+	}
+
+	checkeq(tree, func(k int) (int, bool) {
+		v, ok := cur[k]
+		return v, ok
+	}, t)
+	for _, it := range iters {
+		for it.step(100) {
+		}
+		if got := it.seen.String(); got != want[it.gen] {
+			t.Fatalf("iterator at generation %d saw %s, want %s", it.gen, got, want[it.gen])
+		}
+	}
+	for gen := range want {
+		it := &genIter{next: tree.IterAt(uint64(gen))}
+		for it.step(100) {
+		}
+		if got := it.seen.String(); got != want[gen] {
+			t.Fatalf("generation %d: got %s, want %s", gen, got, want[gen])
+		}
+		if n := strings.Count(want[gen], ":"); tree.SizeAt(uint64(gen)) != n {
+			t.Fatalf("SizeAt(%d) = %d, want %d", gen, tree.SizeAt(uint64(gen)), n)
+		}
+	}
+
+	// compaction only affects the generations before the cutoff
+	const cutoff = 20
+	tree.Compact(cutoff)
+	for gen := cutoff; gen < len(want); gen++ {
+		it := &genIter{next: tree.IterAt(uint64(gen))}
+		for it.step(100) {
+		}
+		if got := it.seen.String(); got != want[gen] {
+			t.Fatalf("after Compact(%d), generation %d: got %s, want %s", cutoff, gen, got, want[gen])
+		}
+	}
+	if tree.Size() != len(cur) {
+		t.Fatalf("Size() = %d after Compact, want %d", tree.Size(), len(cur))
+	}
+
+	// the tree still works after tombstones are dropped from its leaves
+	tree.Compact(tree.Generation() + 1)
+	mutate(2000)
+	checkeq(tree, func(k int) (int, bool) {
+		v, ok := cur[k]
+		return v, ok
+	}, t)
+	if got, want := contents(tree), snapshot(cur); got != want {
+		t.Fatalf("after Compact, tree has %s, want %s", got, want)
+	}
+	if n := tree.CountRange(500, 1500); n != countRange(tree, 500, 1500) {
+		t.Fatalf("CountRange(500, 1500) = %d, want %d", n, countRange(tree, 500, 1500))
+	}
+}
+
+func TestCompactFreesVersions(t *testing.T) {
+	tree := btree.New[int, []byte](g.Less[int])
+	for i := 0; i < 1000; i++ {
+		tree.Put(i, make([]byte, 10000))
+	}
+	old := tree.NewGeneration()
+	for i := 0; i < 1000; i++ {
+		tree.Remove(i)
+	}
+	if tree.Size() != 0 || tree.SizeAt(old) != 1000 {
+		t.Fatalf("Size() = %d, SizeAt(%d) = %d", tree.Size(), old, tree.SizeAt(old))
+	}
+
+	tree.Compact(tree.Generation() + 1)
+	if tree.SizeAt(old) != 0 {
+		t.Fatal("compacted versions are still visible")
+	}
+}
+
+// BenchmarkCompact reports the heap memory freed by compacting away the
+// versions of 1000 removed values of 10000 bytes each.
+func BenchmarkCompact(b *testing.B) {
+	var freed int64
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		tree := btree.New[int, []byte](g.Less[int])
+		for i := 0; i < 1000; i++ {
+			tree.Put(i, make([]byte, 10000))
+		}
+		tree.NewGeneration()
+		for i := 0; i < 1000; i++ {
+			tree.Remove(i)
+		}
+		before := testutil.HeapAlloc()
+		b.StartTimer()
+		tree.Compact(tree.Generation() + 1)
+		b.StopTimer()
+		freed += int64(before) - int64(testutil.HeapAlloc())
+		runtime.KeepAlive(tree)
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(freed)/float64(b.N), "freed-B")
+}
@@ -0,0 +1,27 @@
+package btree
+
+import "unsafe"
+
+// NodeSize returns the size of a node of a Tree[K, V].
+func NodeSize[K, V any]() int64 {
+	return int64(unsafe.Sizeof(node[K, V]{}))
+}
+
+// Nodes returns the number of nodes in the tree, counted level by level.
+func Nodes[K, V any](t *Tree[K, V]) int {
+	n := 0
+	level := []*node[K, V]{t.root}
+	for h := t.height; ; h-- {
+		n += len(level)
+		if h == 0 {
+			return n
+		}
+		var next []*node[K, V]
+		for _, x := range level {
+			for j := 0; j < x.m; j++ {
+				next = append(next, x.children[j].next)
+			}
+		}
+		level = next
+	}
+}
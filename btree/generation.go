@@ -0,0 +1,173 @@
+package btree
+
+// Generations let a reader iterate over the tree as it was at some point,
+// while a writer goes on modifying it, without copying the tree. Every write
+// is recorded in the current generation. A writer calls NewGeneration to
+// finish a batch of writes, and readers call IterAt with the generation it
+// returns to see the tree exactly as it was at the end of that batch. When a
+// key is replaced or removed, the version it replaces is kept for readers of
+// earlier generations until Compact drops it.
+//
+// The tree is not safe for concurrent use: reads at an old generation may be
+// interleaved with writes, but must not run at the same time as them.
+
+// version is a value that a key held from generation ins until generation
+// del, exclusive.
+type version[V any] struct {
+	val      V
+	ins, del uint64
+	prev     *version[V]
+}
+
+// Generation returns the current generation, in which writes are recorded.
+// A new tree starts at generation 0.
+func (t *Tree[K, V]) Generation() uint64 {
+	return t.gen
+}
+
+// NewGeneration ends the current generation and starts the next one. It
+// returns the generation that ended, whose contents no longer change, so
+// that it can be passed to IterAt.
+func (t *Tree[K, V]) NewGeneration() uint64 {
+	t.checkFrozen("NewGeneration")
+	t.gen++
+	return t.gen - 1
+}
+
+// replace records a new value for the key of 'e', or its removal if 'valid'
+// is false, in the current generation. The current value is kept as a
+// version for IterAt if it was live in an earlier generation.
+func (t *Tree[K, V]) replace(e *entry[K, V], val V, valid bool) {
+	if e.valid && e.ins < t.gen {
+		e.prev = &version[V]{
+			val:  e.val,
+			ins:  e.ins,
+			del:  t.gen,
+			prev: e.prev,
+		}
+	}
+	e.val = val
+	e.valid = valid
+	e.ins = t.gen
+}
+
+// at returns the value of the key of 'e' at the end of generation 'gen', or
+// false if the key was not in the tree then.
+func (e *entry[K, V]) at(gen uint64) (V, bool) {
+	if e.ins <= gen {
+		return e.val, e.valid
+	}
+	for v := e.prev; v != nil; v = v.prev {
+		if v.ins <= gen {
+			return v.val, gen < v.del
+		}
+	}
+	var v V
+	return v, false
+}
+
+// IterAt returns an iterator over the key-value pairs in the tree at the end
+// of generation 'gen', in ascending order. Each call to the iterator returns
+// the next pair, or false once there are none left. The tree may be modified
+// between calls, and the iterator still sees generation 'gen', unless Compact
+// drops versions it needs. Each call takes O(lg n) time, not counting removed
+// keys that are skipped.
+func (t *Tree[K, V]) IterAt(gen uint64) func() (K, V, bool) {
+	var last K
+	started := false
+	return func() (K, V, bool) {
+		var after *K
+		if started {
+			after = &last
+		}
+		var key K
+		var val V
+		found := false
+		t.ascendAt(t.root, t.height, gen, after, func(k K, v V) bool {
+			key, val, found = k, v, true
+			return false
+		})
+		if found {
+			last, started = key, true
+		}
+		return key, val, found
+	}
+}
+
+// SizeAt returns the number of keys in the tree at the end of generation
+// 'gen'. Unlike Size, it takes O(n) time.
+func (t *Tree[K, V]) SizeAt(gen uint64) int {
+	n := 0
+	t.ascendAt(t.root, t.height, gen, nil, func(key K, val V) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// ascendAt is like ascend, but visits the keys greater than 'after', or all
+// keys if it is nil, with their values at generation 'gen'.
+func (t *Tree[K, V]) ascendAt(h *node[K, V], height int, gen uint64, after *K, fn func(key K, val V) bool) bool {
+	for j := 0; j < h.m; j++ {
+		ent := &h.children[j]
+		if height == 0 {
+			if after != nil && !t.less(*after, ent.key) {
+				continue
+			}
+			if v, ok := ent.at(gen); ok && !fn(ent.key, v) {
+				return false
+			}
+			continue
+		}
+
+		if after != nil && j+1 < h.m && !t.less(*after, h.children[j+1].key) {
+			// every key in this child is less than or equal to after
+			continue
+		}
+		if !t.ascendAt(ent.next, height-1, gen, after, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compact drops the versions of keys that were replaced or removed before
+// generation 'before', and the tombstones of keys removed before it. This
+// frees their memory, but iterators at generations earlier than 'before' may
+// no longer see the tree as it was; later generations are not affected. To
+// drop every old version and tombstone, use Compact(t.Generation() + 1).
+func (t *Tree[K, V]) Compact(before uint64) {
+	t.checkFrozen("Compact")
+	t.compact(t.root, t.height, before)
+}
+
+func (t *Tree[K, V]) compact(h *node[K, V], height int, before uint64) {
+	if height > 0 {
+		for j := 0; j < h.m; j++ {
+			t.compact(h.children[j].next, height-1, before)
+		}
+		return
+	}
+
+	m := 0
+	for j := 0; j < h.m; j++ {
+		e := h.children[j]
+		// the versions are newest first, so their del decreases
+		for p := &e.prev; *p != nil; p = &(*p).prev {
+			if (*p).del < before {
+				*p = nil
+				break
+			}
+		}
+		if !e.valid && e.ins < before && e.prev == nil {
+			continue
+		}
+		h.children[m] = e
+		m++
+	}
+	// clear the dropped entries so that they can be garbage collected
+	for j := m; j < h.m; j++ {
+		h.children[j] = entry[K, V]{}
+	}
+	h.m = m
+}
@@ -1,11 +1,23 @@
 // Package cache provides an implementation of a key-value store with a maximum
 // size. Once the maximum size is reached, the cache uses a least-recently-used
 // policy to evict old entries. The cache is implemented as a combined hashmap
-// and linked list. This ensures all operations are constant-time.
+// and linked list. This ensures all operations are constant-time. Optionally,
+// an admission filter can reject new entries that are used less often than
+// the entry they would evict, entries can expire after a time to live, and
+// the size of the cache can be measured by the weight of its entries rather
+// than their number.
 package cache
 
 import (
+	"io"
+	"time"
+	"unsafe"
+
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/dump"
+	"github.com/zyedidia/generic/heap"
 	"github.com/zyedidia/generic/list"
+	"github.com/zyedidia/generic/sketch"
 )
 
 // A Cache is an LRU cache for keys and values. Each entry is
@@ -17,6 +29,53 @@ type Cache[K comparable, V any] struct {
 	lru      list.List[KV[K, V]]
 	table    map[K]*list.Node[KV[K, V]]
 	evictCb  func(key K, val V)
+
+	// times holds the timestamps of each entry if WithTimestamps is used,
+	// and is nil otherwise.
+	times map[K]timestamps
+	now   func() time.Time
+
+	// peak is the largest size reached since the last compaction.
+	peak         int
+	compactRatio float64
+
+	// negative holds the expiry time of each negative entry, which is zero
+	// if negative entries do not expire.
+	negative    map[K]time.Time
+	negativeTTL time.Duration
+	ttl         time.Duration
+	// expiry orders the entries that expire by expiry time if WithTTL or
+	// WithNegativeTTL is used, and is nil otherwise. lazyExpiry is the
+	// number of expired entries each Put and lookup removes.
+	expiry     *heap.Indexed[K, time.Time]
+	lazyExpiry int
+
+	// weights holds the weight of each entry if WithWeigher is used, and is
+	// nil otherwise. weight is the total weight of the entries.
+	weigher func(key K, val V) int
+	weights map[K]int
+	weight  int
+
+	// admission estimates how often each key has been used recently, if
+	// WithAdmission is used, and is nil otherwise.
+	admission *sketch.CountMin[K]
+}
+
+// Status is the result of looking up a key with GetStatus.
+type Status int
+
+const (
+	// Miss indicates that the cache has no entry for the key.
+	Miss Status = iota
+	// Hit indicates that the cache has a value for the key.
+	Hit
+	// NegativeHit indicates that the key was recorded as not found with
+	// PutNegative.
+	NegativeHit
+)
+
+type timestamps struct {
+	inserted, accessed time.Time
 }
 
 type KV[K comparable, V any] struct {
@@ -24,38 +83,472 @@ type KV[K comparable, V any] struct {
 	Val V
 }
 
-// New returns a new Cache with the given capacity.
-func New[K comparable, V any](capacity int) *Cache[K, V] {
-	return &Cache[K, V]{
+// An Option configures a Cache with keys of type K and values of type V.
+type Option[K comparable, V any] func(o *options[K, V])
+
+type options[K comparable, V any] struct {
+	timestamps    bool
+	now           func() time.Time
+	compactRatio  float64
+	negativeTTL   time.Duration
+	ttl           time.Duration
+	lazyExpiry    int
+	weigher       func(key K, val V) int
+	admissionHash g.HashFn[K]
+	sketchSize    int
+	evictCb       func(key K, val V)
+}
+
+// WithTimestamps makes the cache record when each entry was inserted and last
+// accessed, so that Age, IdleTime and EachWithMeta can report them.
+func WithTimestamps[K comparable, V any]() Option[K, V] {
+	return func(o *options[K, V]) {
+		o.timestamps = true
+	}
+}
+
+// WithClock sets the function the cache uses to get the current time. The
+// default is time.Now.
+func WithClock[K comparable, V any](now func() time.Time) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.now = now
+	}
+}
+
+// WithAutoCompact makes the cache call Compact automatically after an entry
+// is evicted or removed, whenever the largest size the cache has reached since
+// the last compaction exceeds the current size by more than a factor of
+// 'ratio'. A ratio of 0 disables automatic compaction.
+func WithAutoCompact[K comparable, V any](ratio float64) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.compactRatio = ratio
+	}
+}
+
+// WithNegativeTTL sets how long entries added with PutNegative remain in the
+// cache. Negative entries expire independently of the LRU policy, and are
+// removed the next time they are looked up after expiring. A ttl of 0 (the
+// default) means negative entries never expire.
+func WithNegativeTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithAdmission puts an admission filter in front of the cache, which keeps
+// one-off keys from evicting entries that are used often, as in the TinyLFU
+// policy. A count-min sketch with 'sketchSize' counters per row estimates how
+// often each key has recently been looked up or put, using 'hash' to hash the
+// keys. When the cache is full, a new entry is only added if its key has been
+// used more often than the key of the entry that would be evicted for it. The
+// sketch size should be a few times the capacity of the cache.
+func WithAdmission[K comparable, V any](sketchSize int, hash g.HashFn[K]) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.admissionHash = hash
+		o.sketchSize = sketchSize
+	}
+}
+
+// WithTTL sets how long entries added with Put or PutQuiet remain in the
+// cache after they are inserted or overwritten. Like negative entries with
+// WithNegativeTTL, expired entries are removed the next time they are looked
+// up, by lazy expiry, or by RemoveExpired, without invoking the evict
+// callback. A ttl of 0 (the default) means entries never expire.
+func WithTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.ttl = ttl
+	}
+}
+
+// WithWeigher makes the capacity of the cache a limit on the total weight of
+// its entries, as given by 'weigher', rather than on their number. Entries are
+// evicted until the new entry fits, and an entry that weighs more than the
+// capacity evicts every other entry. The weight of each entry is recorded when
+// it is put, and negative entries are weighed with the zero value.
+func WithWeigher[K comparable, V any](weigher func(key K, val V) int) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.weigher = weigher
+	}
+}
+
+// WithLazyExpiry makes every Put, PutQuiet and lookup also remove up to 'n'
+// expired entries, earliest first, so that expired entries are retired
+// gradually without a background goroutine or calls to RemoveExpired. It has
+// no effect unless WithTTL or WithNegativeTTL is also used.
+func WithLazyExpiry[K comparable, V any](n int) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.lazyExpiry = n
+	}
+}
+
+// WithEvictCallback sets a callback to be invoked before an entry is evicted,
+// as with SetEvictCallback.
+func WithEvictCallback[K comparable, V any](fn func(key K, val V)) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.evictCb = fn
+	}
+}
+
+// New returns a new Cache with the given capacity. The options can be
+// combined freely.
+func New[K comparable, V any](capacity int, opts ...Option[K, V]) *Cache[K, V] {
+	o := options[K, V]{
+		now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := &Cache[K, V]{
 		capacity: capacity,
 		lru:      list.List[KV[K, V]]{},
 		table:    make(map[K]*list.Node[KV[K, V]]),
+		evictCb:  o.evictCb,
+		now:      o.now,
+
+		compactRatio: o.compactRatio,
+		negativeTTL:  o.negativeTTL,
+		ttl:          o.ttl,
+		weigher:      o.weigher,
+	}
+	if o.negativeTTL > 0 || o.ttl > 0 {
+		c.expiry = heap.NewIndexed[K](expiresBefore)
+		c.lazyExpiry = o.lazyExpiry
+	}
+	if o.weigher != nil {
+		c.weights = make(map[K]int)
+	}
+	if o.timestamps {
+		c.times = make(map[K]timestamps)
+	}
+	if o.admissionHash != nil {
+		c.admission = sketch.New(o.sketchSize, o.admissionHash)
 	}
+	return c
 }
 
 // Get returns the entry associated with a given key, and a boolean indicating
-// whether the key exists in the table.
+// whether the key exists in the table. A negative entry is reported as not
+// existing.
 func (t *Cache[K, V]) Get(k K) (V, bool) {
-	if n, ok := t.table[k]; ok {
-		t.lru.Remove(n)
-		t.lru.PushFrontNode(n)
-		return n.Value.Val, true
+	v, s := t.GetStatus(k)
+	return v, s == Hit
+}
+
+// GetStatus returns the entry associated with a given key, and whether the key
+// has a value (Hit), was recorded as not found with PutNegative (NegativeHit),
+// or is not in the cache (Miss). Both hits and negative hits mark the entry as
+// recently used.
+func (t *Cache[K, V]) GetStatus(k K) (V, Status) {
+	var v V
+	n, s := t.touch(k)
+	if s != Hit {
+		return v, s
 	}
+	return n.Value.Val, Hit
+}
+
+// GetWithExpiry is like Get, but also returns the time at which the entry
+// expires, so that callers can refresh entries that are close to expiring.
+// The time is zero if the entry does not expire, which is always the case
+// unless the cache was created with WithTTL. An expired entry is removed and
+// reported as not existing.
+func (t *Cache[K, V]) GetWithExpiry(k K) (V, time.Time, bool) {
 	var v V
-	return v, false
+	n, s := t.touch(k)
+	if s != Hit {
+		return v, time.Time{}, false
+	}
+	var exp time.Time
+	if t.expiry != nil {
+		exp, _ = t.expiry.Priority(k)
+	}
+	return n.Value.Val, exp, true
+}
+
+// Touch marks the entry associated with the given key as recently used,
+// without copying its value, and returns whether the key has a value in the
+// cache. Like GetStatus, it also marks negative entries as recently used.
+func (t *Cache[K, V]) Touch(k K) bool {
+	_, s := t.touch(k)
+	return s == Hit
+}
+
+// touch looks up 'k' and moves its entry to the front of the LRU list. An
+// expired entry is removed and reported as a miss.
+func (t *Cache[K, V]) touch(k K) (*list.Node[KV[K, V]], Status) {
+	if t.lazyExpiry > 0 {
+		t.removeExpired(t.lazyExpiry)
+	}
+	if t.admission != nil {
+		t.admission.Add(k)
+	}
+	n, ok := t.table[k]
+	if !ok {
+		return nil, Miss
+	}
+	if t.expired(k) {
+		t.Remove(k)
+		return nil, Miss
+	}
+
+	t.lru.Remove(n)
+	t.lru.PushFrontNode(n)
+	if t.times != nil {
+		ts := t.times[k]
+		ts.accessed = t.now()
+		t.times[k] = ts
+	}
+	if _, neg := t.negative[k]; neg {
+		return n, NegativeHit
+	}
+	return n, Hit
+}
+
+// expired returns whether 'k' has an expiry time that has passed.
+func (t *Cache[K, V]) expired(k K) bool {
+	if t.expiry == nil {
+		return false
+	}
+	exp, ok := t.expiry.Priority(k)
+	return ok && !t.now().Before(exp)
+}
+
+// PutNegative records that the given key has no value, replacing any existing
+// entry for it. Negative entries count toward the capacity of the cache and
+// are evicted like any other entry, but they are skipped by Each and the
+// evict callback is not invoked for them. If the cache was created with
+// WithNegativeTTL, the entry also expires after that duration.
+func (t *Cache[K, V]) PutNegative(k K) {
+	var zero V
+	if !t.put(k, zero) {
+		return
+	}
+	if t.negative == nil {
+		t.negative = make(map[K]time.Time)
+	}
+	var exp time.Time
+	if t.negativeTTL > 0 {
+		exp = t.now().Add(t.negativeTTL)
+	}
+	t.negative[k] = exp
+	if t.expiry == nil {
+		return
+	}
+	// a negative entry does not take the TTL of a positive one
+	if exp.IsZero() {
+		t.expiry.Remove(k)
+	} else {
+		t.expiry.Push(k, exp)
+	}
+}
+
+// unmarkNegative removes any record of 'k' being a negative entry.
+func (t *Cache[K, V]) unmarkNegative(k K) {
+	delete(t.negative, k)
+	if t.expiry != nil {
+		t.expiry.Remove(k)
+	}
+}
+
+func expiresBefore(a, b time.Time) bool {
+	return a.Before(b)
+}
+
+// RemoveExpired removes every expired entry, and returns how many were
+// removed. Entries that expire are indexed by expiry time, so it takes
+// O(k lg n) time to remove 'k' of 'n' such entries, rather than scanning the
+// cache. The evict callback is not invoked for them.
+func (t *Cache[K, V]) RemoveExpired() int {
+	return t.removeExpired(-1)
+}
+
+// removeExpired removes up to 'max' expired entries, earliest first, or all
+// of them if 'max' is negative.
+func (t *Cache[K, V]) removeExpired(max int) int {
+	if t.expiry == nil {
+		return 0
+	}
+	now := t.now()
+	n := 0
+	for ; n != max; n++ {
+		k, exp, ok := t.expiry.Peek()
+		if !ok || now.Before(exp) {
+			break
+		}
+		t.Remove(k)
+	}
+	return n
 }
 
-// Put adds a new key-entry pair to the table.
+// Put adds a new key-entry pair to the table. If the cache was created with
+// WithAdmission, a new entry may be rejected when the cache is full; use
+// PutIfAdmitted to find out whether it was added.
 func (t *Cache[K, V]) Put(k K, e V) {
+	t.put(k, e)
+}
+
+// PutIfAdmitted is like Put, but returns whether the entry was added. It only
+// returns false if the cache was created with WithAdmission.
+func (t *Cache[K, V]) PutIfAdmitted(k K, e V) bool {
+	return t.put(k, e)
+}
+
+func (t *Cache[K, V]) put(k K, e V) bool {
+	if t.lazyExpiry > 0 {
+		t.removeExpired(t.lazyExpiry)
+	}
+	w := t.weigh(k, e)
+	if !t.admit(k, w) {
+		return false
+	}
+	t.unmarkNegative(k)
+	t.setExpiry(k)
+	if t.times != nil {
+		now := t.now()
+		t.times[k] = timestamps{
+			inserted: now,
+			accessed: now,
+		}
+	}
+
 	if n, ok := t.table[k]; ok {
 		n.Value.Val = e
 		t.lru.Remove(n)
 		t.lru.PushFrontNode(n)
+		t.setWeight(k, w)
+		t.shrink(n)
+		return true
+	}
+
+	n := &list.Node[KV[K, V]]{
+		Value: KV[K, V]{
+			Key: k,
+			Val: e,
+		},
+	}
+	t.lru.PushFrontNode(n)
+	t.insert(n, w)
+	return true
+}
+
+// insert adds the entry 'n', which is already in the LRU list, to the table
+// with weight 'w', and evicts other entries until the cache is within its
+// capacity.
+func (t *Cache[K, V]) insert(n *list.Node[KV[K, V]], w int) {
+	t.table[n.Value.Key] = n
+	t.setWeight(n.Value.Key, w)
+	t.shrink(n)
+	if len(t.table) > t.peak {
+		t.peak = len(t.table)
+	}
+}
+
+// shrink evicts the least recently used entries other than 'keep' until the
+// cache is within its capacity.
+func (t *Cache[K, V]) shrink(keep *list.Node[KV[K, V]]) {
+	for t.used() > t.capacity {
+		n := t.lru.Back
+		if n == keep {
+			n = n.Prev
+		}
+		if n == nil {
+			return
+		}
+		t.evict(n)
+	}
+}
+
+// used returns how much of the capacity the entries take up: their total
+// weight if the cache has a weigher, and their number otherwise.
+func (t *Cache[K, V]) used() int {
+	if t.weights == nil {
+		return len(t.table)
+	}
+	return t.weight
+}
+
+// weigh returns the weight of an entry, which is 1 if the cache has no
+// weigher.
+func (t *Cache[K, V]) weigh(k K, v V) int {
+	if t.weigher == nil {
+		return 1
+	}
+	return t.weigher(k, v)
+}
+
+func (t *Cache[K, V]) setWeight(k K, w int) {
+	if t.weights != nil {
+		t.weight += w - t.weights[k]
+		t.weights[k] = w
+	}
+}
+
+func (t *Cache[K, V]) removeWeight(k K) {
+	if t.weights != nil {
+		t.weight -= t.weights[k]
+		delete(t.weights, k)
+	}
+}
+
+// setExpiry sets the expiry time of 'k' if the cache was created with
+// WithTTL.
+func (t *Cache[K, V]) setExpiry(k K) {
+	if t.ttl > 0 {
+		t.expiry.Push(k, t.now().Add(t.ttl))
+	}
+}
+
+// admit records a use of 'k' in the admission sketch, if there is one, and
+// returns whether an entry for 'k' with weight 'w' may be put in the cache: if
+// there is room for it, it replaces an existing entry, or 'k' has been used
+// more often than the key of the entry that would be evicted.
+func (t *Cache[K, V]) admit(k K, w int) bool {
+	if t.admission == nil {
+		return true
+	}
+	t.admission.Add(k)
+	if _, ok := t.table[k]; ok || t.used()+w <= t.capacity || t.lru.Back == nil {
+		return true
+	}
+	return t.admission.Estimate(k) > t.admission.Estimate(t.lru.Back.Value.Key)
+}
+
+// PutQuiet is like Put, but does not mark the entry as recently used. If the
+// key is already in the cache, its value is replaced and the entry keeps its
+// position in the eviction order, and its access time is not updated. A new
+// entry is added as the least recently used, so it is the next to be evicted
+// unless it is used first. If the cache is full, the least recently used
+// existing entry is evicted to make room before the new entry is added, so
+// the new entry itself is never evicted by the call that adds it. Like Put,
+// the new entry may be rejected by the admission filter.
+func (t *Cache[K, V]) PutQuiet(k K, e V) {
+	if t.lazyExpiry > 0 {
+		t.removeExpired(t.lazyExpiry)
+	}
+	w := t.weigh(k, e)
+	if !t.admit(k, w) {
+		return
+	}
+	t.unmarkNegative(k)
+	t.setExpiry(k)
+	if n, ok := t.table[k]; ok {
+		n.Value.Val = e
+		if t.times != nil {
+			ts := t.times[k]
+			ts.inserted = t.now()
+			t.times[k] = ts
+		}
+		t.setWeight(k, w)
+		t.shrink(n)
 		return
 	}
 
-	if len(t.table) == t.capacity {
-		t.evict()
+	if t.times != nil {
+		now := t.now()
+		t.times[k] = timestamps{
+			inserted: now,
+			accessed: now,
+		}
 	}
 	n := &list.Node[KV[K, V]]{
 		Value: KV[K, V]{
@@ -63,17 +556,21 @@ func (t *Cache[K, V]) Put(k K, e V) {
 			Val: e,
 		},
 	}
-	t.lru.PushFrontNode(n)
-	t.table[k] = n
+	t.lru.PushBackNode(n)
+	t.insert(n, w)
 }
 
-func (t *Cache[K, V]) evict() {
-	entry := t.lru.Back.Value
-	if t.evictCb != nil {
+func (t *Cache[K, V]) evict(n *list.Node[KV[K, V]]) {
+	entry := n.Value
+	if _, neg := t.negative[entry.Key]; !neg && t.evictCb != nil {
 		t.evictCb(entry.Key, entry.Val)
 	}
-	t.lru.Remove(t.lru.Back)
+	t.lru.Remove(n)
 	delete(t.table, entry.Key)
+	delete(t.times, entry.Key)
+	t.removeWeight(entry.Key)
+	t.unmarkNegative(entry.Key)
+	t.autoCompact()
 }
 
 // Remove causes the entry associated with the given key to be immediately
@@ -82,23 +579,158 @@ func (t *Cache[K, V]) Remove(k K) {
 	if n, ok := t.table[k]; ok {
 		t.lru.Remove(n)
 		delete(t.table, k)
+		delete(t.times, k)
+		t.removeWeight(k)
+		t.unmarkNegative(k)
+		t.autoCompact()
 	}
 }
 
+// Clear removes every entry from the cache, without invoking the evict
+// callback. The capacity and options of the cache are unchanged.
+func (t *Cache[K, V]) Clear() {
+	t.lru = list.List[KV[K, V]]{}
+	t.table = make(map[K]*list.Node[KV[K, V]])
+	if t.times != nil {
+		t.times = make(map[K]timestamps)
+	}
+	t.negative = nil
+	if t.expiry != nil {
+		t.expiry = heap.NewIndexed[K](expiresBefore)
+	}
+	if t.weights != nil {
+		t.weights = make(map[K]int)
+		t.weight = 0
+	}
+	t.peak = 0
+}
+
+// DrainAll removes every entry from the cache and returns them, from least to
+// most recently used, for example to write them back to a store when the
+// cache is closed. The evict callback is invoked on each returned entry in
+// the same order, after the cache has been emptied. Negative entries are
+// removed but not returned.
+func (t *Cache[K, V]) DrainAll() []KV[K, V] {
+	kvs := make([]KV[K, V], 0, len(t.table)-len(t.negative))
+	t.lru.Back.EachReverse(func(kv KV[K, V]) {
+		if _, neg := t.negative[kv.Key]; !neg {
+			kvs = append(kvs, kv)
+		}
+	})
+	t.Clear()
+	if t.evictCb != nil {
+		for _, kv := range kvs {
+			t.evictCb(kv.Key, kv.Val)
+		}
+	}
+	return kvs
+}
+
+// Compact rebuilds the internal table of the cache sized to the current
+// number of entries, releasing the memory retained from when the cache held
+// more entries. The order of entries is unchanged and no callbacks are
+// invoked.
+func (t *Cache[K, V]) Compact() {
+	table := make(map[K]*list.Node[KV[K, V]], len(t.table))
+	for k, n := range t.table {
+		table[k] = n
+	}
+	t.table = table
+	if t.times != nil {
+		times := make(map[K]timestamps, len(t.times))
+		for k, ts := range t.times {
+			times[k] = ts
+		}
+		t.times = times
+	}
+	if t.negative != nil {
+		negative := make(map[K]time.Time, len(t.negative))
+		for k, exp := range t.negative {
+			negative[k] = exp
+		}
+		t.negative = negative
+	}
+	if t.weights != nil {
+		weights := make(map[K]int, len(t.weights))
+		for k, w := range t.weights {
+			weights[k] = w
+		}
+		t.weights = weights
+	}
+	t.peak = len(t.table)
+}
+
+func (t *Cache[K, V]) autoCompact() {
+	if t.compactRatio > 0 && float64(t.peak) > t.compactRatio*float64(len(t.table)) {
+		t.Compact()
+	}
+}
+
+// Memory returns an estimate of the number of bytes used by the cache: its
+// entries and the maps that index them. Since Go maps do not shrink, the maps
+// are estimated from the largest size the cache has reached since it was last
+// compacted. Memory referenced by pointers in the keys or values is not
+// counted; use MemoryFunc to include it.
+func (t *Cache[K, V]) Memory() int64 {
+	var k K
+	var n *list.Node[KV[K, V]]
+	total := int64(unsafe.Sizeof(*t))
+	total += int64(len(t.table)) * int64(unsafe.Sizeof(*n))
+	total += mapMemory(t.peak, unsafe.Sizeof(k)+unsafe.Sizeof(n))
+	if t.times != nil {
+		total += mapMemory(t.peak, unsafe.Sizeof(k)+unsafe.Sizeof(timestamps{}))
+	}
+	if t.negative != nil {
+		total += mapMemory(len(t.negative), unsafe.Sizeof(k)+unsafe.Sizeof(time.Time{}))
+	}
+	if t.weights != nil {
+		total += mapMemory(t.peak, unsafe.Sizeof(k)+unsafe.Sizeof(t.weight))
+	}
+	if t.expiry != nil {
+		// the index holds a key and expiry time per entry, and maps keys to
+		// their positions
+		n := t.expiry.Size()
+		total += int64(n) * int64(unsafe.Sizeof(k)+unsafe.Sizeof(time.Time{}))
+		total += mapMemory(n, unsafe.Sizeof(k)+unsafe.Sizeof(n))
+	}
+	return total
+}
+
+// MemoryFunc is like Memory, but also adds 'sizeOf' for every entry, to
+// account for memory referenced by the keys and values.
+func (t *Cache[K, V]) MemoryFunc(sizeOf func(key K, val V) int64) int64 {
+	total := t.Memory()
+	for n := t.lru.Front; n != nil; n = n.Next {
+		total += sizeOf(n.Value.Key, n.Value.Val)
+	}
+	return total
+}
+
+// mapMemory estimates the number of bytes used by a Go map that has held up
+// to 'n' entries of 'entrySize' bytes each. Maps use power-of-two sized tables
+// that are at most 7/8 full, with a control byte per slot.
+func mapMemory(n int, entrySize uintptr) int64 {
+	slots := 8
+	for slots*7/8 < n {
+		slots *= 2
+	}
+	return int64(slots) * (int64(entrySize) + 1)
+}
+
 // Resize changes the maximum capacity for this cache to 'capacity'.
 func (t *Cache[K, V]) Resize(capacity int) {
 	t.capacity = capacity
-	for len(t.table) > capacity {
-		t.evict()
-	}
+	t.shrink(nil)
 }
 
-// Size returns the number of active elements in the cache.
+// Size returns the number of active elements in the cache, including negative
+// entries.
 func (t *Cache[K, V]) Size() int {
 	return len(t.table)
 }
 
-// Capacity returns the maximum capacity of the cache.
+// Capacity returns the maximum capacity of the cache, which limits the total
+// weight of the entries if the cache was created with WithWeigher.
 func (t *Cache[K, V]) Capacity() int {
 	return t.capacity
 }
@@ -107,10 +739,69 @@ func (t *Cache[K, V]) Capacity() int {
 // least recently used.
 func (t *Cache[K, V]) Each(fn func(key K, val V)) {
 	t.lru.Front.Each(func(kv KV[K, V]) {
-		fn(kv.Key, kv.Val)
+		if _, neg := t.negative[kv.Key]; !neg {
+			fn(kv.Key, kv.Val)
+		}
 	})
 }
 
+// EachUntil calls 'fn' on every value in the cache, from most recently used to
+// least recently used, until 'fn' returns false.
+func (t *Cache[K, V]) EachUntil(fn func(key K, val V) bool) {
+	for n := t.lru.Front; n != nil; n = n.Next {
+		if _, neg := t.negative[n.Value.Key]; neg {
+			continue
+		}
+		if !fn(n.Value.Key, n.Value.Val) {
+			return
+		}
+	}
+}
+
+// DumpNDJSON writes every entry in the cache to 'w', from most recently used
+// to least recently used, as a JSON object per line. At most 'limit' entries
+// are written; if 'limit' is zero or negative all entries are written. Dumping
+// does not affect the recency of entries.
+func (t *Cache[K, V]) DumpNDJSON(w io.Writer, limit int) error {
+	return dump.DumpMap(w, t.EachUntil, dump.JSONPair[K, V], limit)
+}
+
+// EachWithMeta calls 'fn' on every value in the cache, from most recently used
+// to least recently used, along with the time the entry was inserted and the
+// time it was last accessed. The times are zero unless the cache was created
+// with WithTimestamps.
+func (t *Cache[K, V]) EachWithMeta(fn func(key K, val V, inserted, accessed time.Time)) {
+	t.lru.Front.Each(func(kv KV[K, V]) {
+		if _, neg := t.negative[kv.Key]; neg {
+			return
+		}
+		ts := t.times[kv.Key]
+		fn(kv.Key, kv.Val, ts.inserted, ts.accessed)
+	})
+}
+
+// Age returns how long ago the entry associated with 'k' was inserted or last
+// overwritten by Put. It returns false if there is no such entry or the cache
+// was not created with WithTimestamps.
+func (t *Cache[K, V]) Age(k K) (time.Duration, bool) {
+	ts, ok := t.times[k]
+	if !ok {
+		return 0, false
+	}
+	return t.now().Sub(ts.inserted), true
+}
+
+// IdleTime returns how long ago the entry associated with 'k' was last
+// accessed by Get or Put. It returns false if there is no such entry or the
+// cache was not created with WithTimestamps.
+func (t *Cache[K, V]) IdleTime(k K) (time.Duration, bool) {
+	ts, ok := t.times[k]
+	if !ok {
+		return 0, false
+	}
+	return t.now().Sub(ts.accessed), true
+}
+
 // SetEvictCallback sets a callback to be invoked before an entry is evicted.
 // This replaces any prior callback set by this method.
 func (t *Cache[K, V]) SetEvictCallback(fn func(key K, val V)) {
@@ -2,10 +2,850 @@ package cache_test
 
 import (
 	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
 
+	g "github.com/zyedidia/generic"
 	"github.com/zyedidia/generic/cache"
+	"github.com/zyedidia/generic/internal/testutil"
+	"github.com/zyedidia/generic/list"
 )
 
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func checkDuration(t *testing.T, name string, got time.Duration, ok bool, want time.Duration) {
+	t.Helper()
+	if !ok {
+		t.Fatalf("%s: entry not found", name)
+	}
+	if got != want {
+		t.Fatalf("%s: got %v, want %v", name, got, want)
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	c := cache.New[int, int](2, cache.WithTimestamps[int, int](), cache.WithClock[int, int](clock.now))
+
+	c.Put(1, 1)
+	clock.advance(time.Second)
+	age, ok := c.Age(1)
+	checkDuration(t, "age after put", age, ok, time.Second)
+	idle, ok := c.IdleTime(1)
+	checkDuration(t, "idle after put", idle, ok, time.Second)
+
+	c.Get(1)
+	clock.advance(2 * time.Second)
+	age, ok = c.Age(1)
+	checkDuration(t, "age after get", age, ok, 3*time.Second)
+	idle, ok = c.IdleTime(1)
+	checkDuration(t, "idle after get", idle, ok, 2*time.Second)
+
+	c.Put(1, 10) // overwrite
+	clock.advance(time.Second)
+	age, ok = c.Age(1)
+	checkDuration(t, "age after overwrite", age, ok, time.Second)
+
+	c.EachWithMeta(func(key, val int, inserted, accessed time.Time) {
+		if want := clock.t.Add(-time.Second); !inserted.Equal(want) || !accessed.Equal(want) {
+			t.Fatalf("meta mismatch: %v %v, want %v", inserted, accessed, want)
+		}
+	})
+
+	c.Put(2, 2)
+	c.Put(3, 3) // evicts 1
+	if _, ok := c.Age(1); ok {
+		t.Fatal("evicted entry should have no age")
+	}
+	c.Remove(2)
+	if _, ok := c.IdleTime(2); ok {
+		t.Fatal("removed entry should have no idle time")
+	}
+}
+
+func TestNoTimestamps(t *testing.T) {
+	c := cache.New[int, int](2)
+	c.Put(1, 1)
+	c.Get(1)
+	if _, ok := c.Age(1); ok {
+		t.Fatal("age recorded without WithTimestamps")
+	}
+	if _, ok := c.IdleTime(1); ok {
+		t.Fatal("idle time recorded without WithTimestamps")
+	}
+	c.EachWithMeta(func(key, val int, inserted, accessed time.Time) {
+		if !inserted.IsZero() || !accessed.IsZero() {
+			t.Fatal("timestamps recorded without WithTimestamps")
+		}
+	})
+}
+
+func TestNegative(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	c := cache.New[int, int](3, cache.WithNegativeTTL[int, int](time.Minute), cache.WithClock[int, int](clock.now))
+
+	var evicted []int
+	c.SetEvictCallback(func(key, val int) {
+		evicted = append(evicted, key)
+	})
+
+	c.Put(1, 10)
+	c.PutNegative(2)
+
+	if v, s := c.GetStatus(1); s != cache.Hit || v != 10 {
+		t.Fatalf("GetStatus(1) = %d, %d, want hit", v, s)
+	}
+	if _, s := c.GetStatus(2); s != cache.NegativeHit {
+		t.Fatalf("GetStatus(2) = %d, want negative hit", s)
+	}
+	if _, s := c.GetStatus(3); s != cache.Miss {
+		t.Fatalf("GetStatus(3) = %d, want miss", s)
+	}
+	if _, ok := c.Get(2); ok {
+		t.Fatal("Get found a negative entry")
+	}
+	if c.Size() != 2 {
+		t.Fatalf("negative entry should count toward size, got %d", c.Size())
+	}
+	c.Each(func(key, val int) {
+		if key == 2 {
+			t.Fatal("Each visited a negative entry")
+		}
+	})
+
+	clock.advance(59 * time.Second)
+	if _, s := c.GetStatus(2); s != cache.NegativeHit {
+		t.Fatalf("negative entry expired early: %d", s)
+	}
+	clock.advance(time.Second)
+	if _, s := c.GetStatus(2); s != cache.Miss {
+		t.Fatalf("negative entry should have expired, got %d", s)
+	}
+	if _, s := c.GetStatus(1); s != cache.Hit {
+		t.Fatal("positive entry should not expire")
+	}
+	if c.Size() != 1 {
+		t.Fatalf("expired entry not removed, size %d", c.Size())
+	}
+
+	// a negative entry can be replaced by a value and vice versa
+	c.PutNegative(4)
+	c.Put(4, 40)
+	if v, s := c.GetStatus(4); s != cache.Hit || v != 40 {
+		t.Fatalf("GetStatus(4) = %d, %d, want hit", v, s)
+	}
+	c.PutNegative(1)
+	if _, s := c.GetStatus(1); s != cache.NegativeHit {
+		t.Fatalf("GetStatus(1) = %d, want negative hit", s)
+	}
+
+	// negative entries are evicted by LRU without invoking the callback
+	c.Put(5, 50)
+	c.Put(6, 60) // evicts 4
+	c.Put(7, 70) // evicts 1, which is negative
+	if len(evicted) != 1 || evicted[0] != 4 {
+		t.Fatalf("evicted %v, want [4]", evicted)
+	}
+	if _, s := c.GetStatus(1); s != cache.Miss {
+		t.Fatalf("evicted negative entry still present: %d", s)
+	}
+}
+
+func TestRemoveExpired(t *testing.T) {
+	const ttl = time.Minute
+	type entry struct {
+		neg bool
+		exp time.Time
+	}
+	for _, lazy := range []int{0, 3} {
+		clock := &fakeClock{t: time.Unix(1000, 0)}
+		c := cache.New[int, int](1000, cache.WithNegativeTTL[int, int](ttl), cache.WithClock[int, int](clock.now), cache.WithLazyExpiry[int, int](lazy))
+		model := make(map[int]entry)
+		r := rand.New(rand.NewSource(1))
+
+		for i := 0; i < 5000; i++ {
+			k := r.Intn(100)
+			switch r.Intn(5) {
+			case 0, 1:
+				c.PutNegative(k)
+				model[k] = entry{true, clock.t.Add(ttl)}
+			case 2:
+				c.Put(k, k)
+				model[k] = entry{}
+			case 3:
+				c.Remove(k)
+				delete(model, k)
+			case 4:
+				clock.advance(time.Duration(r.Intn(20)) * time.Second)
+			}
+			if i%10 != 0 {
+				continue
+			}
+
+			expired := 0
+			for k, e := range model {
+				if e.neg && !clock.t.Before(e.exp) {
+					expired++
+					delete(model, k)
+				}
+			}
+			n := c.RemoveExpired()
+			if lazy == 0 && n != expired {
+				t.Fatalf("RemoveExpired removed %d entries, want %d", n, expired)
+			}
+			if c.Size() != len(model) {
+				t.Fatalf("lazy %d: size %d after RemoveExpired, want %d", lazy, c.Size(), len(model))
+			}
+			for k, e := range model {
+				want := cache.Hit
+				if e.neg {
+					want = cache.NegativeHit
+				}
+				if _, s := c.GetStatus(k); s != want {
+					t.Fatalf("lazy %d: GetStatus(%d) = %d, want %d", lazy, k, s, want)
+				}
+			}
+		}
+	}
+}
+
+func TestLazyExpiry(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	c := cache.New[int, int](100, cache.WithNegativeTTL[int, int](time.Minute), cache.WithClock[int, int](clock.now), cache.WithLazyExpiry[int, int](2))
+	for i := 0; i < 10; i++ {
+		c.PutNegative(i)
+	}
+	c.Put(100, 100)
+	clock.advance(time.Minute)
+
+	// each lookup retires two expired entries
+	for i := 1; i <= 3; i++ {
+		c.Get(100)
+		if c.Size() != 11-2*i {
+			t.Fatalf("size %d after %d lookups, want %d", c.Size(), i, 11-2*i)
+		}
+	}
+	c.Put(101, 101)
+	if c.Size() != 4 {
+		t.Fatalf("size %d after Put, want 4", c.Size())
+	}
+	c.PutQuiet(102, 102)
+	if c.Size() != 3 {
+		t.Fatalf("size %d after PutQuiet, want 3", c.Size())
+	}
+	if n := c.RemoveExpired(); n != 0 || c.Size() != 3 {
+		t.Fatalf("RemoveExpired removed %d entries, leaving %d", n, c.Size())
+	}
+}
+
+func BenchmarkRemoveExpired(b *testing.B) {
+	const n = 1000000
+	const expired = n / 100
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	c := cache.New[int, int](n, cache.WithNegativeTTL[int, int](time.Hour), cache.WithClock[int, int](clock.now))
+	start := clock.t
+	for i := expired; i < n; i++ {
+		c.PutNegative(i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		// add entries that expire before the others
+		clock.t = start.Add(-time.Hour)
+		for k := 0; k < expired; k++ {
+			c.PutNegative(k)
+		}
+		clock.t = start
+		b.StartTimer()
+
+		if c.RemoveExpired() != expired {
+			b.Fatal("wrong number of entries removed")
+		}
+	}
+}
+
+func TestCompactReclaimsMemory(t *testing.T) {
+	const n = 200000
+	c := cache.New[int, int](n)
+	for i := 0; i < n; i++ {
+		c.Put(i, i)
+	}
+	c.Resize(10)
+
+	// the table keeps its size until it is compacted
+	if got, want := c.Memory(), memory(10, n); got != want {
+		t.Fatalf("Memory() = %d before Compact, want %d", got, want)
+	}
+	c.Compact()
+	if got, want := c.Memory(), memory(10, 10); got != want {
+		t.Fatalf("Memory() = %d after Compact, want %d", got, want)
+	}
+}
+
+// BenchmarkCompact reports the heap memory freed by compacting a cache that
+// has shrunk from 200000 entries to 10.
+func BenchmarkCompact(b *testing.B) {
+	const n = 200000
+	var freed int64
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		c := cache.New[int, int](n)
+		for i := 0; i < n; i++ {
+			c.Put(i, i)
+		}
+		c.Resize(10)
+		before := testutil.HeapAlloc()
+		b.StartTimer()
+		c.Compact()
+		b.StopTimer()
+		freed += int64(before) - int64(testutil.HeapAlloc())
+		runtime.KeepAlive(c)
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(freed)/float64(b.N), "freed-B")
+}
+
+func contents(c *cache.Cache[int, int]) string {
+	var s string
+	c.Each(func(key, val int) {
+		s += fmt.Sprintf("%d:%d ", key, val)
+	})
+	return s
+}
+
+func TestCompactPreservesBehavior(t *testing.T) {
+	var evicted1, evicted2 []int
+	c1 := cache.New[int, int](50)
+	c1.SetEvictCallback(func(key, val int) { evicted1 = append(evicted1, key) })
+	c2 := cache.New[int, int](50, cache.WithTimestamps[int, int](), cache.WithAutoCompact[int, int](1.5))
+	c2.SetEvictCallback(func(key, val int) { evicted2 = append(evicted2, key) })
+
+	for i := 0; i < 5000; i++ {
+		k := rand.Intn(100)
+		switch rand.Intn(4) {
+		case 0:
+			c1.Get(k)
+			c2.Get(k)
+		case 1:
+			c1.Remove(k)
+			c2.Remove(k)
+		default:
+			c1.Put(k, i)
+			c2.Put(k, i)
+		}
+		if i%100 == 0 {
+			c2.Compact()
+		}
+		if contents(c1) != contents(c2) {
+			t.Fatalf("contents diverged after compaction:\n%s\n%s", contents(c1), contents(c2))
+		}
+	}
+	if fmt.Sprint(evicted1) != fmt.Sprint(evicted2) {
+		t.Fatal("compaction changed evictions")
+	}
+	c2.Each(func(key, val int) {
+		if _, ok := c2.Age(key); !ok {
+			t.Fatalf("timestamps of %d lost by compaction", key)
+		}
+	})
+}
+
+func TestPutQuietAndTouch(t *testing.T) {
+	var evicted []int
+	c := cache.New[int, int](3)
+	c.SetEvictCallback(func(key, val int) { evicted = append(evicted, key) })
+
+	// entries are listed from most to least recently used
+	steps := []struct {
+		op   func()
+		want string
+	}{
+		{func() { c.Put(1, 1); c.Put(2, 2) }, "2:2 1:1 "},
+		{func() { c.PutQuiet(1, 10) }, "2:2 1:10 "},
+		{func() { c.PutQuiet(3, 3) }, "2:2 1:10 3:3 "},
+		// a new quiet entry in a full cache evicts the LRU entry, and
+		// then becomes the LRU entry itself
+		{func() { c.PutQuiet(4, 4) }, "2:2 1:10 4:4 "},
+		{func() { c.Put(5, 5) }, "5:5 2:2 1:10 "},
+		{func() { c.Touch(1) }, "1:10 5:5 2:2 "},
+		{func() { c.PutQuiet(2, 20) }, "1:10 5:5 2:20 "},
+		{func() { c.Get(2) }, "2:20 1:10 5:5 "},
+		{func() { c.PutQuiet(6, 6); c.PutQuiet(7, 7) }, "2:20 1:10 7:7 "},
+	}
+	for i, step := range steps {
+		step.op()
+		if got := contents(c); got != step.want {
+			t.Fatalf("step %d: got %q, want %q", i, got, step.want)
+		}
+	}
+	if fmt.Sprint(evicted) != "[3 4 5 6]" {
+		t.Fatalf("evicted %v, want [3 4 5 6]", evicted)
+	}
+	if c.Touch(3) {
+		t.Fatal("Touch of a missing key should return false")
+	}
+	c.PutNegative(8)
+	if c.Touch(8) {
+		t.Fatal("Touch of a negative entry should return false")
+	}
+}
+
+func TestPutQuietTimestamps(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	c := cache.New[int, int](2, cache.WithTimestamps[int, int](), cache.WithClock[int, int](clock.now))
+
+	c.PutQuiet(1, 1)
+	clock.advance(time.Second)
+	c.PutQuiet(1, 2)
+	clock.advance(time.Second)
+	age, ok := c.Age(1)
+	checkDuration(t, "age after quiet refresh", age, ok, time.Second)
+	idle, ok := c.IdleTime(1)
+	checkDuration(t, "idle after quiet refresh", idle, ok, 2*time.Second)
+
+	c.Touch(1)
+	idle, ok = c.IdleTime(1)
+	checkDuration(t, "idle after touch", idle, ok, 0)
+}
+
+func TestDrainAll(t *testing.T) {
+	var evicted []cache.KV[int, int]
+	c := cache.New[int, int](5)
+	c.SetEvictCallback(func(key, val int) {
+		evicted = append(evicted, cache.KV[int, int]{Key: key, Val: val})
+	})
+	for i := 0; i < 4; i++ {
+		c.Put(i, 10*i)
+	}
+	c.Get(1)
+	c.PutNegative(9)
+
+	want := []cache.KV[int, int]{{0, 0}, {2, 20}, {3, 30}, {1, 10}}
+	drained := c.DrainAll()
+	if fmt.Sprint(drained) != fmt.Sprint(want) {
+		t.Fatalf("DrainAll = %v, want %v", drained, want)
+	}
+	if fmt.Sprint(evicted) != fmt.Sprint(want) {
+		t.Fatalf("evicted %v, want %v", evicted, want)
+	}
+	if c.Size() != 0 || contents(c) != "" {
+		t.Fatalf("cache not empty after DrainAll: %q", contents(c))
+	}
+	if _, s := c.GetStatus(9); s != cache.Miss {
+		t.Fatal("negative entry not removed by DrainAll")
+	}
+	if len(c.DrainAll()) != 0 {
+		t.Fatal("draining an empty cache should return nothing")
+	}
+
+	// the cache is still usable, and Clear does not invoke the callback
+	evicted = nil
+	for i := 0; i < 7; i++ {
+		c.Put(i, i)
+	}
+	c.Clear()
+	if c.Size() != 0 || len(evicted) != 2 {
+		t.Fatalf("size %d after Clear, %d evictions, want 0 and 2", c.Size(), len(evicted))
+	}
+	c.Put(1, 1)
+	if v, ok := c.Get(1); !ok || v != 1 {
+		t.Fatal("cache unusable after Clear")
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	var evicted []int
+	c := cache.New[int, int](6)
+	c.SetEvictCallback(func(key, val int) { evicted = append(evicted, key) })
+	for _, k := range []int{50, 10, 40, 20, 30} {
+		c.Put(k, k/10)
+	}
+	c.PutNegative(35)
+
+	var sorted []string
+	c.EachSortedKeys(g.Less[int], func(key, val int) {
+		sorted = append(sorted, fmt.Sprintf("%d:%d", key, val))
+	})
+	if fmt.Sprint(sorted) != "[10:1 20:2 30:3 40:4 50:5]" {
+		t.Fatalf("EachSortedKeys visited %v", sorted)
+	}
+
+	tests := []struct {
+		k, n int
+		want string
+	}{
+		{30, 3, "[20 30 40]"},
+		{30, 1, "[30]"},
+		{30, 2, "[20 30]"},
+		{25, 2, "[20 30]"},
+		{25, 4, "[10 20 30 40]"},
+		{5, 2, "[10 20]"},
+		{60, 3, "[30 40 50]"},
+		{50, 3, "[30 40 50]"},
+		{30, 10, "[10 20 30 40 50]"},
+		{30, 0, "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(c.NearestKeys(tt.k, g.Less[int], tt.n)); got != tt.want {
+			t.Errorf("NearestKeys(%d, %d) = %s, want %s", tt.k, tt.n, got, tt.want)
+		}
+	}
+
+	// the recency order is untouched, so entries are evicted in insertion
+	// order
+	for i := 0; i < 6; i++ {
+		c.Put(100+i, 0)
+	}
+	if fmt.Sprint(evicted) != "[50 10 40 20 30]" {
+		t.Fatalf("evicted %v, want [50 10 40 20 30]", evicted)
+	}
+}
+
+// hitRate replays a Zipfian trace of lookups against 'c', putting each key
+// that misses, with a scan of one-off keys after every 'scanEvery' lookups,
+// and returns the fraction of lookups that hit.
+func hitRate(c *cache.Cache[int, int], scanEvery int) float64 {
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, 100000)
+	const n = 200000
+	hits, scan := 0, 1<<30
+	for i := 0; i < n; i++ {
+		k := int(zipf.Uint64())
+		if _, ok := c.Get(k); ok {
+			hits++
+		} else {
+			c.Put(k, k)
+		}
+		if i%scanEvery == 0 {
+			for j := 0; j < c.Capacity(); j++ {
+				scan++
+				if _, ok := c.Get(scan); !ok {
+					c.Put(scan, scan)
+				}
+			}
+		}
+	}
+	return float64(hits) / n
+}
+
+func TestAdmission(t *testing.T) {
+	const capacity = 500
+	for _, scanEvery := range []int{1 << 30, 1000} {
+		lru := hitRate(cache.New[int, int](capacity), scanEvery)
+		tiny := hitRate(cache.New[int, int](capacity, cache.WithAdmission[int, int](4*capacity, g.HashInt)), scanEvery)
+		t.Logf("scan every %d: LRU hit rate %.3f, with admission %.3f", scanEvery, lru, tiny)
+		if tiny <= lru {
+			t.Errorf("scan every %d: hit rate with admission %.3f, want more than LRU %.3f", scanEvery, tiny, lru)
+		}
+	}
+}
+
+func TestPutIfAdmitted(t *testing.T) {
+	c := cache.New[int, int](2, cache.WithAdmission[int, int](64, g.HashInt))
+	if !c.PutIfAdmitted(1, 1) || !c.PutIfAdmitted(2, 2) {
+		t.Fatal("entries rejected while there was room")
+	}
+	c.Get(1)
+	c.Get(2)
+	c.Get(2)
+
+	// 2 is used more than 1, which is used more than 3
+	if c.PutIfAdmitted(3, 3) {
+		t.Fatal("admitted a new key over a more popular victim")
+	}
+	if _, ok := c.Get(3); ok || c.Size() != 2 {
+		t.Fatal("rejected entry was added")
+	}
+	c.PutNegative(4)
+	if _, s := c.GetStatus(4); s != cache.Miss {
+		t.Fatal("rejected negative entry was added")
+	}
+
+	// replacing an existing entry is always admitted
+	if !c.PutIfAdmitted(1, 10) {
+		t.Fatal("rejected an existing key")
+	}
+
+	// once 3 has been used more often than the victim 2, it is admitted
+	c.Get(3)
+	c.Get(3)
+	c.Get(3)
+	if !c.PutIfAdmitted(3, 3) {
+		t.Fatal("rejected a popular key")
+	}
+	if _, ok := c.Get(2); ok {
+		t.Fatal("victim was not evicted")
+	}
+
+	plain := cache.New[int, int](1)
+	if !plain.PutIfAdmitted(1, 1) || !plain.PutIfAdmitted(2, 2) {
+		t.Fatal("cache without admission rejected an entry")
+	}
+}
+
+func TestTTLWeigher(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	var evicted []int
+	c := cache.New[int, string](10,
+		cache.WithClock[int, string](clock.now),
+		cache.WithTTL[int, string](time.Minute),
+		cache.WithWeigher(func(key int, val string) int {
+			return len(val)
+		}),
+		cache.WithEvictCallback(func(key int, val string) {
+			evicted = append(evicted, key)
+		}),
+	)
+	checkEvicted := func(want ...int) {
+		t.Helper()
+		if fmt.Sprint(evicted) != fmt.Sprint(want) {
+			t.Fatalf("evicted %v, want %v", evicted, want)
+		}
+	}
+
+	c.Put(1, "aaaa")
+	c.Put(2, "bbbb")
+	c.Put(3, "ccc") // weighs 11 in total, so 1 is evicted
+	checkEvicted(1)
+	c.Put(2, "b") // overwriting 2 makes it lighter
+	c.Put(4, "dddddd")
+	checkEvicted(1)
+	if c.Size() != 3 {
+		t.Fatalf("size %d, want 3", c.Size())
+	}
+
+	clock.advance(30 * time.Second)
+	c.Put(5, "e")
+	checkEvicted(1, 3)
+
+	// 2 and 4 expire, but are not evicted
+	clock.advance(31 * time.Second)
+	if _, ok := c.Get(2); ok {
+		t.Fatal("expired entry was returned")
+	}
+	if n := c.RemoveExpired(); n != 1 {
+		t.Fatalf("RemoveExpired() = %d, want 1", n)
+	}
+	if v, ok := c.Get(5); !ok || v != "e" {
+		t.Fatalf("Get(5) = %q, %v", v, ok)
+	}
+	checkEvicted(1, 3)
+
+	// an entry heavier than the capacity evicts everything else
+	c.Put(6, "ffffffffffff")
+	checkEvicted(1, 3, 5)
+	if v, ok := c.Get(6); !ok || v != "ffffffffffff" || c.Size() != 1 {
+		t.Fatalf("Get(6) = %q, %v with size %d", v, ok, c.Size())
+	}
+	c.Resize(20)
+	c.Put(7, "g")
+	clock.advance(time.Minute)
+	if _, s := c.GetStatus(7); s != cache.Miss {
+		t.Fatalf("entry should have expired, got %d", s)
+	}
+	checkEvicted(1, 3, 5)
+}
+
+func TestGetWithExpiry(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	c := cache.New[int, string](10, cache.WithTTL[int, string](time.Minute), cache.WithClock[int, string](clock.now))
+
+	c.Put(1, "a")
+	v, exp, ok := c.GetWithExpiry(1)
+	if !ok || v != "a" || !exp.Equal(clock.now().Add(time.Minute)) {
+		t.Fatalf("GetWithExpiry(1) = %q, %v, %v", v, exp, ok)
+	}
+
+	// a refresh with Put moves the expiry forward, but a lookup does not
+	clock.advance(40 * time.Second)
+	if _, got, _ := c.GetWithExpiry(1); !got.Equal(exp) {
+		t.Fatalf("expiry moved to %v on lookup, want %v", got, exp)
+	}
+	c.Put(1, "b")
+	v, exp, ok = c.GetWithExpiry(1)
+	if !ok || v != "b" || !exp.Equal(clock.now().Add(time.Minute)) {
+		t.Fatalf("GetWithExpiry(1) after Put = %q, %v, %v", v, exp, ok)
+	}
+
+	clock.advance(time.Minute)
+	if v, exp, ok := c.GetWithExpiry(1); ok || v != "" || !exp.IsZero() {
+		t.Fatalf("expired entry returned %q, %v, %v", v, exp, ok)
+	}
+	if c.Size() != 0 {
+		t.Fatalf("expired entry was not removed, size %d", c.Size())
+	}
+
+	c.PutNegative(2)
+	if _, _, ok := c.GetWithExpiry(2); ok {
+		t.Fatal("negative entry was reported as existing")
+	}
+	plain := cache.New[int, string](1)
+	plain.Put(1, "a")
+	if _, exp, ok := plain.GetWithExpiry(1); !ok || !exp.IsZero() {
+		t.Fatalf("entry without a TTL has expiry %v, %v", exp, ok)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	var evicted []int
+	c := cache.New[int, string](2,
+		cache.WithClock[int, string](clock.now),
+		cache.WithTimestamps[int, string](),
+		cache.WithNegativeTTL[int, string](time.Minute),
+		cache.WithEvictCallback[int, string](func(key int, val string) {
+			evicted = append(evicted, key)
+		}),
+	)
+
+	c.Put(1, "a")
+	clock.advance(time.Second)
+	c.PutNegative(2)
+	clock.advance(time.Second)
+	age, ok := c.Age(1)
+	checkDuration(t, "Age(1)", age, ok, 2*time.Second)
+
+	clock.advance(time.Minute)
+	if _, s := c.GetStatus(2); s != cache.Miss {
+		t.Fatalf("negative entry should have expired, got %d", s)
+	}
+	c.Put(3, "c")
+	c.Put(4, "d") // evicts 1
+	if len(evicted) != 1 || evicted[0] != 1 {
+		t.Fatalf("evicted %v, want [1]", evicted)
+	}
+}
+
+// memory returns the expected Memory of a Cache[int, int] without options
+// holding 'n' entries, whose table has held up to 'peak'.
+func memory(n, peak int) int64 {
+	var c cache.Cache[int, int]
+	var node *list.Node[cache.KV[int, int]]
+	return int64(unsafe.Sizeof(c)) + int64(n)*int64(unsafe.Sizeof(*node)) +
+		cache.MapMemory(peak, unsafe.Sizeof(0)+unsafe.Sizeof(node))
+}
+
+func buildCache(n int) *cache.Cache[int, int] {
+	c := cache.New[int, int](n)
+	for i := 0; i < n; i++ {
+		c.Put(i, i)
+	}
+	return c
+}
+
+func TestMemory(t *testing.T) {
+	for _, n := range []int{1, 1000, 100000} {
+		if got, want := buildCache(n).Memory(), memory(n, n); got != want {
+			t.Fatalf("Memory() = %d for %d entries, want %d", got, n, want)
+		}
+	}
+}
+
+func BenchmarkMemory(b *testing.B) {
+	testutil.ReportMemory(b, func() *cache.Cache[int, int] {
+		return buildCache(100000)
+	}, (*cache.Cache[int, int]).Memory)
+}
+
+func TestSharded(t *testing.T) {
+	c := cache.NewSharded[int, int](8, 100, g.HashInt)
+	if c.Capacity() != 800 {
+		t.Fatalf("capacity = %d, want 800", c.Capacity())
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 5000; i++ {
+				k := rand.Intn(2000)
+				switch rand.Intn(4) {
+				case 0:
+					c.Remove(k)
+				case 1:
+					if v, ok := c.Get(k); ok && v != 2*k {
+						t.Errorf("Get(%d) = %d", k, v)
+					}
+				default:
+					c.Put(k, 2*k)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	n := 0
+	c.Each(func(key, val int) {
+		n++
+		if val != 2*key {
+			t.Fatalf("entry %d: %d", key, val)
+		}
+		// the cache may be used from Each
+		c.Get(key)
+	})
+	if n != c.Size() || n > c.Capacity() {
+		t.Fatalf("Each visited %d entries, size %d, capacity %d", n, c.Size(), c.Capacity())
+	}
+}
+
+// lockedCache is a cache behind a single mutex, for comparison with
+// ShardedCache.
+type lockedCache struct {
+	sync.Mutex
+	c *cache.Cache[int, int]
+}
+
+func (l *lockedCache) Get(k int) (int, bool) {
+	l.Lock()
+	defer l.Unlock()
+	return l.c.Get(k)
+}
+
+func (l *lockedCache) Put(k, v int) {
+	l.Lock()
+	defer l.Unlock()
+	l.c.Put(k, v)
+}
+
+func BenchmarkConcurrent(b *testing.B) {
+	run := func(b *testing.B, get func(k int) (int, bool), put func(k, v int)) {
+		b.SetParallelism(16)
+		b.RunParallel(func(pb *testing.PB) {
+			rng := rand.New(rand.NewSource(rand.Int63()))
+			for pb.Next() {
+				k := rng.Intn(100000)
+				if _, ok := get(k); !ok {
+					put(k, k)
+				}
+			}
+		})
+	}
+	b.Run("single-lock", func(b *testing.B) {
+		l := &lockedCache{c: cache.New[int, int](64 * 1024)}
+		run(b, l.Get, l.Put)
+	})
+	b.Run("sharded", func(b *testing.B) {
+		c := cache.NewSharded[int, int](64, 1024, g.HashInt)
+		run(b, c.Get, c.Put)
+	})
+}
+
 func Example() {
 	c := cache.New[int, int](2)
 
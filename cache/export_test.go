@@ -0,0 +1,4 @@
+package cache
+
+// MapMemory is mapMemory, for the tests.
+var MapMemory = mapMemory
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"sync"
+
+	g "github.com/zyedidia/generic"
+)
+
+// A ShardedCache is a cache that is safe for concurrent use. Keys are hashed
+// into a fixed number of independent LRU caches, each guarded by its own
+// mutex, so that goroutines working on keys in different shards do not
+// contend for a lock. Eviction is least-recently-used within each shard, so
+// the cache as a whole only approximates an LRU policy.
+type ShardedCache[K comparable, V any] struct {
+	shards []shard[K, V]
+	hash   g.HashFn[K]
+}
+
+type shard[K comparable, V any] struct {
+	sync.Mutex
+	c *Cache[K, V]
+	// pad to a 64-byte cache line, so that locking one shard does not
+	// invalidate the line holding its neighbours
+	_ [48]byte
+}
+
+// NewSharded returns a new ShardedCache with 'shards' shards, each holding at
+// most 'capacity' entries, so the total capacity is shards*capacity. Keys are
+// assigned to shards using 'hash'. The options apply to every shard.
+func NewSharded[K comparable, V any](shards, capacity int, hash g.HashFn[K], opts ...Option[K, V]) *ShardedCache[K, V] {
+	if shards < 1 {
+		shards = 1
+	}
+	s := &ShardedCache[K, V]{
+		shards: make([]shard[K, V], shards),
+		hash:   hash,
+	}
+	for i := range s.shards {
+		s.shards[i].c = New[K, V](capacity, opts...)
+	}
+	return s
+}
+
+func (s *ShardedCache[K, V]) shard(k K) *shard[K, V] {
+	return &s.shards[s.hash(k)%uint64(len(s.shards))]
+}
+
+// Get returns the entry associated with a given key, and a boolean indicating
+// whether the key exists in the cache.
+func (s *ShardedCache[K, V]) Get(k K) (V, bool) {
+	sh := s.shard(k)
+	sh.Lock()
+	defer sh.Unlock()
+	return sh.c.Get(k)
+}
+
+// Put adds a new key-entry pair to the cache, evicting the least recently
+// used entry of its shard if the shard is full.
+func (s *ShardedCache[K, V]) Put(k K, e V) {
+	sh := s.shard(k)
+	sh.Lock()
+	defer sh.Unlock()
+	sh.c.Put(k, e)
+}
+
+// Remove causes the entry associated with the given key to be immediately
+// evicted from the cache.
+func (s *ShardedCache[K, V]) Remove(k K) {
+	sh := s.shard(k)
+	sh.Lock()
+	defer sh.Unlock()
+	sh.c.Remove(k)
+}
+
+// RemoveExpired removes every expired entry from each shard, both entries
+// past their TTL and negative entries, and returns how many were removed.
+func (s *ShardedCache[K, V]) RemoveExpired() int {
+	n := 0
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.Lock()
+		n += sh.c.RemoveExpired()
+		sh.Unlock()
+	}
+	return n
+}
+
+// Size returns the number of entries in the cache. The shards are counted
+// one at a time, so with concurrent writers the result may not correspond to
+// the size at any single moment.
+func (s *ShardedCache[K, V]) Size() int {
+	n := 0
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.Lock()
+		n += sh.c.Size()
+		sh.Unlock()
+	}
+	return n
+}
+
+// Capacity returns the maximum capacity of the cache, which is the sum of the
+// capacities of the shards.
+func (s *ShardedCache[K, V]) Capacity() int {
+	n := 0
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.Lock()
+		n += sh.c.Capacity()
+		sh.Unlock()
+	}
+	return n
+}
+
+// Each calls 'fn' on every entry in the cache, one shard at a time, and within
+// each shard from most recently used to least recently used. The entries of a
+// shard are copied before 'fn' is called on them, so 'fn' may use the cache.
+func (s *ShardedCache[K, V]) Each(fn func(key K, val V)) {
+	var kvs []KV[K, V]
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.Lock()
+		kvs = kvs[:0]
+		sh.c.Each(func(key K, val V) {
+			kvs = append(kvs, KV[K, V]{key, val})
+		})
+		sh.Unlock()
+		for _, kv := range kvs {
+			fn(kv.Key, kv.Val)
+		}
+	}
+}
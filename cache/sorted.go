@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"sort"
+
+	g "github.com/zyedidia/generic"
+	"golang.org/x/exp/slices"
+)
+
+// These methods are meant for debugging: they copy and sort the entries on
+// every call, so they take O(n lg n) time for a cache of n entries. Neither
+// marks any entry as recently used, and negative entries are skipped.
+
+// sorted returns the entries of the cache sorted by key.
+func (t *Cache[K, V]) sorted(less g.LessFn[K]) []KV[K, V] {
+	kvs := make([]KV[K, V], 0, len(t.table))
+	t.Each(func(key K, val V) {
+		kvs = append(kvs, KV[K, V]{key, val})
+	})
+	slices.SortFunc(kvs, func(a, b KV[K, V]) bool {
+		return less(a.Key, b.Key)
+	})
+	return kvs
+}
+
+// EachSortedKeys calls 'fn' on every value in the cache, in the order of the
+// keys given by 'less'. The entries are copied before iteration begins, so
+// 'fn' may modify the cache.
+func (t *Cache[K, V]) EachSortedKeys(less g.LessFn[K], fn func(key K, val V)) {
+	for _, kv := range t.sorted(less) {
+		fn(kv.Key, kv.Val)
+	}
+}
+
+// NearestKeys returns up to 'n' keys in the cache that are nearest to 'k' in
+// the order given by 'less', in that order. The keys form a run centered on
+// the position of 'k' in the sorted keys: it includes 'k' if it is in the
+// cache, and otherwise has as many keys before 'k' as after it, as far as the
+// cache allows.
+func (t *Cache[K, V]) NearestKeys(k K, less g.LessFn[K], n int) []K {
+	kvs := t.sorted(less)
+	if n > len(kvs) {
+		n = len(kvs)
+	}
+	if n <= 0 {
+		return nil
+	}
+	// i is the position of 'k' among the sorted keys
+	i := sort.Search(len(kvs), func(j int) bool {
+		return !less(kvs[j].Key, k)
+	})
+	start := g.Clamp(i-n/2, 0, len(kvs)-n)
+	keys := make([]K, n)
+	for j := range keys {
+		keys[j] = kvs[start+j].Key
+	}
+	return keys
+}
@@ -0,0 +1,109 @@
+package generic
+
+// PermutationsIter returns a function that calls 'yield' on every permutation
+// of 's', in lexicographic order of the positions of the elements in 's',
+// until 'yield' returns false. The slice passed to 'yield' is reused for
+// every permutation, so it must be copied to be kept. Since there are n!
+// permutations of n elements, iterating is preferable to Permutations for
+// more than a few elements.
+func PermutationsIter[T any](s []T) func(yield func([]T) bool) {
+	return func(yield func([]T) bool) {
+		idx := make([]int, len(s))
+		for i := range idx {
+			idx[i] = i
+		}
+		perm := make([]T, len(s))
+		for {
+			for i, j := range idx {
+				perm[i] = s[j]
+			}
+			if !yield(perm) || !nextPermutation(idx) {
+				return
+			}
+		}
+	}
+}
+
+// nextPermutation rearranges 'idx' into the next permutation in lexicographic
+// order, and returns false if it was already the last one.
+func nextPermutation(idx []int) bool {
+	i := len(idx) - 2
+	for i >= 0 && idx[i] >= idx[i+1] {
+		i--
+	}
+	if i < 0 {
+		return false
+	}
+	j := len(idx) - 1
+	for idx[j] <= idx[i] {
+		j--
+	}
+	idx[i], idx[j] = idx[j], idx[i]
+	for l, r := i+1, len(idx)-1; l < r; l, r = l+1, r-1 {
+		idx[l], idx[r] = idx[r], idx[l]
+	}
+	return true
+}
+
+// Permutations returns every permutation of 's', in the order of
+// PermutationsIter.
+func Permutations[T any](s []T) [][]T {
+	return collect(PermutationsIter(s))
+}
+
+// CombinationsIter returns a function that calls 'yield' on every combination
+// of 'k' elements of 's', in lexicographic order of the positions of the
+// elements in 's', until 'yield' returns false. The elements of each
+// combination are in the order they appear in 's'. The slice passed to
+// 'yield' is reused for every combination, so it must be copied to be kept. If
+// 'k' is negative or greater than len(s) there are no combinations.
+func CombinationsIter[T any](s []T, k int) func(yield func([]T) bool) {
+	return func(yield func([]T) bool) {
+		n := len(s)
+		if k < 0 || k > n {
+			return
+		}
+		idx := make([]int, k)
+		for i := range idx {
+			idx[i] = i
+		}
+		comb := make([]T, k)
+		for {
+			for i, j := range idx {
+				comb[i] = s[j]
+			}
+			if !yield(comb) {
+				return
+			}
+
+			// advance the rightmost index that can still move right, and
+			// reset the ones after it
+			i := k - 1
+			for i >= 0 && idx[i] == n-k+i {
+				i--
+			}
+			if i < 0 {
+				return
+			}
+			idx[i]++
+			for j := i + 1; j < k; j++ {
+				idx[j] = idx[j-1] + 1
+			}
+		}
+	}
+}
+
+// Combinations returns every combination of 'k' elements of 's', in the order
+// of CombinationsIter.
+func Combinations[T any](s []T, k int) [][]T {
+	return collect(CombinationsIter(s, k))
+}
+
+func collect[T any](iter func(yield func([]T) bool)) [][]T {
+	var all [][]T
+	iter(func(s []T) bool {
+		all = append(all, append([]T(nil), s...))
+		return true
+	})
+	return all
+}
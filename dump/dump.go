@@ -0,0 +1,123 @@
+// Package dump provides helpers for writing the contents of a container to
+// an io.Writer, one record per line, for debugging. Records are written as
+// they are visited, so the container is never buffered in memory, and
+// iteration stops as soon as the writer returns an error.
+package dump
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DumpMap writes one line for every key-value pair visited by 'each', using
+// 'marshal' to encode the pair. 'each' must call its argument on every pair
+// and stop as soon as it returns false. At most 'limit' pairs are written; if
+// 'limit' is zero or negative all pairs are written. The first error returned
+// by 'marshal' or by the writer aborts iteration and is returned.
+func DumpMap[K, V any](w io.Writer, each func(fn func(key K, val V) bool), marshal func(key K, val V) ([]byte, error), limit int) error {
+	var err error
+	n := 0
+	each(func(key K, val V) bool {
+		if err != nil || (limit > 0 && n >= limit) {
+			return false
+		}
+		err = writeLine(w, func() ([]byte, error) {
+			return marshal(key, val)
+		})
+		n++
+		return err == nil && (limit <= 0 || n < limit)
+	})
+	return err
+}
+
+// DumpSeq writes one line for every element visited by 'each', using
+// 'marshal' to encode the element. It behaves like DumpMap.
+func DumpSeq[T any](w io.Writer, each func(fn func(t T) bool), marshal func(t T) ([]byte, error), limit int) error {
+	return DumpMap(w, func(fn func(t T, _ struct{}) bool) {
+		each(func(t T) bool {
+			return fn(t, struct{}{})
+		})
+	}, func(t T, _ struct{}) ([]byte, error) {
+		return marshal(t)
+	}, limit)
+}
+
+func writeLine(w io.Writer, marshal func() ([]byte, error)) error {
+	b, err := marshal()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// MapFromEach adapts an Each method that cannot stop early for use with
+// DumpMap. Once the dump is finished, the remaining pairs are still visited
+// but are ignored.
+func MapFromEach[K, V any](each func(fn func(key K, val V))) func(fn func(key K, val V) bool) {
+	return func(fn func(key K, val V) bool) {
+		done := false
+		each(func(key K, val V) {
+			if !done {
+				done = !fn(key, val)
+			}
+		})
+	}
+}
+
+// SeqFromEach adapts an Each method that cannot stop early for use with
+// DumpSeq. Once the dump is finished, the remaining elements are still
+// visited but are ignored.
+func SeqFromEach[T any](each func(fn func(t T))) func(fn func(t T) bool) {
+	return func(fn func(t T) bool) {
+		done := false
+		each(func(t T) {
+			if !done {
+				done = !fn(t)
+			}
+		})
+	}
+}
+
+type jsonPair[K, V any] struct {
+	Key K `json:"key"`
+	Val V `json:"value"`
+}
+
+// JSONPair encodes a key-value pair as a JSON object with the fields "key"
+// and "value".
+func JSONPair[K, V any](key K, val V) ([]byte, error) {
+	return json.Marshal(jsonPair[K, V]{key, val})
+}
+
+// JSON encodes 't' as JSON.
+func JSON[T any](t T) ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// CSVPair encodes a key-value pair as a two-field CSV record, formatting both
+// fields with the %v verb.
+func CSVPair[K, V any](key K, val V) ([]byte, error) {
+	return csvRecord(fmt.Sprint(key), fmt.Sprint(val))
+}
+
+// CSV encodes 't' as a single-field CSV record, formatted with the %v verb.
+func CSV[T any](t T) ([]byte, error) {
+	return csvRecord(fmt.Sprint(t))
+}
+
+func csvRecord(fields ...string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(fields); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
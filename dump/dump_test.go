@@ -0,0 +1,163 @@
+package dump_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/avl"
+	"github.com/zyedidia/generic/cache"
+	"github.com/zyedidia/generic/dump"
+	"github.com/zyedidia/generic/queue"
+)
+
+// failWriter fails every write after the first 'n' writes.
+type failWriter struct {
+	n      int
+	writes int
+}
+
+var errWrite = errors.New("write failed")
+
+func (w *failWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.writes > w.n {
+		return 0, errWrite
+	}
+	return len(b), nil
+}
+
+func tree(n int) *avl.Tree[int, string] {
+	t := avl.New[int, string](g.Less[int])
+	for i := 0; i < n; i++ {
+		t.Put(i, string(rune('a'+i%26)))
+	}
+	return t
+}
+
+func TestAbortOnError(t *testing.T) {
+	visited := 0
+	each := func(fn func(key int, val string) bool) {
+		tree(100).EachUntil(func(key int, val string) bool {
+			visited++
+			return fn(key, val)
+		})
+	}
+
+	w := &failWriter{n: 3}
+	err := dump.DumpMap(w, each, dump.JSONPair[int, string], 0)
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("expected write error, got %v", err)
+	}
+	if visited != 4 || w.writes != 4 {
+		t.Fatalf("iteration not aborted: visited %d, writes %d", visited, w.writes)
+	}
+}
+
+func TestAbortOnMarshalError(t *testing.T) {
+	errMarshal := errors.New("marshal failed")
+	var buf bytes.Buffer
+	err := dump.DumpMap(&buf, tree(10).EachUntil, func(key int, val string) ([]byte, error) {
+		if key == 2 {
+			return nil, errMarshal
+		}
+		return dump.CSVPair(key, val)
+	}, 0)
+	if !errors.Is(err, errMarshal) {
+		t.Fatalf("expected marshal error, got %v", err)
+	}
+	if got := buf.String(); got != "0,a\n1,b\n" {
+		t.Fatalf("unexpected output %q", got)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		dump func(buf *bytes.Buffer) error
+		want string
+	}{{
+		name: "avl",
+		dump: func(buf *bytes.Buffer) error {
+			return tree(3).DumpNDJSON(buf, 0)
+		},
+		want: `{"key":0,"value":"a"}` + "\n" + `{"key":1,"value":"b"}` + "\n" + `{"key":2,"value":"c"}` + "\n",
+	}, {
+		name: "empty",
+		dump: func(buf *bytes.Buffer) error {
+			return tree(0).DumpNDJSON(buf, 0)
+		},
+		want: "",
+	}, {
+		name: "cache",
+		dump: func(buf *bytes.Buffer) error {
+			c := cache.New[string, int](2)
+			c.Put("x", 1)
+			c.Put("y", 2)
+			return c.DumpNDJSON(buf, 0)
+		},
+		want: `{"key":"y","value":2}` + "\n" + `{"key":"x","value":1}` + "\n",
+	}, {
+		name: "queue csv",
+		dump: func(buf *bytes.Buffer) error {
+			q := queue.Of([]string{"a", "b,c", `"d"`})
+			return dump.DumpSeq(buf, dump.SeqFromEach(q.Each), dump.CSV[string], 0)
+		},
+		want: "a\n\"b,c\"\n\"\"\"d\"\"\"\n",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.dump(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimit(t *testing.T) {
+	for _, limit := range []int{-1, 0, 1, 5, 10, 20} {
+		var buf bytes.Buffer
+		if err := tree(10).DumpNDJSON(&buf, limit); err != nil {
+			t.Fatal(err)
+		}
+		want := limit
+		if limit <= 0 || limit > 10 {
+			want = 10
+		}
+		if lines := bytes.Count(buf.Bytes(), []byte{'\n'}); lines != want {
+			t.Fatalf("limit %d: got %d lines, want %d", limit, lines, want)
+		}
+	}
+
+	visited := 0
+	var buf bytes.Buffer
+	dump.DumpSeq(&buf, dump.SeqFromEach(func(fn func(i int)) {
+		for i := 0; i < 10; i++ {
+			visited++
+			fn(i)
+		}
+	}), dump.JSON[int], 3)
+	if buf.String() != "0\n1\n2\n" || visited != 10 {
+		t.Fatalf("unexpected output %q after visiting %d", buf.String(), visited)
+	}
+}
+
+func Example() {
+	c := cache.New[string, []int](4)
+	c.Put("foo", []int{1, 2})
+	c.Put("bar", nil)
+	c.DumpNDJSON(os.Stdout, 0)
+
+	dump.DumpMap(os.Stdout, dump.MapFromEach(c.Each), dump.CSVPair[string, []int], 1)
+	// Output:
+	// {"key":"bar","value":null}
+	// {"key":"foo","value":[1,2]}
+	// bar,[]
+}
@@ -0,0 +1,140 @@
+// Package freq provides a counter of the number of occurrences of keys, for
+// example of tokens in a text, with queries for the most frequent keys.
+package freq
+
+import (
+	"math"
+
+	"github.com/zyedidia/generic/heap"
+)
+
+// A Counter counts occurrences of keys. Counts saturate at math.MaxInt64
+// rather than overflowing, and a key whose count drops to zero or below is
+// removed.
+type Counter[K comparable] struct {
+	counts map[K]int64
+	total  int64
+}
+
+// An Entry is a key and its count.
+type Entry[K comparable] struct {
+	Key   K
+	Count int64
+}
+
+// New returns an empty counter.
+func New[K comparable]() *Counter[K] {
+	return &Counter[K]{
+		counts: make(map[K]int64),
+	}
+}
+
+// saturatingAdd returns a+b, clamped to the range of int64.
+func saturatingAdd(a, b int64) int64 {
+	s := a + b
+	if b > 0 && s < a {
+		return math.MaxInt64
+	} else if b < 0 && s > a {
+		return math.MinInt64
+	}
+	return s
+}
+
+// Add counts one occurrence of 'k'.
+func (c *Counter[K]) Add(k K) {
+	c.AddN(k, 1)
+}
+
+// AddN adds 'n' to the count of 'k'. A negative 'n' decreases the count, and
+// removes the key if the count drops to zero or below.
+func (c *Counter[K]) AddN(k K, n int64) {
+	old := c.counts[k]
+	count := saturatingAdd(old, n)
+	if count <= 0 {
+		delete(c.counts, k)
+		count = 0
+	} else {
+		c.counts[k] = count
+	}
+	c.total = saturatingAdd(c.total, count-old)
+}
+
+// Count returns the count of 'k', which is 0 if it has not been added.
+func (c *Counter[K]) Count(k K) int64 {
+	return c.counts[k]
+}
+
+// Total returns the sum of the counts of all keys.
+func (c *Counter[K]) Total() int64 {
+	return c.total
+}
+
+// Distinct returns the number of keys with a positive count.
+func (c *Counter[K]) Distinct() int {
+	return len(c.counts)
+}
+
+// Each calls 'fn' on every key and its count, in no particular order.
+func (c *Counter[K]) Each(fn func(k K, count int64)) {
+	for k, n := range c.counts {
+		fn(k, n)
+	}
+}
+
+// Merge adds the counts of 'other' to this counter.
+func (c *Counter[K]) Merge(other *Counter[K]) {
+	for k, n := range other.counts {
+		c.AddN(k, n)
+	}
+}
+
+// Decay multiplies every count by 'factor', rounding down, and removes the
+// keys whose count becomes zero. Decaying periodically by a factor less than
+// 1 makes the counts favor recent occurrences. It panics if 'factor' is
+// negative or NaN.
+func (c *Counter[K]) Decay(factor float64) {
+	if !(factor >= 0) {
+		panic("freq: negative or NaN decay factor")
+	}
+	c.total = 0
+	for k, n := range c.counts {
+		f := math.Floor(float64(n) * factor)
+		if f >= math.MaxInt64 {
+			n = math.MaxInt64
+		} else {
+			n = int64(f)
+		}
+		if n <= 0 {
+			delete(c.counts, k)
+			continue
+		}
+		c.counts[k] = n
+		c.total = saturatingAdd(c.total, n)
+	}
+}
+
+// TopK returns the 'k' keys with the highest counts, in decreasing order of
+// count. If several keys have the same count, which of them are returned
+// when not all fit, and their order, is unspecified. Complexity: O(n lg k),
+// using a heap of the best 'k' keys seen so far rather than sorting all keys.
+func (c *Counter[K]) TopK(k int) []Entry[K] {
+	if k <= 0 {
+		return nil
+	}
+	// a min-heap, so that the top is the entry to replace
+	h := heap.New(func(a, b Entry[K]) bool {
+		return a.Count < b.Count
+	})
+	for key, n := range c.counts {
+		if h.Size() < k {
+			h.Push(Entry[K]{key, n})
+		} else if top, _ := h.Peek(); n > top.Count {
+			h.Replace(Entry[K]{key, n})
+		}
+	}
+	top := make([]Entry[K], h.Size())
+	for i := len(top) - 1; i >= 0; i-- {
+		top[i], _ = h.Pop()
+	}
+	return top
+}
@@ -0,0 +1,156 @@
+package freq_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/zyedidia/generic/freq"
+)
+
+func TestCounter(t *testing.T) {
+	c := freq.New[string]()
+	for _, w := range strings.Fields("a b a c a b") {
+		c.Add(w)
+	}
+	c.AddN("d", 10)
+	c.AddN("c", -5) // removed
+	if c.Count("a") != 3 || c.Count("b") != 2 || c.Count("c") != 0 || c.Count("d") != 10 {
+		t.Fatal("wrong counts")
+	}
+	if c.Total() != 15 || c.Distinct() != 3 {
+		t.Fatalf("total %d, distinct %d", c.Total(), c.Distinct())
+	}
+
+	// counts saturate instead of overflowing
+	c.AddN("d", math.MaxInt64)
+	c.Add("d")
+	if c.Count("d") != math.MaxInt64 || c.Total() != math.MaxInt64 {
+		t.Fatalf("count %d, total %d after saturating", c.Count("d"), c.Total())
+	}
+}
+
+func TestDecay(t *testing.T) {
+	c := freq.New[int]()
+	for k := 1; k <= 10; k++ {
+		c.AddN(k, int64(k))
+	}
+	c.Decay(0.25)
+	// counts below 4 round down to zero and are dropped
+	if c.Distinct() != 7 || c.Count(3) != 0 || c.Count(4) != 1 || c.Count(10) != 2 {
+		t.Fatalf("distinct %d after decay", c.Distinct())
+	}
+	total := int64(0)
+	c.Each(func(k int, n int64) { total += n })
+	if c.Total() != total {
+		t.Fatalf("total %d, want %d", c.Total(), total)
+	}
+	c.Decay(0)
+	if c.Distinct() != 0 || c.Total() != 0 {
+		t.Fatal("decay by 0 should empty the counter")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := freq.New[int](), freq.New[int]()
+	want := make(map[int]int64)
+	for i := 0; i < 1000; i++ {
+		k := rand.Intn(50)
+		if i%2 == 0 {
+			a.Add(k)
+		} else {
+			b.Add(k)
+		}
+		want[k]++
+	}
+	a.Merge(b)
+	if a.Distinct() != len(want) || a.Total() != 1000 {
+		t.Fatalf("distinct %d, total %d", a.Distinct(), a.Total())
+	}
+	for k, n := range want {
+		if a.Count(k) != n {
+			t.Fatalf("count of %d = %d, want %d", k, a.Count(k), n)
+		}
+	}
+	if b.Total() != 500 {
+		t.Fatal("Merge modified its argument")
+	}
+}
+
+func TestTopK(t *testing.T) {
+	c := freq.New[string]()
+	for k, n := range map[string]int64{"a": 5, "b": 9, "c": 5, "d": 5, "e": 1, "f": 7} {
+		c.AddN(k, n)
+	}
+
+	top := c.TopK(3)
+	if fmt.Sprint(top[:2]) != "[{b 9} {f 7}]" || top[2].Count != 5 || !strings.Contains("acd", top[2].Key) {
+		t.Fatalf("TopK(3) = %v", top)
+	}
+	// all tied keys fit
+	top = c.TopK(5)
+	tied := ""
+	for _, e := range top[2:] {
+		if e.Count != 5 {
+			t.Fatalf("TopK(5) = %v", top)
+		}
+		tied += e.Key
+	}
+	if len(tied) != 3 || !strings.ContainsRune(tied, 'a') || !strings.ContainsRune(tied, 'c') || !strings.ContainsRune(tied, 'd') {
+		t.Fatalf("TopK(5) = %v", top)
+	}
+	if len(c.TopK(100)) != 6 || c.TopK(0) != nil {
+		t.Fatal("TopK with k out of range")
+	}
+}
+
+func corpus() []string {
+	rng := rand.New(rand.NewSource(1))
+	words := make([]string, 5000)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i)
+	}
+	tokens := make([]string, 100000)
+	for i := range tokens {
+		// a skewed distribution, as in natural text
+		tokens[i] = words[int(rng.ExpFloat64()*200)%len(words)]
+	}
+	return tokens
+}
+
+func BenchmarkCount(b *testing.B) {
+	tokens := corpus()
+	b.Run("Counter", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c := freq.New[string]()
+			for _, tok := range tokens {
+				c.Add(tok)
+			}
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			m := make(map[string]int64)
+			for _, tok := range tokens {
+				m[tok]++
+			}
+		}
+	})
+}
+
+func Example() {
+	c := freq.New[string]()
+	for _, w := range strings.Fields("the cat saw the dog and the cat ran to the cat") {
+		c.Add(w)
+	}
+	for _, e := range c.TopK(2) {
+		fmt.Println(e.Key, e.Count)
+	}
+	fmt.Println(c.Total(), c.Distinct())
+	// Output:
+	// the 4
+	// cat 3
+	// 12 7
+}
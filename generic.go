@@ -25,6 +25,58 @@ func Less[T constraints.Ordered](a, b T) bool {
 	return a < b
 }
 
+// Comparable is implemented by types that define their own ordering.
+// CompareTo returns a negative number if the receiver is less than 'other', a
+// positive number if it is greater, and 0 if they are equal.
+type Comparable[T any] interface {
+	CompareTo(other T) int
+}
+
+// LessComparable is a less function for types that implement Comparable.
+func LessComparable[T Comparable[T]](a, b T) bool {
+	return a.CompareTo(b) < 0
+}
+
+// LessThenBy returns a less function that orders by 'primary', and orders
+// values that are equal under 'primary' by 'tiebreak'. It can be chained to
+// order by any number of keys:
+//
+//	LessThenBy(LessThenBy(byPriority, byTime), byID)
+func LessThenBy[T any](primary, tiebreak LessFn[T]) LessFn[T] {
+	return func(a, b T) bool {
+		if primary(a, b) {
+			return true
+		} else if primary(b, a) {
+			return false
+		}
+		return tiebreak(a, b)
+	}
+}
+
+// LessReverse returns a less function for the reverse of the ordering given
+// by 'less'.
+func LessReverse[T any](less LessFn[T]) LessFn[T] {
+	return func(a, b T) bool {
+		return less(b, a)
+	}
+}
+
+// LessBy returns a less function that orders values by the ordered key
+// extracted from them by 'key'.
+func LessBy[T any, K constraints.Ordered](key func(T) K) LessFn[T] {
+	return func(a, b T) bool {
+		return key(a) < key(b)
+	}
+}
+
+// LessByFunc returns a less function that orders values by the key extracted
+// from them by 'key', using 'less' to order the keys.
+func LessByFunc[T, K any](key func(T) K, less LessFn[K]) LessFn[T] {
+	return func(a, b T) bool {
+		return less(key(a), key(b))
+	}
+}
+
 // Compare uses a less function to determine the ordering of 'a' and 'b'. It returns:
 //
 // * -1 if a < b
@@ -85,6 +137,118 @@ func ClampFunc[T any](x, lo, hi T, less LessFn[T]) T {
 	return MaxFunc(lo, MinFunc(hi, x, less), less)
 }
 
+// WrapMod returns x modulo n in the range [0, n), so that unlike x % n it is
+// never negative: WrapMod(-1, 5) is 4. It panics if n is not positive.
+func WrapMod[T constraints.Integer](x, n T) T {
+	if n <= 0 {
+		panic("generic: WrapMod requires a positive modulus")
+	}
+	r := x % n
+	if r < 0 {
+		r += n
+	}
+	return r
+}
+
+// ClampWrap returns x wrapped into the cyclic range [lo, hi), which is
+// treated as a circle where hi is the same point as lo: ClampWrap(370, 0, 360)
+// is 10 and ClampWrap(-1, 0, 360) is 359. It panics if hi is not greater than
+// lo. The result is only meaningful if x-lo does not overflow T.
+func ClampWrap[T constraints.Integer](x, lo, hi T) T {
+	if hi <= lo {
+		panic("generic: ClampWrap requires lo < hi")
+	}
+	return lo + WrapMod(x-lo, hi-lo)
+}
+
+// TransformSlice returns a new slice containing the result of calling 'f' on
+// each element of 's', in order.
+func TransformSlice[T, U any](s []T, f func(T) U) []U {
+	out := make([]U, len(s))
+	for i, v := range s {
+		out[i] = f(v)
+	}
+	return out
+}
+
+// Index returns the index of the first element of 's' equal to 'v' according
+// to 'eq', or -1 if there is none.
+func Index[T any](s []T, v T, eq EqualsFn[T]) int {
+	for i, x := range s {
+		if eq(x, v) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Contains returns whether 's' has an element equal to 'v' according to 'eq'.
+func Contains[T any](s []T, v T, eq EqualsFn[T]) bool {
+	return Index(s, v, eq) >= 0
+}
+
+// IndexOf returns the index of the first element of 's' equal to 'v', or -1 if
+// there is none.
+func IndexOf[T comparable](s []T, v T) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// ContainsEq returns whether 's' has an element equal to 'v'.
+func ContainsEq[T comparable](s []T, v T) bool {
+	return IndexOf(s, v) >= 0
+}
+
+// MergeSorted merges 'a' and 'b', which must be sorted according to 'less',
+// into a new sorted slice in O(len(a) + len(b)) time. The merge is stable:
+// elements of 'a' come before equal elements of 'b'.
+func MergeSorted[T any](less LessFn[T], a, b []T) []T {
+	out := make([]T, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if less(b[0], a[0]) {
+			out = append(out, b[0])
+			b = b[1:]
+		} else {
+			out = append(out, a[0])
+			a = a[1:]
+		}
+	}
+	out = append(out, a...)
+	return append(out, b...)
+}
+
+// MergeSortedDedup is like MergeSorted, but keeps only the first of each run
+// of equal elements, including duplicates within 'a' or 'b'. Two elements are
+// equal if neither is less than the other.
+func MergeSortedDedup[T any](less LessFn[T], a, b []T) []T {
+	out := make([]T, 0, len(a)+len(b))
+	push := func(v T) {
+		if len(out) == 0 || less(out[len(out)-1], v) {
+			out = append(out, v)
+		}
+	}
+	for len(a) > 0 && len(b) > 0 {
+		if less(b[0], a[0]) {
+			push(b[0])
+			b = b[1:]
+		} else {
+			push(a[0])
+			a = a[1:]
+		}
+	}
+	for _, v := range a {
+		push(v)
+	}
+	for _, v := range b {
+		push(v)
+	}
+	return out
+}
+
 func HashUint64(u uint64) uint64 {
 	return hash(u)
 }
@@ -1,11 +1,19 @@
 package generic_test
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
 	"time"
 
 	"github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/heap"
 )
 
 func ExampleMax() {
@@ -83,3 +91,499 @@ func ExampleClampFunc() {
 	// -2.1
 	// 1.5
 }
+
+func ExampleResult_Map() {
+	double := func(i int) int { return 2 * i }
+
+	r := generic.Ok(21).Map(double)
+	fmt.Println(r.Unwrap())
+
+	r = generic.ToResult(strconv.Atoi("x")).Map(func(i int) int {
+		panic("not called on an error")
+	})
+	fmt.Println(r.IsOk())
+	// Output:
+	// 42 <nil>
+	// false
+}
+
+func ExampleMapResult() {
+	r := generic.MapResult(generic.ToResult(strconv.Atoi("42")), strconv.Itoa)
+	fmt.Println(r.Map(func(s string) string { return s + "!" }).Unwrap())
+	// Output:
+	// 42! <nil>
+}
+
+func TestResult(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	if v, err := generic.Ok(1).Unwrap(); v != 1 || err != nil {
+		t.Fatalf("Ok(1).Unwrap() = %v, %v", v, err)
+	}
+	if v, err := generic.Err[int](errFailed).Unwrap(); v != 0 || err != errFailed {
+		t.Fatalf("Err.Unwrap() = %v, %v", v, err)
+	}
+	if r := generic.ToResult(1, errFailed); r.IsOk() {
+		t.Fatal("ToResult with an error should not be ok")
+	}
+
+	calls := 0
+	inc := func(i int) int {
+		calls++
+		return i + 1
+	}
+	if v, err := generic.Ok(1).Map(inc).Map(inc).Unwrap(); v != 3 || err != nil || calls != 2 {
+		t.Fatalf("Map over Ok = %v, %v after %d calls", v, err, calls)
+	}
+	calls = 0
+	if _, err := generic.Err[int](errFailed).Map(inc).Map(inc).Unwrap(); err != errFailed || calls != 0 {
+		t.Fatalf("Map over Err = %v after %d calls", err, calls)
+	}
+}
+
+func TestTransformSlice(t *testing.T) {
+	got := generic.TransformSlice([]int{3, 1, 2}, strconv.Itoa)
+	if fmt.Sprintf("%q", got) != `["3" "1" "2"]` {
+		t.Fatalf("got %q", got)
+	}
+	if empty := generic.TransformSlice([]int{}, strconv.Itoa); len(empty) != 0 {
+		t.Fatalf("got %q for an empty slice", empty)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	s := []int{4, 7, 2, 7, 9}
+	eqMod := func(a, b int) bool { return a%5 == b%5 }
+	tests := []struct {
+		v, want, wantMod int
+	}{
+		{4, 0, 0},
+		{7, 1, 1},
+		{9, 4, 0},
+		{12, -1, 1},
+		{5, -1, -1},
+	}
+	for _, tt := range tests {
+		if got := generic.IndexOf(s, tt.v); got != tt.want {
+			t.Errorf("IndexOf(%d) = %d, want %d", tt.v, got, tt.want)
+		}
+		if got := generic.Index(s, tt.v, generic.Equals[int]); got != tt.want {
+			t.Errorf("Index(%d) = %d, want %d", tt.v, got, tt.want)
+		}
+		if got := generic.Index(s, tt.v, eqMod); got != tt.wantMod {
+			t.Errorf("Index(%d, mod 5) = %d, want %d", tt.v, got, tt.wantMod)
+		}
+		if got := generic.ContainsEq(s, tt.v); got != (tt.want >= 0) {
+			t.Errorf("ContainsEq(%d) = %v", tt.v, got)
+		}
+		if got := generic.Contains(s, tt.v, eqMod); got != (tt.wantMod >= 0) {
+			t.Errorf("Contains(%d, mod 5) = %v", tt.v, got)
+		}
+	}
+
+	if generic.IndexOf(nil, 1) != -1 || generic.ContainsEq([]int{}, 0) {
+		t.Error("found an element in an empty slice")
+	}
+	if generic.Index(nil, 1, generic.Equals[int]) != -1 || generic.Contains(nil, 0, generic.Equals[int]) {
+		t.Error("found an element in an empty slice")
+	}
+}
+
+func TestMergeSorted(t *testing.T) {
+	tests := []struct {
+		a, b, want, dedup []int
+	}{
+		{[]int{1, 3, 5, 7}, []int{2, 3, 4, 8, 9}, []int{1, 2, 3, 3, 4, 5, 7, 8, 9}, []int{1, 2, 3, 4, 5, 7, 8, 9}},
+		{[]int{1, 2, 2}, nil, []int{1, 2, 2}, []int{1, 2}},
+		{nil, []int{4, 5}, []int{4, 5}, []int{4, 5}},
+		{nil, nil, []int{}, []int{}},
+		{[]int{7, 7, 7}, []int{7, 7}, []int{7, 7, 7, 7, 7}, []int{7}},
+	}
+	for _, tt := range tests {
+		if got := generic.MergeSorted(generic.Less[int], tt.a, tt.b); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("MergeSorted(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := generic.MergeSortedDedup(generic.Less[int], tt.a, tt.b); fmt.Sprint(got) != fmt.Sprint(tt.dedup) {
+			t.Errorf("MergeSortedDedup(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.dedup)
+		}
+	}
+
+	// equal elements of 'a' come first, and are the ones kept by Dedup
+	type kv struct {
+		k int
+		v string
+	}
+	byKey := func(x, y kv) bool { return x.k < y.k }
+	a := []kv{{1, "a"}, {2, "a"}}
+	b := []kv{{1, "b"}, {3, "b"}}
+	if got := generic.MergeSorted(byKey, a, b); fmt.Sprint(got) != "[{1 a} {1 b} {2 a} {3 b}]" {
+		t.Errorf("MergeSorted is not stable: %v", got)
+	}
+	if got := generic.MergeSortedDedup(byKey, a, b); fmt.Sprint(got) != "[{1 a} {2 a} {3 b}]" {
+		t.Errorf("MergeSortedDedup kept %v", got)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	weights := []float64{1, 0, 3, 6, 10}
+	const ndraws = 200000
+
+	check := func(name string, draw func() string) {
+		counts := make(map[string]int)
+		for i := 0; i < ndraws; i++ {
+			counts[draw()]++
+		}
+		for i, item := range items {
+			want := weights[i] / 20
+			got := float64(counts[item]) / ndraws
+			if math.Abs(got-want) > 0.01 {
+				t.Errorf("%s: drew %q with frequency %.3f, want %.3f", name, item, got, want)
+			}
+		}
+		if counts["b"] != 0 {
+			t.Errorf("%s: drew an item with zero weight", name)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	check("WeightedChoice", func() string {
+		item, ok := generic.WeightedChoice(items, weights, rng)
+		if !ok {
+			t.Fatal("WeightedChoice failed")
+		}
+		return item
+	})
+	sampler := generic.NewAliasSampler(items, weights)
+	check("AliasSampler", func() string {
+		return sampler.Sample(rng)
+	})
+
+	if _, ok := generic.WeightedChoice(items, make([]float64, len(items)), rng); ok {
+		t.Error("WeightedChoice with zero weights succeeded")
+	}
+	mustPanic := func(name string, fn func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s did not panic", name)
+			}
+		}()
+		fn()
+	}
+	mustPanic("mismatched lengths", func() { generic.WeightedChoice(items, weights[1:], rng) })
+	mustPanic("negative weight", func() { generic.NewAliasSampler([]int{1, 2}, []float64{1, -1}) })
+	mustPanic("zero weights", func() { generic.NewAliasSampler([]int{1, 2}, []float64{0, 0}) })
+}
+
+func factorial(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return n * factorial(n-1)
+}
+
+func binomial(n, k int) int {
+	if k < 0 || k > n {
+		return 0
+	}
+	return factorial(n) / (factorial(k) * factorial(n-k))
+}
+
+// distinct reports whether every slice in 'all' is different.
+func distinct(all [][]int) bool {
+	seen := make(map[string]bool)
+	for _, s := range all {
+		key := fmt.Sprint(s)
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+	}
+	return true
+}
+
+func TestPermutations(t *testing.T) {
+	for n := 0; n <= 6; n++ {
+		s := make([]int, n)
+		for i := range s {
+			s[i] = i
+		}
+		perms := generic.Permutations(s)
+		if len(perms) != factorial(n) || !distinct(perms) {
+			t.Fatalf("n=%d: got %d permutations, want %d distinct", n, len(perms), factorial(n))
+		}
+		for _, p := range perms {
+			if len(p) != n {
+				t.Fatalf("n=%d: permutation %v has the wrong length", n, p)
+			}
+		}
+	}
+	if got := fmt.Sprint(generic.Permutations([]string{"a", "b", "c"})); got != "[[a b c] [a c b] [b a c] [b c a] [c a b] [c b a]]" {
+		t.Fatalf("got permutations %s", got)
+	}
+
+	// the iterator stops early
+	n := 0
+	generic.PermutationsIter(make([]int, 20))(func([]int) bool {
+		n++
+		return n < 5
+	})
+	if n != 5 {
+		t.Fatalf("iterator yielded %d times after stopping", n)
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	s := []int{0, 1, 2, 3, 4, 5, 6}
+	for k := -1; k <= len(s)+1; k++ {
+		combs := generic.Combinations(s, k)
+		if len(combs) != binomial(len(s), k) || !distinct(combs) {
+			t.Fatalf("k=%d: got %d combinations, want %d distinct", k, len(combs), binomial(len(s), k))
+		}
+		for _, c := range combs {
+			if len(c) != k || !sort.IntsAreSorted(c) {
+				t.Fatalf("k=%d: bad combination %v", k, c)
+			}
+		}
+	}
+	if got := fmt.Sprint(generic.Combinations([]string{"a", "b", "c", "d"}, 2)); got != "[[a b] [a c] [a d] [b c] [b d] [c d]]" {
+		t.Fatalf("got combinations %s", got)
+	}
+}
+
+type task struct {
+	priority int
+	time     int
+	id       string
+}
+
+func TestLessThenBy(t *testing.T) {
+	byPriority := generic.LessReverse(generic.LessBy(func(t task) int { return t.priority }))
+	byTime := generic.LessBy(func(t task) int { return t.time })
+	byID := generic.LessByFunc(func(t task) string { return t.id }, generic.Less[string])
+	less := generic.LessThenBy(generic.LessThenBy(byPriority, byTime), byID)
+
+	// sorted by priority descending, then time, then id, with ties at each
+	// level
+	want := []task{
+		{3, 5, "a"},
+		{3, 5, "b"},
+		{3, 7, "a"},
+		{1, 2, "c"},
+		{1, 4, "a"},
+		{1, 4, "d"},
+		{0, 1, "a"},
+	}
+	for i := range want {
+		for j := range want {
+			if got := less(want[i], want[j]); got != (i < j) {
+				t.Fatalf("less(%v, %v) = %v", want[i], want[j], got)
+			}
+		}
+	}
+
+	tasks := append([]task(nil), want...)
+	rand.Shuffle(len(tasks), func(i, j int) { tasks[i], tasks[j] = tasks[j], tasks[i] })
+	sort.Slice(tasks, func(i, j int) bool { return less(tasks[i], tasks[j]) })
+	if fmt.Sprint(tasks) != fmt.Sprint(want) {
+		t.Fatalf("sorted to %v, want %v", tasks, want)
+	}
+}
+
+func ExampleLessThenBy() {
+	type job struct {
+		priority int
+		deadline int
+		name     string
+	}
+	// highest priority first, then earliest deadline, then by name
+	less := generic.LessThenBy(
+		generic.LessThenBy(
+			generic.LessReverse(generic.LessBy(func(j job) int { return j.priority })),
+			generic.LessBy(func(j job) int { return j.deadline }),
+		),
+		generic.LessBy(func(j job) string { return j.name }),
+	)
+
+	h := heap.From(less,
+		job{1, 10, "backup"},
+		job{2, 30, "deploy"},
+		job{2, 20, "review"},
+		job{2, 20, "build"},
+	)
+	for h.Size() > 0 {
+		j, _ := h.Pop()
+		fmt.Println(j.name)
+	}
+	// Output:
+	// build
+	// review
+	// deploy
+	// backup
+}
+
+func TestTopoSort(t *testing.T) {
+	nodes := []string{"shirt", "tie", "jacket", "belt", "pants", "shoes", "socks", "watch"}
+	edges := map[string][]string{
+		"shirt": {"tie", "belt"},
+		"tie":   {"jacket"},
+		"pants": {"belt", "shoes"},
+		"belt":  {"jacket"},
+		"socks": {"shoes"},
+		"hat":   {"shirt"}, // not a node, ignored
+	}
+	order, ok := generic.TopoSort(nodes, edges)
+	if !ok || len(order) != len(nodes) {
+		t.Fatalf("TopoSort = %v, %v", order, ok)
+	}
+	pos := make(map[string]int)
+	for i, n := range order {
+		pos[n] = i
+	}
+	for _, n := range nodes {
+		if _, ok := pos[n]; !ok {
+			t.Fatalf("%s missing from %v", n, order)
+		}
+		for _, m := range edges[n] {
+			if pos[n] >= pos[m] {
+				t.Fatalf("%s does not come before %s in %v", n, m, order)
+			}
+		}
+	}
+
+	// a cycle through b, c and d
+	order, ok = generic.TopoSort([]string{"a", "b", "c", "d"}, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"d"},
+		"d": {"b"},
+	})
+	if ok || fmt.Sprint(order) != "[a]" {
+		t.Fatalf("TopoSort of a cyclic graph = %v, %v", order, ok)
+	}
+
+	if order, ok := generic.TopoSort([]int{3, 1, 3, 2}, nil); !ok || fmt.Sprint(order) != "[3 1 2]" {
+		t.Fatalf("TopoSort without edges = %v, %v", order, ok)
+	}
+
+	// d becomes ready after c, but comes before it in the nodes
+	order, ok = generic.TopoSort([]string{"a", "b", "d", "c"}, map[string][]string{
+		"a": {"d"},
+	})
+	if !ok || fmt.Sprint(order) != "[a b d c]" {
+		t.Fatalf("TopoSort = %v, %v, want the order of the nodes", order, ok)
+	}
+}
+
+func TestLazy(t *testing.T) {
+	var calls int32
+	get := generic.Lazy(func() []int {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(time.Millisecond)
+		return []int{1, 2, 3}
+	})
+	if calls != 0 {
+		t.Fatal("init called before the first call")
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]int, 16)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = get()
+		}(i)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("init called %d times, want 1", n)
+	}
+	for _, r := range results {
+		if len(r) != 3 || &r[0] != &results[0][0] {
+			t.Fatalf("got %v, want the same slice from every call", r)
+		}
+	}
+}
+
+func TestZip(t *testing.T) {
+	keys := []string{"a", "b", "c"}
+	vals := []int{1, 2, 3}
+	pairs := generic.Zip(keys, vals)
+	if len(pairs) != 3 || pairs[1] != (generic.KV[string, int]{Key: "b", Val: 2}) {
+		t.Fatalf("Zip = %v", pairs)
+	}
+	k, v := generic.Unzip(pairs)
+	if fmt.Sprint(k, v) != fmt.Sprint(keys, vals) {
+		t.Fatalf("Unzip = %v %v", k, v)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Zip with mismatched lengths did not panic")
+		}
+	}()
+	generic.Zip(keys, vals[1:])
+}
+
+func TestWrapMod(t *testing.T) {
+	tests := []struct {
+		x, n, want int
+	}{
+		{7, 5, 2},
+		{-1, 5, 4},
+		{-5, 5, 0},
+		{-10, 5, 0},
+		{10, 5, 0},
+		{-6, 5, 4},
+		{0, 5, 0},
+		{3, 1, 0},
+		{-3, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := generic.WrapMod(tt.x, tt.n); got != tt.want {
+			t.Errorf("WrapMod(%d, %d) = %d, want %d", tt.x, tt.n, got, tt.want)
+		}
+	}
+	if got := generic.WrapMod[uint8](250, 7); got != 5 {
+		t.Errorf("WrapMod[uint8](250, 7) = %d, want 5", got)
+	}
+	if got := generic.WrapMod[int8](-128, 3); got != 1 {
+		t.Errorf("WrapMod[int8](-128, 3) = %d, want 1", got)
+	}
+
+	wrap := []struct {
+		x, lo, hi, want int
+	}{
+		{370, 0, 360, 10},
+		{-1, 0, 360, 359},
+		{360, 0, 360, 0},
+		{-720, 0, 360, 0},
+		{5, 3, 6, 5},
+		{6, 3, 6, 3},
+		{2, 3, 6, 5},
+		{-4, -3, 3, 2},
+		{7, 4, 5, 4},
+	}
+	for _, tt := range wrap {
+		if got := generic.ClampWrap(tt.x, tt.lo, tt.hi); got != tt.want {
+			t.Errorf("ClampWrap(%d, %d, %d) = %d, want %d", tt.x, tt.lo, tt.hi, got, tt.want)
+		}
+	}
+
+	for name, fn := range map[string]func(){
+		"WrapMod with n = 0":     func() { generic.WrapMod(1, 0) },
+		"WrapMod with n < 0":     func() { generic.WrapMod(1, -3) },
+		"ClampWrap with lo = hi": func() { generic.ClampWrap(1, 2, 2) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,24 @@
+package hashmap
+
+import "unsafe"
+
+// Resizes returns the number of times the entries of 'm' have been
+// reallocated.
+func Resizes[K, V any](m *Map[K, V]) int {
+	return m.resizes
+}
+
+// Capacity returns the number of slots in the entries of 'm'.
+func Capacity[K, V any](m *Map[K, V]) uint64 {
+	return m.capacity
+}
+
+// EntrySize returns the size of an entry of a Map[K, V].
+func EntrySize[K, V any]() int64 {
+	return int64(unsafe.Sizeof(entry[K, V]{}))
+}
+
+// PackStringRef packs an arena reference into a StringMap entry word.
+func PackStringRef(ref uint64) uint64 {
+	return pack(ref, 0)
+}
@@ -7,7 +7,13 @@
 package hashmap
 
 import (
+	"io"
+	"math/rand"
+	"unsafe"
+
 	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/dump"
+	"golang.org/x/exp/slices"
 )
 
 type entry[K, V any] struct {
@@ -22,8 +28,76 @@ type Map[K, V any] struct {
 	capacity uint64
 	length   uint64
 	readonly bool
+	frozen   bool
+
+	ops    ops[K]
+	shrink shrinkPolicy
+	// eachStart returns the index at which iteration starts, given the
+	// number of entries. It is nil if iteration always starts at 0.
+	eachStart func(n int) int
+
+	// opcount counts calls to Put and Remove. If small is set, the map has
+	// had at most capacity/8 entries since operation smallSince.
+	opcount    uint64
+	small      bool
+	smallSince uint64
+	// resizes counts how many times the entries have been reallocated.
+	resizes int
+
+	// watchers maps key hashes to the watchers registered with Watch. It is
+	// nil if there are none, so that Put and Remove only check for nil.
+	watchers map[uint64][]*watcher[K, V]
+}
+
+type shrinkPolicy struct {
+	minCapacity uint64
+	delay       uint64
+}
 
-	ops ops[K]
+type options struct {
+	shrink    shrinkPolicy
+	eachStart func(n int) int
+}
+
+// An Option configures a Map.
+type Option func(o *options)
+
+// WithMinCapacity prevents the map from shrinking below 'capacity' entries
+// when keys are removed. The default is 16.
+func WithMinCapacity(capacity uint64) Option {
+	return func(o *options) {
+		o.shrink.minCapacity = capacity
+	}
+}
+
+// WithShrinkDelay sets how many Put and Remove operations the map must stay at
+// or below 1/8 of its capacity before Remove halves the capacity. This
+// prevents a map whose size oscillates around the threshold from repeatedly
+// shrinking and growing. The default is 256; a delay of 0 shrinks as soon as
+// the threshold is reached.
+func WithShrinkDelay(ops uint64) Option {
+	return func(o *options) {
+		o.shrink.delay = ops
+	}
+}
+
+// WithEachRandomStart makes iteration with Each, EachUntil and EachSnapshot
+// start at a random position in the table and wrap around, so that the order
+// changes from one call to the next, as with builtin maps. Every entry is
+// still visited exactly once. This makes code that accidentally depends on
+// the iteration order fail early, instead of after the map happens to be
+// resized. The position is chosen with 'rng', or with the global math/rand
+// source if 'rng' is nil; since a rand.Rand is not safe for concurrent use, a
+// map given one must not be iterated from several goroutines at once. Copies
+// of the map share the option.
+func WithEachRandomStart(rng *rand.Rand) Option {
+	return func(o *options) {
+		if rng == nil {
+			o.eachStart = rand.Intn
+		} else {
+			o.eachStart = rng.Intn
+		}
+	}
 }
 
 type ops[T any] struct {
@@ -40,11 +114,20 @@ func pow2ceil(num uint64) uint64 {
 }
 
 // New constructs a new map with the given capacity.
-func New[K, V any](capacity uint64, equals g.EqualsFn[K], hash g.HashFn[K]) *Map[K, V] {
+func New[K, V any](capacity uint64, equals g.EqualsFn[K], hash g.HashFn[K], opts ...Option) *Map[K, V] {
 	if capacity == 0 {
 		capacity = 1
 	}
 	capacity = pow2ceil(capacity)
+	o := options{
+		shrink: shrinkPolicy{
+			minCapacity: 16,
+			delay:       256,
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
 	return &Map[K, V]{
 		entries:  make([]entry[K, V], capacity),
 		capacity: capacity,
@@ -52,7 +135,60 @@ func New[K, V any](capacity uint64, equals g.EqualsFn[K], hash g.HashFn[K]) *Map
 			equals: equals,
 			hash:   hash,
 		},
+		shrink:    o.shrink,
+		eachStart: o.eachStart,
+	}
+}
+
+// FromPairs returns a map containing the given key-value pairs. The map is
+// sized up front so that it does not resize while it is filled. If a key
+// appears more than once, its last value is kept.
+func FromPairs[K, V any](pairs []g.KV[K, V], equals g.EqualsFn[K], hash g.HashFn[K], opts ...Option) *Map[K, V] {
+	m := New[K, V](2*uint64(len(pairs)), equals, hash, opts...)
+	for _, p := range pairs {
+		m.Put(p.Key, p.Val)
 	}
+	return m
+}
+
+// FromSlices is like FromPairs, but takes the keys and values as parallel
+// slices, mapping keys[i] to vals[i]. It panics if they have different
+// lengths.
+func FromSlices[K, V any](keys []K, vals []V, equals g.EqualsFn[K], hash g.HashFn[K], opts ...Option) *Map[K, V] {
+	if len(keys) != len(vals) {
+		panic("hashmap: keys and values have different lengths")
+	}
+	m := New[K, V](2*uint64(len(keys)), equals, hash, opts...)
+	for i := range keys {
+		m.Put(keys[i], vals[i])
+	}
+	return m
+}
+
+// FromMap returns a map containing the key-value pairs of the Go map 'src',
+// sized up front like FromPairs.
+func FromMap[K comparable, V any](src map[K]V, equals g.EqualsFn[K], hash g.HashFn[K], opts ...Option) *Map[K, V] {
+	m := New[K, V](2*uint64(len(src)), equals, hash, opts...)
+	for k, v := range src {
+		m.Put(k, v)
+	}
+	return m
+}
+
+// ToStdMap returns the key-value pairs of 'm' as a new Go map. Since a Go map
+// needs comparable keys, this is a function rather than a method of Map.
+func ToStdMap[K comparable, V any](m *Map[K, V]) map[K]V {
+	dst := make(map[K]V, m.Size())
+	CopyToMap(m, dst)
+	return dst
+}
+
+// CopyToMap puts the key-value pairs of 'm' into the Go map 'dst',
+// overwriting the values of keys that are already in 'dst'.
+func CopyToMap[K comparable, V any](m *Map[K, V], dst map[K]V) {
+	m.Each(func(key K, val V) {
+		dst[key] = val
+	})
 }
 
 // Get returns the value stored for this key, or false if there is no such
@@ -76,25 +212,36 @@ func (m *Map[K, V]) Get(key K) (V, bool) {
 }
 
 func (m *Map[K, V]) resize(newcap uint64) {
-	newm := Map[K, V]{
-		capacity: newcap,
-		length:   m.length,
-		entries:  make([]entry[K, V], newcap),
-		ops:      m.ops,
-	}
+	old := m.entries
+	m.entries = make([]entry[K, V], newcap)
+	m.capacity = newcap
+	m.readonly = false
+	m.small = false
+	m.resizes++
 
-	for _, ent := range m.entries {
+	for _, ent := range old {
 		if ent.filled {
-			newm.Put(ent.key, ent.value)
+			m.insert(ent.key, ent.value)
 		}
 	}
-	m.capacity = newm.capacity
-	m.entries = newm.entries
+}
+
+// insert places a key that is not in the map, without resizing or counting it.
+func (m *Map[K, V]) insert(key K, val V) {
+	idx := m.ops.hash(key) & (m.capacity - 1)
+	for m.entries[idx].filled {
+		idx = (idx + 1) & (m.capacity - 1)
+	}
+	m.entries[idx].key = key
+	m.entries[idx].value = val
+	m.entries[idx].filled = true
 }
 
 // Put maps the given key to the given value. If the key already exists its
 // value will be overwritten with the new value.
 func (m *Map[K, V]) Put(key K, val V) {
+	m.checkFrozen("Put")
+	m.opcount++
 	if m.length >= m.capacity/2 {
 		m.resize(m.capacity * 2)
 	} else if m.readonly {
@@ -109,7 +256,11 @@ func (m *Map[K, V]) Put(key K, val V) {
 
 	for m.entries[idx].filled {
 		if m.ops.equals(m.entries[idx].key, key) {
+			old := m.entries[idx].value
 			m.entries[idx].value = val
+			if m.watchers != nil {
+				m.notify(key, hash, old, val, false)
+			}
 			return
 		}
 		idx++
@@ -122,6 +273,13 @@ func (m *Map[K, V]) Put(key K, val V) {
 	m.entries[idx].value = val
 	m.entries[idx].filled = true
 	m.length++
+	if m.length > m.capacity/8 {
+		m.small = false
+	}
+	if m.watchers != nil {
+		var old V
+		m.notify(key, hash, old, val, false)
+	}
 }
 
 func (m *Map[K, V]) remove(idx uint64) {
@@ -135,6 +293,7 @@ func (m *Map[K, V]) remove(idx uint64) {
 
 // Remove removes the specified key-value pair from the map.
 func (m *Map[K, V]) Remove(key K) {
+	m.checkFrozen("Remove")
 	hash := m.ops.hash(key)
 	idx := hash & (m.capacity - 1)
 
@@ -153,25 +312,56 @@ func (m *Map[K, V]) Remove(key K) {
 		m.readonly = false
 	}
 
+	m.opcount++
+	old := m.entries[idx].value
 	m.remove(idx)
 
 	idx = (idx + 1) & (m.capacity - 1)
 	for m.entries[idx].filled {
 		krehash := m.entries[idx].key
 		vrehash := m.entries[idx].value
-		m.remove(idx)
-		m.Put(krehash, vrehash)
+		m.entries[idx] = entry[K, V]{}
+		m.insert(krehash, vrehash)
 		idx = (idx + 1) & (m.capacity - 1)
 	}
 
-	// halves the array if it is 12.5% full or less
-	if m.length > 0 && m.length <= m.capacity/8 {
-		m.resize(m.capacity / 2)
+	// halves the array if it has been 12.5% full or less for long enough
+	if m.length > 0 && m.length <= m.capacity/8 && m.capacity/2 >= m.shrink.minCapacity {
+		if !m.small {
+			m.small = true
+			m.smallSince = m.opcount
+		}
+		if m.opcount-m.smallSince >= m.shrink.delay {
+			m.resize(m.capacity / 2)
+		}
+	}
+
+	if m.watchers != nil {
+		var v V
+		m.notify(key, hash, old, v, true)
 	}
 }
 
 // Clear removes all key-value pairs from the map.
 func (m *Map[K, V]) Clear() {
+	m.checkFrozen("Clear")
+	if m.watchers != nil {
+		// the entries are cleared in place, so collect the removed pairs first
+		var removed []entry[K, V]
+		for _, ent := range m.entries {
+			if ent.filled {
+				removed = append(removed, ent)
+			}
+		}
+		defer m.notifyCleared(removed)
+	}
+	if m.readonly {
+		// the entries are shared with a copy, so they must not be modified
+		m.entries = make([]entry[K, V], len(m.entries))
+		m.length = 0
+		m.readonly = false
+		return
+	}
 	for idx, entry := range m.entries {
 		if entry.filled {
 			m.remove(uint64(idx))
@@ -179,6 +369,47 @@ func (m *Map[K, V]) Clear() {
 	}
 }
 
+// ShrinkToFit resizes the map to the smallest capacity that keeps it at most
+// half full, for example after removing many keys at once, regardless of the
+// shrink policy. It does nothing if the map is already that small. If the
+// entries are shared with a copy, the copy is not affected.
+func (m *Map[K, V]) ShrinkToFit() {
+	m.checkFrozen("ShrinkToFit")
+	if newcap := pow2ceil(2 * m.length); newcap < m.capacity {
+		m.resize(newcap)
+	}
+}
+
+// notifyCleared notifies the watchers of every key in 'removed' that it was
+// removed.
+func (m *Map[K, V]) notifyCleared(removed []entry[K, V]) {
+	var v V
+	for _, ent := range removed {
+		m.notify(ent.key, m.ops.hash(ent.key), ent.value, v, true)
+	}
+}
+
+// Freeze makes the map immutable: any later call to a method that would
+// modify it panics. Reads, including Copy, do not modify a frozen
+// map, so it may be read from multiple goroutines concurrently. Copies of a
+// frozen map are not frozen.
+func (m *Map[K, V]) Freeze() {
+	m.frozen = true
+	// a read-only map is not modified by Copy
+	m.readonly = true
+}
+
+// Frozen returns true if Freeze has been called on the map.
+func (m *Map[K, V]) Frozen() bool {
+	return m.frozen
+}
+
+func (m *Map[K, V]) checkFrozen(op string) {
+	if m.frozen {
+		panic("hashmap: " + op + " called on a frozen hashmap.Map")
+	}
+}
+
 // Size returns the number of items in the map.
 func (m *Map[K, V]) Size() int {
 	return int(m.length)
@@ -186,24 +417,127 @@ func (m *Map[K, V]) Size() int {
 
 // Copy returns a copy of this map. The copy will not allocate any memory until
 // the first write, so any number of read-only copies can be made without any
-// additional allocations.
+// additional allocations. Copy does not modify a map that is already
+// read-only (because it was itself made by Copy, or has been copied and not
+// written to since), so such a map may be copied from several goroutines at
+// once.
 func (m *Map[K, V]) Copy() *Map[K, V] {
-	m.readonly = true
+	if !m.readonly {
+		m.readonly = true
+	}
+	// the counters are copied too, so that the shrink policy and resize count
+	// of each side carry on from the state at the time of the copy
 	return &Map[K, V]{
-		entries:  m.entries,
-		capacity: m.capacity,
-		length:   m.length,
-		readonly: true,
-		ops:      m.ops,
+		entries:    m.entries,
+		capacity:   m.capacity,
+		length:     m.length,
+		readonly:   true,
+		ops:        m.ops,
+		shrink:     m.shrink,
+		eachStart:  m.eachStart,
+		opcount:    m.opcount,
+		small:      m.small,
+		smallSince: m.smallSince,
+		resizes:    m.resizes,
 	}
 }
 
 // Each calls 'fn' on every key-value pair in the hashmap in no particular
-// order.
+// order. The map must not be modified by 'fn': a Put or Remove may resize the
+// map mid-iteration, causing entries to be skipped or visited twice. Use
+// EachSnapshot if 'fn' needs to modify the map.
 func (m *Map[K, V]) Each(fn func(key K, val V)) {
+	for _, part := range m.parts() {
+		for _, ent := range part {
+			if ent.filled {
+				fn(ent.key, ent.value)
+			}
+		}
+	}
+}
+
+// EachUntil calls 'fn' on every key-value pair in the hashmap in no particular
+// order, until 'fn' returns false. As with Each, the map must not be modified
+// by 'fn'.
+func (m *Map[K, V]) EachUntil(fn func(key K, val V) bool) {
+	for _, part := range m.parts() {
+		for _, ent := range part {
+			if ent.filled && !fn(ent.key, ent.value) {
+				return
+			}
+		}
+	}
+}
+
+// parts returns the entries split in two at the position where iteration
+// starts, in the order they should be iterated over.
+func (m *Map[K, V]) parts() [2][]entry[K, V] {
+	start := 0
+	if m.eachStart != nil {
+		start = m.eachStart(len(m.entries))
+	}
+	return [2][]entry[K, V]{m.entries[start:], m.entries[:start]}
+}
+
+// DumpNDJSON writes every key-value pair in the hashmap to 'w' as a JSON
+// object per line, in no particular order. At most 'limit' pairs are written;
+// if 'limit' is zero or negative all pairs are written.
+func (m *Map[K, V]) DumpNDJSON(w io.Writer, limit int) error {
+	return dump.DumpMap(w, m.EachUntil, dump.JSONPair[K, V], limit)
+}
+
+// EachSnapshot calls 'fn' on every key-value pair in the hashmap in no
+// particular order. The key-value pairs are copied before iteration begins, so
+// 'fn' may safely modify the map. Modifications made by 'fn' are not visible
+// to the iteration.
+func (m *Map[K, V]) EachSnapshot(fn func(key K, val V)) {
+	snapshot := make([]entry[K, V], 0, m.length)
+	for _, part := range m.parts() {
+		for _, ent := range part {
+			if ent.filled {
+				snapshot = append(snapshot, ent)
+			}
+		}
+	}
+	for _, ent := range snapshot {
+		fn(ent.key, ent.value)
+	}
+}
+
+// EachOrdered calls 'fn' on every key-value pair in the hashmap, in the order
+// of the keys given by 'less'. Unlike Each, the order does not depend on the
+// history of the map, so it is reproducible. The key-value pairs are copied
+// and sorted before iteration begins, so 'fn' may safely modify the map.
+// Complexity: O(n lg n).
+func (m *Map[K, V]) EachOrdered(less g.LessFn[K], fn func(key K, val V)) {
+	sorted := make([]entry[K, V], 0, m.length)
 	for _, ent := range m.entries {
 		if ent.filled {
-			fn(ent.key, ent.value)
+			sorted = append(sorted, ent)
 		}
 	}
+	slices.SortFunc(sorted, func(a, b entry[K, V]) bool {
+		return less(a.key, b.key)
+	})
+	for _, ent := range sorted {
+		fn(ent.key, ent.value)
+	}
+}
+
+// Memory returns an estimate of the number of bytes used by the map: the map
+// header and its table of entries. Memory referenced by pointers in the keys
+// or values is not counted; use MemoryFunc to include it. A map that shares
+// its table with a copy counts the whole table.
+func (m *Map[K, V]) Memory() int64 {
+	return int64(unsafe.Sizeof(*m)) + int64(len(m.entries))*int64(unsafe.Sizeof(entry[K, V]{}))
+}
+
+// MemoryFunc is like Memory, but also adds 'sizeOf' for every key-value pair,
+// to account for memory referenced by the keys and values.
+func (m *Map[K, V]) MemoryFunc(sizeOf func(key K, val V) int64) int64 {
+	total := m.Memory()
+	m.Each(func(key K, val V) {
+		total += sizeOf(key, val)
+	})
+	return total
 }
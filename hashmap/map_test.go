@@ -1,12 +1,18 @@
 package hashmap_test
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"unsafe"
 
 	g "github.com/zyedidia/generic"
 	"github.com/zyedidia/generic/hashmap"
+	"github.com/zyedidia/generic/internal/testutil"
 )
 
 func checkeq[K any, V comparable](cm *hashmap.Map[K, V], get func(k K) (V, bool), t *testing.T) {
@@ -69,6 +75,445 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+func TestCopyClear(t *testing.T) {
+	const n = 100
+	check := func(m *hashmap.Map[int, int], name string) {
+		t.Helper()
+		if m.Size() != n {
+			t.Fatalf("%s: size %d, want %d", name, m.Size(), n)
+		}
+		count := 0
+		m.Each(func(key, val int) {
+			if val != 2*key {
+				t.Fatalf("%s: %d maps to %d", name, key, val)
+			}
+			count++
+		})
+		if count != n {
+			t.Fatalf("%s: Each visited %d entries, want %d", name, count, n)
+		}
+		for i := 0; i < n; i++ {
+			if v, ok := m.Get(i); !ok || v != 2*i {
+				t.Fatalf("%s: Get(%d) = %d, %v", name, i, v, ok)
+			}
+		}
+	}
+	build := func() *hashmap.Map[int, int] {
+		m := hashmap.New[int, int](1, g.Equals[int], g.HashInt)
+		for i := 0; i < n; i++ {
+			m.Put(i, 2*i)
+		}
+		return m
+	}
+
+	// clear the original, with and without watchers
+	for _, watch := range []bool{false, true} {
+		orig := build()
+		if watch {
+			orig.Watch(0, func(old, new int, deleted bool) {})
+		}
+		cpy := orig.Copy()
+		orig.Clear()
+		if orig.Size() != 0 {
+			t.Fatalf("cleared map has size %d", orig.Size())
+		}
+		check(cpy, "copy")
+		orig.Put(0, 1)
+		check(cpy, "copy")
+	}
+
+	// clear the copy
+	orig := build()
+	cpy := orig.Copy()
+	cpy.Clear()
+	if _, ok := cpy.Get(1); ok || cpy.Size() != 0 {
+		t.Fatal("cleared copy is not empty")
+	}
+	check(orig, "original")
+
+	// clear a copy of a copy
+	c1 := orig.Copy()
+	c2 := c1.Copy()
+	c1.Clear()
+	check(orig, "original")
+	check(c2, "second copy")
+
+	// the copy keeps the counters of the original
+	if hashmap.Resizes(cpy) != hashmap.Resizes(orig) {
+		t.Fatalf("copy has %d resizes, original %d", hashmap.Resizes(cpy), hashmap.Resizes(orig))
+	}
+}
+
+func TestEachSnapshot(t *testing.T) {
+	m := hashmap.New[uint64, uint64](1, g.Equals[uint64], g.HashUint64)
+
+	const n = 100
+	for i := uint64(0); i < n; i++ {
+		m.Put(i, i)
+	}
+
+	visited := make(map[uint64]bool)
+	m.EachSnapshot(func(key, val uint64) {
+		if visited[key] {
+			t.Fatalf("key %v visited twice", key)
+		}
+		visited[key] = true
+		// forces several resizes during iteration
+		m.Put(key+n, val+n)
+	})
+
+	if len(visited) != n {
+		t.Fatalf("visited %d keys, expected %d", len(visited), n)
+	}
+	if m.Size() != 2*n {
+		t.Fatalf("size mismatch: %d != %d", m.Size(), 2*n)
+	}
+	for i := uint64(0); i < 2*n; i++ {
+		if v, ok := m.Get(i); !ok || v != i {
+			t.Fatalf("key %v lost: got %v, %v", i, v, ok)
+		}
+	}
+}
+
+func TestDumpNDJSON(t *testing.T) {
+	m := hashmap.New[string, int](1, g.Equals[string], g.HashString)
+	m.Put("foo", 42)
+
+	var buf bytes.Buffer
+	if err := m.DumpNDJSON(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `{"key":"foo","value":42}`+"\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	m.Put("bar", 13)
+	buf.Reset()
+	if err := m.DumpNDJSON(&buf, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte{'\n'}); n != 1 {
+		t.Fatalf("limit ignored: wrote %d lines", n)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	m := hashmap.New[int, int](1, g.Equals[int], g.HashInt)
+	for i := 0; i < 100; i++ {
+		m.Put(i, i)
+	}
+	m.Freeze()
+	if !m.Frozen() {
+		t.Fatal("map should be frozen")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				if v, ok := m.Get(k); !ok || v != k {
+					t.Errorf("Get(%d) = %d, %v", k, v, ok)
+				}
+			}
+			m.Each(func(key, val int) {})
+			// copies of a frozen map may be made and written concurrently
+			c := m.Copy()
+			c.Put(1000, 1000)
+		}()
+	}
+	wg.Wait()
+
+	testutil.MustPanic(t, "hashmap.Map", func() { m.Put(1, 2) })
+	testutil.MustPanic(t, "hashmap.Map", func() { m.Remove(1) })
+	testutil.MustPanic(t, "hashmap.Map", func() { m.Clear() })
+	testutil.MustPanic(t, "hashmap.Map", func() { m.ShrinkToFit() })
+}
+
+// oscillate repeatedly grows the map to 40 keys and shrinks it back to 10,
+// which crosses both the grow and the shrink threshold, for 'nops' operations.
+func oscillate(m *hashmap.Map[int, int], nops int) {
+	for ops := 0; ops < nops; {
+		for k := 0; k < 40; k++ {
+			m.Put(k, k)
+			ops++
+		}
+		for k := 39; k >= 10; k-- {
+			m.Remove(k)
+			ops++
+		}
+	}
+}
+
+func TestShrinkHysteresis(t *testing.T) {
+	m := hashmap.New[int, int](1, g.Equals[int], g.HashInt)
+	oscillate(m, 1000) // warm up
+	before := hashmap.Resizes(m)
+	oscillate(m, 10000)
+	if n := hashmap.Resizes(m) - before; n > 2 {
+		t.Fatalf("%d resizes per 10k oscillating ops", n)
+	}
+
+	eager := hashmap.New[int, int](1, g.Equals[int], g.HashInt, hashmap.WithShrinkDelay(0))
+	oscillate(eager, 1000)
+	before = hashmap.Resizes(eager)
+	oscillate(eager, 10000)
+	if n := hashmap.Resizes(eager) - before; n < 100 {
+		t.Fatalf("expected thrashing without hysteresis, got %d resizes", n)
+	}
+
+	// a map that stays small does eventually shrink, but not below the
+	// minimum capacity
+	for k := 0; k < 10000; k++ {
+		m.Put(k, k)
+	}
+	grown := hashmap.Resizes(m)
+	for k := 9999; k >= 1; k-- {
+		m.Remove(k)
+	}
+	if hashmap.Resizes(m) == grown {
+		t.Fatal("map did not shrink after most keys were removed")
+	}
+	for k := 0; k < 1000; k++ {
+		m.Put(1, 1)
+		m.Remove(1)
+	}
+	checkeq(m, func(k int) (int, bool) {
+		return k, k == 0
+	}, t)
+}
+
+func TestShrinkToFit(t *testing.T) {
+	const n, keep = 1000000, 300000
+	m := hashmap.New[int, int](1, g.Equals[int], g.HashInt)
+	for i := 0; i < n; i++ {
+		m.Put(i, i)
+	}
+	// the map stays above 1/8 full, so it does not shrink by itself
+	for i := keep; i < n; i++ {
+		m.Remove(i)
+	}
+	before := hashmap.Capacity(m)
+	c := m.Copy()
+
+	m.ShrinkToFit()
+	after := hashmap.Capacity(m)
+	if after >= before || after < 2*keep || after >= 4*keep {
+		t.Fatalf("capacity %d before ShrinkToFit and %d after, for %d keys", before, after, keep)
+	}
+	if m.Size() != keep {
+		t.Fatalf("size %d after ShrinkToFit, want %d", m.Size(), keep)
+	}
+	for _, mm := range []*hashmap.Map[int, int]{m, c} {
+		for i := 0; i < n; i++ {
+			if v, ok := mm.Get(i); ok != (i < keep) || (ok && v != i) {
+				t.Fatalf("Get(%d) = %d, %v", i, v, ok)
+			}
+		}
+	}
+	if hashmap.Capacity(c) != before {
+		t.Fatal("ShrinkToFit resized a copy")
+	}
+
+	// a map that is already small enough is not resized
+	resizes := hashmap.Resizes(m)
+	m.ShrinkToFit()
+	if hashmap.Resizes(m) != resizes {
+		t.Fatal("ShrinkToFit resized a map that fits")
+	}
+
+	m.Clear()
+	m.ShrinkToFit()
+	m.Put(1, 1)
+	if v, ok := m.Get(1); !ok || v != 1 || m.Size() != 1 {
+		t.Fatal("emptied map is unusable after ShrinkToFit")
+	}
+}
+
+func BenchmarkOscillate(b *testing.B) {
+	m := hashmap.New[int, int](1, g.Equals[int], g.HashInt)
+	oscillate(m, 1000)
+	before := hashmap.Resizes(m)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		oscillate(m, 10000)
+	}
+	b.ReportMetric(float64(hashmap.Resizes(m)-before)/float64(b.N), "resizes/10kops")
+}
+
+func buildMap(n int) *hashmap.Map[int, int] {
+	m := hashmap.New[int, int](1, g.Equals[int], g.HashInt)
+	for i := 0; i < n; i++ {
+		m.Put(i, i)
+	}
+	return m
+}
+
+func TestMemory(t *testing.T) {
+	for _, n := range []int{0, 1, 1000, 100000} {
+		m := buildMap(n)
+		slots := hashmap.Capacity(m)
+		if slots < uint64(n) {
+			t.Fatalf("%d slots for %d keys", slots, n)
+		}
+		want := int64(unsafe.Sizeof(*m)) + int64(slots)*hashmap.EntrySize[int, int]()
+		if got := m.Memory(); got != want {
+			t.Fatalf("Memory() = %d for %d keys in %d slots, want %d", got, n, slots, want)
+		}
+	}
+}
+
+func BenchmarkMemory(b *testing.B) {
+	testutil.ReportMemory(b, func() *hashmap.Map[int, int] {
+		return buildMap(100000)
+	}, (*hashmap.Map[int, int]).Memory)
+}
+
+func TestMemoryFunc(t *testing.T) {
+	m := hashmap.New[int, string](1, g.Equals[int], g.HashInt)
+	m.Put(1, "hello")
+	m.Put(2, "world!")
+	deep := m.MemoryFunc(func(key int, val string) int64 {
+		return int64(len(val))
+	})
+	if deep != m.Memory()+11 {
+		t.Fatalf("MemoryFunc = %d, want Memory()+11 = %d", deep, m.Memory()+11)
+	}
+}
+
+type change struct {
+	old, new int
+	deleted  bool
+}
+
+func TestWatch(t *testing.T) {
+	m := hashmap.New[string, int](1, g.Equals[string], g.HashString)
+	var got, other []change
+	cancel := m.Watch("a", func(old, new int, deleted bool) {
+		got = append(got, change{old, new, deleted})
+	})
+	cancelOther := m.Watch("a", func(old, new int, deleted bool) {
+		other = append(other, change{old, new, deleted})
+	})
+
+	m.Put("a", 1) // first insert
+	m.Put("b", 5) // unwatched key
+	for i := 0; i < 100; i++ {
+		// force several resizes
+		m.Put(fmt.Sprint(i), i)
+	}
+	m.Put("a", 2) // overwrite
+	m.Remove("b")
+	m.Remove("a")
+	m.Remove("a") // absent, not reported
+
+	want := []change{{0, 1, false}, {1, 2, false}, {2, 0, true}}
+	if fmt.Sprint(got) != fmt.Sprint(want) || fmt.Sprint(other) != fmt.Sprint(want) {
+		t.Fatalf("got changes %v and %v, want %v", got, other, want)
+	}
+
+	// copies do not inherit watchers
+	m.Put("a", 3)
+	c := m.Copy()
+	c.Put("a", 4)
+	c.Remove("a")
+
+	cancel()
+	cancel() // cancelling twice is harmless
+	m.Put("a", 5)
+	m.Clear()
+	want = append(want, change{0, 3, false})
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got changes %v after cancel, want %v", got, want)
+	}
+	wantOther := append(want, change{3, 5, false}, change{5, 0, true})
+	if fmt.Sprint(other) != fmt.Sprint(wantOther) {
+		t.Fatalf("got changes %v, want %v", other, wantOther)
+	}
+
+	cancelOther()
+	m.Put("a", 6)
+	if len(other) != len(wantOther) {
+		t.Fatal("cancelled watcher called")
+	}
+}
+
+func TestWatchCancelInCallback(t *testing.T) {
+	m := hashmap.New[int, int](1, g.Equals[int], g.HashInt)
+	calls := 0
+	var cancel func()
+	cancel = m.Watch(1, func(old, new int, deleted bool) {
+		calls++
+		cancel()
+	})
+	m.Watch(1, func(old, new int, deleted bool) {
+		calls++
+	})
+	m.Put(1, 1)
+	m.Put(1, 2)
+	if calls != 3 {
+		t.Fatalf("got %d calls, want 3", calls)
+	}
+}
+
+func BenchmarkPut(b *testing.B) {
+	run := func(b *testing.B, m *hashmap.Map[int, int]) {
+		for i := 0; i < b.N; i++ {
+			m.Put(i&1023, i)
+		}
+	}
+	b.Run("unwatched", func(b *testing.B) {
+		run(b, hashmap.New[int, int](2048, g.Equals[int], g.HashInt))
+	})
+	b.Run("watched", func(b *testing.B) {
+		m := hashmap.New[int, int](2048, g.Equals[int], g.HashInt)
+		m.Watch(-1, func(old, new int, deleted bool) {})
+		run(b, m)
+	})
+}
+
+func TestEachOrdered(t *testing.T) {
+	keys := rand.Perm(1000)
+	a := hashmap.New[int, int](1, g.Equals[int], g.HashInt)
+	for _, k := range keys {
+		a.Put(k, k*k)
+	}
+	// a map with a different history: reverse insertion order, a different
+	// initial capacity, and removed keys
+	b := hashmap.New[int, int](4096, g.Equals[int], g.HashInt)
+	for i := len(keys) - 1; i >= 0; i-- {
+		b.Put(keys[i], keys[i]*keys[i])
+		b.Put(-keys[i]-1, 0)
+	}
+	for _, k := range keys {
+		b.Remove(-k - 1)
+	}
+
+	sequence := func(m *hashmap.Map[int, int]) string {
+		var sb strings.Builder
+		m.EachOrdered(g.Less[int], func(key, val int) {
+			fmt.Fprintf(&sb, "%d:%d ", key, val)
+		})
+		return sb.String()
+	}
+	if sa, sb := sequence(a), sequence(b); sa != sb {
+		t.Fatal("EachOrdered sequences differ")
+	}
+	prev := -1
+	a.EachOrdered(g.Less[int], func(key, val int) {
+		if key <= prev {
+			t.Fatalf("key %d after %d", key, prev)
+		}
+		prev = key
+		// modifying the map during the iteration is allowed
+		a.Remove(key)
+	})
+	if a.Size() != 0 {
+		t.Fatal("keys not removed")
+	}
+}
+
 func Example() {
 	m := hashmap.New[string, int](1, g.Equals[string], g.HashString)
 	m.Put("foo", 42)
@@ -94,3 +539,119 @@ func Example() {
 	// 0 false
 	// 0 false
 }
+
+func TestFromPairs(t *testing.T) {
+	var keys []int
+	var vals []int
+	want := hashmap.New[int, int](1, g.Equals[int], g.HashInt)
+	for i := 0; i < 1000; i++ {
+		k := rand.Intn(500)
+		keys = append(keys, k)
+		vals = append(vals, i)
+		want.Put(k, i)
+	}
+
+	for _, m := range []*hashmap.Map[int, int]{
+		hashmap.FromPairs(g.Zip(keys, vals), g.Equals[int], g.HashInt),
+		hashmap.FromSlices(keys, vals, g.Equals[int], g.HashInt),
+	} {
+		if r := hashmap.Resizes(m); r != 0 {
+			t.Fatalf("map resized %d times while filled", r)
+		}
+		if m.Size() != want.Size() {
+			t.Fatalf("size %d, want %d", m.Size(), want.Size())
+		}
+		// duplicate keys keep their last value
+		checkeq(m, want.Get, t)
+	}
+
+	testutil.MustPanic(t, "different lengths", func() {
+		hashmap.FromSlices(keys, vals[1:], g.Equals[int], g.HashInt)
+	})
+}
+
+func TestStdMap(t *testing.T) {
+	src := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		src[rand.Intn(5000)] = i
+	}
+
+	m := hashmap.FromMap(src, g.Equals[int], g.HashInt)
+	if r := hashmap.Resizes(m); r != 0 {
+		t.Fatalf("map resized %d times while filled", r)
+	}
+	got := hashmap.ToStdMap(m)
+	if !reflect.DeepEqual(got, src) {
+		t.Fatal("round trip through FromMap and ToStdMap changed the map")
+	}
+
+	// CopyToMap keeps other keys and overwrites shared ones
+	dst := map[int]int{-1: -1}
+	for k := range src {
+		dst[k] = -1
+		break
+	}
+	hashmap.CopyToMap(m, dst)
+	if len(dst) != len(src)+1 || dst[-1] != -1 {
+		t.Fatalf("CopyToMap lost keys: %d entries", len(dst))
+	}
+	for k, v := range src {
+		if dst[k] != v {
+			t.Fatalf("dst[%d] = %d, want %d", k, dst[k], v)
+		}
+	}
+
+	// the functions also accept the map embedded in a StringKeyed
+	sk := hashmap.NewStringKeyed[int](1)
+	sk.Put("a", 1)
+	sk.Put("b", 2)
+	if got := hashmap.ToStdMap(sk.Map); !reflect.DeepEqual(got, map[string]int{"a": 1, "b": 2}) {
+		t.Fatalf("ToStdMap(StringKeyed) = %v", got)
+	}
+}
+
+func TestEachRandomStart(t *testing.T) {
+	first := func(m *hashmap.Map[int, int]) int {
+		k := -1
+		m.EachUntil(func(key, val int) bool {
+			k = key
+			return false
+		})
+		return k
+	}
+
+	plain := hashmap.New[int, int](1, g.Equals[int], g.HashInt)
+	m := hashmap.New[int, int](1, g.Equals[int], g.HashInt, hashmap.WithEachRandomStart(rand.New(rand.NewSource(1))))
+	for i := 0; i < 100; i++ {
+		plain.Put(i, i)
+		m.Put(i, i)
+	}
+	if first(plain) != first(plain) {
+		t.Fatal("iteration order without the option should be deterministic")
+	}
+
+	for _, m := range []*hashmap.Map[int, int]{m, m.Copy()} {
+		differ := 0
+		for trial := 0; trial < 20; trial++ {
+			seen := make(map[int]int)
+			m.Each(func(key, val int) { seen[key]++ })
+			m.EachSnapshot(func(key, val int) { seen[key]++ })
+			if len(seen) != 100 {
+				t.Fatalf("visited %d keys, want 100", len(seen))
+			}
+			for k, n := range seen {
+				if n != 2 {
+					t.Fatalf("key %d visited %d times by Each and EachSnapshot, want 2", k, n)
+				}
+			}
+			if first(m) != first(m) {
+				differ++
+			}
+		}
+		// with 100 keys in a table of 256 entries, two random starts give
+		// the same first key with probability about 1/100
+		if differ < 10 {
+			t.Fatalf("first key differed in only %d of 20 pairs of iterations", differ)
+		}
+	}
+}
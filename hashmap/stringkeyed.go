@@ -0,0 +1,51 @@
+package hashmap
+
+import (
+	g "github.com/zyedidia/generic"
+)
+
+// A StringKeyed is a Map with string keys that can also be looked up by byte
+// slices, for example tokens produced by a parser, without converting them to
+// strings. Keys are hashed with g.HashString, which hashes a string the same
+// way g.HashBytes hashes its bytes. The byte-slice methods are lookup-only:
+// keys are still added with Put, which takes a string.
+type StringKeyed[V any] struct {
+	*Map[string, V]
+}
+
+// NewStringKeyed constructs a new string-keyed map with the given capacity.
+func NewStringKeyed[V any](capacity uint64, opts ...Option) *StringKeyed[V] {
+	return &StringKeyed[V]{
+		Map: New[string, V](capacity, g.Equals[string], g.HashString, opts...),
+	}
+}
+
+// GetBytes returns the value stored for the key with the same bytes as 'key',
+// or false if there is no such value. It does not allocate.
+func (m *StringKeyed[V]) GetBytes(key []byte) (V, bool) {
+	idx := g.HashBytes(key) & (m.capacity - 1)
+	for m.entries[idx].filled {
+		// the conversion is only used for the comparison, so the compiler
+		// does not allocate a string for it
+		if m.entries[idx].key == string(key) {
+			return m.entries[idx].value, true
+		}
+		idx = (idx + 1) & (m.capacity - 1)
+	}
+	var v V
+	return v, false
+}
+
+// HasBytes returns whether the map has a key with the same bytes as 'key'. It
+// does not allocate.
+func (m *StringKeyed[V]) HasBytes(key []byte) bool {
+	_, ok := m.GetBytes(key)
+	return ok
+}
+
+// Copy returns a copy of this map, like Map.Copy.
+func (m *StringKeyed[V]) Copy() *StringKeyed[V] {
+	return &StringKeyed[V]{
+		Map: m.Map.Copy(),
+	}
+}
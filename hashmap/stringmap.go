@@ -0,0 +1,282 @@
+package hashmap
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	g "github.com/zyedidia/generic"
+)
+
+const (
+	tagBits = 24
+	// maxRef is the largest arena reference that fits in an entry.
+	maxRef = 1<<(64-tagBits) - 1
+)
+
+type stringEntry[V any] struct {
+	// meta packs one more than the offset of the key in the arena (the ref)
+	// into the upper 40 bits and the top 24 bits of the key's hash (the tag)
+	// into the lower bits. It is 0 if the entry is empty.
+	meta  uint64
+	value V
+}
+
+func (e *stringEntry[V]) ref() uint64 {
+	return e.meta >> tagBits
+}
+
+func (e *stringEntry[V]) tag() uint64 {
+	return e.meta & (1<<tagBits - 1)
+}
+
+func tag(hash uint64) uint64 {
+	return hash >> (64 - tagBits)
+}
+
+// pack returns the meta word for a key at arena reference 'ref' with the
+// given tag. It panics if the arena has grown past what a ref can address.
+func pack(ref, tag uint64) uint64 {
+	if ref > maxRef {
+		panic("hashmap: StringMap arena exceeds 1TB")
+	}
+	return ref<<tagBits | tag
+}
+
+// A StringMap is a hashmap with string keys that is optimized for memory use
+// when storing very many keys. Rather than storing a string header per entry,
+// each key is appended to a single byte arena and the entry stores the key's
+// offset in the arena packed into a single word along with 24 bits of its
+// hash. Lookups compare the hash bits first and only then compare the key
+// bytes. Since the full hash is not stored, resizing rehashes the keys from
+// the arena; storing it would widen each entry by a word, which costs more
+// than the string headers it replaces. The arena may hold up to 1TB of keys,
+// and Put panics if it would grow past that.
+//
+// The arena is append-only: removing or overwriting a key does not reclaim the
+// space used by its bytes. Call Compact to rewrite the arena once many keys
+// have been removed.
+type StringMap[V any] struct {
+	entries  []stringEntry[V]
+	capacity uint64
+	length   uint64
+
+	arena []byte
+	// garbage is the number of arena bytes used by removed keys.
+	garbage int
+}
+
+// NewStringMap constructs a new string-keyed map with the given capacity.
+func NewStringMap[V any](capacity uint64) *StringMap[V] {
+	if capacity == 0 {
+		capacity = 1
+	}
+	capacity = pow2ceil(capacity)
+	return &StringMap[V]{
+		entries:  make([]stringEntry[V], capacity),
+		capacity: capacity,
+	}
+}
+
+// key returns the bytes of the key stored at the given arena reference.
+func (m *StringMap[V]) key(ref uint64) []byte {
+	n, w := binary.Uvarint(m.arena[ref-1:])
+	start := ref - 1 + uint64(w)
+	return m.arena[start : start+n]
+}
+
+// refSize returns the number of arena bytes used by the key at 'ref'.
+func (m *StringMap[V]) refSize(ref uint64) int {
+	n, w := binary.Uvarint(m.arena[ref-1:])
+	return int(n) + w
+}
+
+func (m *StringMap[V]) find(key string, hash uint64) uint64 {
+	idx := hash & (m.capacity - 1)
+	for m.entries[idx].meta != 0 {
+		if m.entries[idx].tag() == tag(hash) && string(m.key(m.entries[idx].ref())) == key {
+			return idx
+		}
+		idx = (idx + 1) & (m.capacity - 1)
+	}
+	return idx
+}
+
+// Get returns the value stored for this key, or false if there is no such
+// value.
+func (m *StringMap[V]) Get(key string) (V, bool) {
+	idx := m.find(key, g.HashString(key))
+	if m.entries[idx].meta != 0 {
+		return m.entries[idx].value, true
+	}
+	var v V
+	return v, false
+}
+
+// GetBytes is like Get, but takes the key as a byte slice, for example a token
+// produced by a parser, without converting it to a string. It does not
+// allocate.
+func (m *StringMap[V]) GetBytes(key []byte) (V, bool) {
+	hash := g.HashBytes(key)
+	idx := hash & (m.capacity - 1)
+	for m.entries[idx].meta != 0 {
+		if m.entries[idx].tag() == tag(hash) && bytes.Equal(m.key(m.entries[idx].ref()), key) {
+			return m.entries[idx].value, true
+		}
+		idx = (idx + 1) & (m.capacity - 1)
+	}
+	var v V
+	return v, false
+}
+
+// HasBytes returns whether the map has a key with the same bytes as 'key'. It
+// does not allocate.
+func (m *StringMap[V]) HasBytes(key []byte) bool {
+	_, ok := m.GetBytes(key)
+	return ok
+}
+
+// insert places an entry for a key not already in the map, without resizing.
+func (m *StringMap[V]) insert(ent stringEntry[V]) {
+	idx := g.HashBytes(m.key(ent.ref())) & (m.capacity - 1)
+	for m.entries[idx].meta != 0 {
+		idx = (idx + 1) & (m.capacity - 1)
+	}
+	m.entries[idx] = ent
+}
+
+func (m *StringMap[V]) resize(newcap uint64) {
+	old := m.entries
+	m.entries = make([]stringEntry[V], newcap)
+	m.capacity = newcap
+	for _, ent := range old {
+		if ent.meta != 0 {
+			m.insert(ent)
+		}
+	}
+}
+
+// Put maps the given key to the given value. If the key already exists its
+// value will be overwritten with the new value.
+func (m *StringMap[V]) Put(key string, val V) {
+	if m.length >= m.capacity/2 {
+		m.resize(m.capacity * 2)
+	}
+
+	hash := g.HashString(key)
+	idx := m.find(key, hash)
+	if m.entries[idx].meta != 0 {
+		m.entries[idx].value = val
+		return
+	}
+
+	ref := uint64(len(m.arena)) + 1
+	var buf [binary.MaxVarintLen64]byte
+	w := binary.PutUvarint(buf[:], uint64(len(key)))
+	m.arena = append(m.arena, buf[:w]...)
+	m.arena = append(m.arena, key...)
+
+	m.entries[idx] = stringEntry[V]{
+		meta:  pack(ref, tag(hash)),
+		value: val,
+	}
+	m.length++
+}
+
+// Remove removes the specified key-value pair from the map. The arena space
+// used by the key is not reclaimed until Compact is called.
+func (m *StringMap[V]) Remove(key string) {
+	idx := m.find(key, g.HashString(key))
+	if m.entries[idx].meta == 0 {
+		return
+	}
+
+	m.garbage += m.refSize(m.entries[idx].ref())
+	m.entries[idx] = stringEntry[V]{}
+	m.length--
+
+	idx = (idx + 1) & (m.capacity - 1)
+	for m.entries[idx].meta != 0 {
+		ent := m.entries[idx]
+		m.entries[idx] = stringEntry[V]{}
+		m.insert(ent)
+		idx = (idx + 1) & (m.capacity - 1)
+	}
+
+	// halves the array if it is 12.5% full or less
+	if m.length > 0 && m.length <= m.capacity/8 {
+		m.resize(m.capacity / 2)
+	}
+}
+
+// Reserve grows the map so that it can hold at least 'n' keys without
+// resizing.
+func (m *StringMap[V]) Reserve(n int) {
+	newcap := pow2ceil(2*uint64(n) + 1)
+	if newcap > m.capacity {
+		m.resize(newcap)
+	}
+}
+
+// ToMap returns the key-value pairs of the map as a new Go map.
+func (m *StringMap[V]) ToMap() map[string]V {
+	dst := make(map[string]V, m.Size())
+	m.CopyToMap(dst)
+	return dst
+}
+
+// CopyToMap puts the key-value pairs of the map into the Go map 'dst',
+// overwriting the values of keys that are already in 'dst'.
+func (m *StringMap[V]) CopyToMap(dst map[string]V) {
+	m.Each(func(key string, val V) {
+		dst[key] = val
+	})
+}
+
+// Clear removes all key-value pairs from the map, and releases the arena.
+func (m *StringMap[V]) Clear() {
+	for i := range m.entries {
+		m.entries[i] = stringEntry[V]{}
+	}
+	m.length = 0
+	m.arena = nil
+	m.garbage = 0
+}
+
+// Compact rewrites the arena so that it only holds the keys currently in the
+// map, releasing the space leaked by removed keys.
+func (m *StringMap[V]) Compact() {
+	arena := make([]byte, 0, len(m.arena)-m.garbage)
+	for i, ent := range m.entries {
+		if ent.meta == 0 {
+			continue
+		}
+		ref := ent.ref()
+		n := m.refSize(ref)
+		m.entries[i].meta = pack(uint64(len(arena))+1, ent.tag())
+		arena = append(arena, m.arena[ref-1:ref-1+uint64(n)]...)
+	}
+	m.arena = arena
+	m.garbage = 0
+}
+
+// ArenaSize returns the number of bytes in the key arena, including space
+// leaked by removed keys.
+func (m *StringMap[V]) ArenaSize() int {
+	return len(m.arena)
+}
+
+// Size returns the number of items in the map.
+func (m *StringMap[V]) Size() int {
+	return int(m.length)
+}
+
+// Each calls 'fn' on every key-value pair in the map in no particular order.
+// Each key passed to 'fn' is a newly allocated copy of the bytes in the arena.
+// The map must not be modified by 'fn'.
+func (m *StringMap[V]) Each(fn func(key string, val V)) {
+	for _, ent := range m.entries {
+		if ent.meta != 0 {
+			fn(string(m.key(ent.ref())), ent.value)
+		}
+	}
+}
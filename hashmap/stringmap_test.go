@@ -0,0 +1,202 @@
+package hashmap_test
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/hashmap"
+	"github.com/zyedidia/generic/internal/testutil"
+)
+
+func TestStringMapCrossCheck(t *testing.T) {
+	keys := []string{"", "a", "b", "ab", "héllo", "日本語", "🙂", "a\x00b", "longer key with spaces"}
+	for i := 0; i < 200; i++ {
+		keys = append(keys, fmt.Sprintf("key-%d", i))
+	}
+
+	stdm := make(map[string]int)
+	sm := hashmap.NewStringMap[int](uint64(rand.Intn(64)))
+
+	for i := 0; i < 20000; i++ {
+		key := keys[rand.Intn(len(keys))]
+		switch rand.Intn(10) {
+		case 0, 1, 2, 3:
+			v := rand.Int()
+			stdm[key] = v
+			sm.Put(key, v)
+		case 4, 5, 6:
+			delete(stdm, key)
+			sm.Remove(key)
+		case 7:
+			sm.Compact()
+		default:
+			v, ok := sm.Get(key)
+			if sv, sok := stdm[key]; v != sv || ok != sok {
+				t.Fatalf("Get(%q) = %d, %v, want %d, %v", key, v, ok, sv, sok)
+			}
+		}
+
+		if sm.Size() != len(stdm) {
+			t.Fatalf("size %d, want %d", sm.Size(), len(stdm))
+		}
+	}
+
+	seen := 0
+	sm.Each(func(key string, val int) {
+		if sv, ok := stdm[key]; !ok || sv != val {
+			t.Fatalf("Each: %q=%d, want %d, %v", key, val, sv, ok)
+		}
+		seen++
+	})
+	if seen != len(stdm) {
+		t.Fatalf("Each visited %d keys, want %d", seen, len(stdm))
+	}
+}
+
+func TestStringMapCompact(t *testing.T) {
+	sm := hashmap.NewStringMap[int](1)
+	for i := 0; i < 1000; i++ {
+		sm.Put(fmt.Sprint(i), i)
+	}
+	full := sm.ArenaSize()
+	for i := 0; i < 1000; i += 2 {
+		sm.Remove(fmt.Sprint(i))
+	}
+	if sm.ArenaSize() != full {
+		t.Fatal("Remove should not shrink the arena")
+	}
+	sm.Compact()
+	if sm.ArenaSize() >= full*3/4 {
+		t.Fatalf("arena not compacted: %d of %d bytes", sm.ArenaSize(), full)
+	}
+	for i := 0; i < 1000; i++ {
+		v, ok := sm.Get(fmt.Sprint(i))
+		if ok != (i%2 == 1) || (ok && v != i) {
+			t.Fatalf("Get(%d) = %d, %v after compaction", i, v, ok)
+		}
+	}
+}
+
+func TestStringMapArenaLimit(t *testing.T) {
+	const maxRef = 1<<40 - 1
+	if got := hashmap.PackStringRef(maxRef); got>>24 != maxRef {
+		t.Fatalf("largest ref packed as %#x", got)
+	}
+	testutil.MustPanic(t, "hashmap: StringMap arena exceeds 1TB", func() {
+		hashmap.PackStringRef(maxRef + 1)
+	})
+}
+
+const benchKeys = 10_000_000
+
+func url(i int) string {
+	return fmt.Sprintf("https://example.com/articles/%d/index.html", i)
+}
+
+// Run with -bench=Memory -benchtime=1x to compare the heap used for
+// benchKeys URL keys.
+func BenchmarkMemoryStringMap(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		before := testutil.HeapAlloc()
+		sm := hashmap.NewStringMap[uint32](1)
+		for j := 0; j < benchKeys; j++ {
+			sm.Put(url(j), uint32(j))
+		}
+		b.ReportMetric(float64(testutil.HeapAlloc()-before)/benchKeys, "bytes/key")
+		if sm.Size() != benchKeys {
+			b.Fatal("wrong size")
+		}
+	}
+}
+
+func BenchmarkMemoryMap(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		before := testutil.HeapAlloc()
+		m := hashmap.New[string, uint32](1, g.Equals[string], g.HashString)
+		for j := 0; j < benchKeys; j++ {
+			m.Put(url(j), uint32(j))
+		}
+		b.ReportMetric(float64(testutil.HeapAlloc()-before)/benchKeys, "bytes/key")
+		if m.Size() != benchKeys {
+			b.Fatal("wrong size")
+		}
+	}
+}
+
+func TestGetBytes(t *testing.T) {
+	keys := []string{"a", "ab", "abc", "abcd", "b", "\xff\xfe", "\xff", "a\x00", "日本"}
+	absent := []string{"", "abcde", "ba", "\xfe", "\xff\xfe\x00", "a\x00\x00", "日"}
+
+	km := hashmap.NewStringKeyed[int](1)
+	sm := hashmap.NewStringMap[int](1)
+	for i, k := range keys {
+		km.Put(k, i)
+		sm.Put(k, i)
+	}
+
+	type lookup struct {
+		name string
+		get  func(key []byte) (int, bool)
+		has  func(key []byte) bool
+	}
+	for _, l := range []lookup{
+		{"StringKeyed", km.GetBytes, km.HasBytes},
+		{"StringMap", sm.GetBytes, sm.HasBytes},
+	} {
+		for i, k := range keys {
+			if v, ok := l.get([]byte(k)); !ok || v != i {
+				t.Errorf("%s: GetBytes(%q) = %d, %v, want %d", l.name, k, v, ok, i)
+			}
+			if !l.has([]byte(k)) {
+				t.Errorf("%s: HasBytes(%q) = false", l.name, k)
+			}
+		}
+		for _, k := range absent {
+			if v, ok := l.get([]byte(k)); ok {
+				t.Errorf("%s: GetBytes(%q) = %d for an absent key", l.name, k, v)
+			}
+			if l.has([]byte(k)) {
+				t.Errorf("%s: HasBytes(%q) = true for an absent key", l.name, k)
+			}
+		}
+
+		hit, miss := []byte("abc"), []byte("abcde")
+		allocs := testing.AllocsPerRun(100, func() {
+			l.get(hit)
+			l.get(miss)
+			l.has(hit)
+			l.has(miss)
+		})
+		if allocs != 0 {
+			t.Errorf("%s: byte lookups made %v allocations", l.name, allocs)
+		}
+	}
+
+	c := km.Copy()
+	c.Remove("ab")
+	if !km.HasBytes([]byte("ab")) || c.HasBytes([]byte("ab")) {
+		t.Error("copy not independent of the original")
+	}
+}
+
+func TestStringMapToMap(t *testing.T) {
+	sm := hashmap.NewStringMap[int](1)
+	want := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprint(i)
+		sm.Put(k, i)
+		want[k] = i
+	}
+	if got := sm.ToMap(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ToMap = %v, want %v", got, want)
+	}
+
+	dst := map[string]int{"0": -1, "other": -1}
+	sm.CopyToMap(dst)
+	if len(dst) != 101 || dst["0"] != 0 || dst["other"] != -1 {
+		t.Fatalf("CopyToMap gave %d entries, dst[0] = %d", len(dst), dst["0"])
+	}
+}
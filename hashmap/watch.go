@@ -0,0 +1,58 @@
+package hashmap
+
+type watcher[K, V any] struct {
+	key K
+	fn  func(old, new V, deleted bool)
+}
+
+// Watch registers 'fn' to be called whenever the value for 'key' is set by
+// Put or removed by Remove or Clear. It is called synchronously, after the map
+// has been updated, with the previous value (the zero value if the key was
+// absent) and the new value (the zero value if the key was removed). Values are
+// not compared, so every Put of the key is reported, even if it stores an equal
+// value. Any number of watchers may be registered for the same key; they are
+// called in the order they were registered. Calling the returned function
+// removes the registration.
+//
+// Watchers belong to this map: they are kept when the map resizes, but copies
+// made with Copy do not inherit them, and modifications to a copy are never
+// reported to watchers of the original.
+func (m *Map[K, V]) Watch(key K, fn func(old, new V, deleted bool)) (cancel func()) {
+	if m.watchers == nil {
+		m.watchers = make(map[uint64][]*watcher[K, V])
+	}
+	hash := m.ops.hash(key)
+	w := &watcher[K, V]{key: key, fn: fn}
+	m.watchers[hash] = append(m.watchers[hash], w)
+
+	return func() {
+		ws := m.watchers[hash]
+		for i := range ws {
+			if ws[i] == w {
+				// build a new slice, since a notification may be iterating
+				// over the old one
+				rest := make([]*watcher[K, V], 0, len(ws)-1)
+				rest = append(rest, ws[:i]...)
+				rest = append(rest, ws[i+1:]...)
+				if len(rest) > 0 {
+					m.watchers[hash] = rest
+				} else {
+					delete(m.watchers, hash)
+				}
+				break
+			}
+		}
+		if len(m.watchers) == 0 {
+			m.watchers = nil
+		}
+	}
+}
+
+// notify calls the watchers registered for 'key', which has the given hash.
+func (m *Map[K, V]) notify(key K, hash uint64, old, new V, deleted bool) {
+	for _, w := range m.watchers[hash] {
+		if m.ops.equals(w.key, key) {
+			w.fn(old, new, deleted)
+		}
+	}
+}
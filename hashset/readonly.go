@@ -0,0 +1,92 @@
+package hashset
+
+import (
+	"sort"
+
+	g "github.com/zyedidia/generic"
+	"golang.org/x/exp/slices"
+)
+
+// A ReadOnlySet is an immutable snapshot of a Set, made by Finalize or
+// FinalizeSorted. It has no methods that modify it, so it is safe for
+// concurrent use by multiple goroutines.
+type ReadOnlySet[K any] struct {
+	// set is a frozen copy of the original set, or nil if the set was made
+	// by FinalizeSorted, in which case keys holds the elements in the order
+	// given by less.
+	set  *Set[K]
+	keys []K
+	less g.LessFn[K]
+}
+
+// Finalize returns a read-only snapshot of the elements of the set. The
+// snapshot is a frozen copy of the set, which shares its storage until the
+// set is next modified, so Finalize takes O(1) time, and later changes to the
+// set, which copy the storage, take O(n) time once.
+func (s *Set[K]) Finalize() ReadOnlySet[K] {
+	c := s.Copy()
+	c.Freeze()
+	return ReadOnlySet[K]{
+		set: c,
+	}
+}
+
+// FinalizeSorted is like Finalize, but copies the elements into a slice
+// sorted according to 'less', which uses less memory than a hashset. Has
+// then takes O(lg n) time using binary search, and Each and Keys visit the
+// elements in sorted order. It takes O(n lg n) time.
+func (s *Set[K]) FinalizeSorted(less g.LessFn[K]) ReadOnlySet[K] {
+	keys := make([]K, 0, s.Size())
+	s.Each(func(key K) {
+		keys = append(keys, key)
+	})
+	slices.SortFunc(keys, less)
+	return ReadOnlySet[K]{
+		keys: keys,
+		less: less,
+	}
+}
+
+// Has returns true only if 'val' is in the set.
+func (r ReadOnlySet[K]) Has(val K) bool {
+	if r.set != nil {
+		return r.set.Has(val)
+	}
+	i := sort.Search(len(r.keys), func(i int) bool {
+		return !r.less(r.keys[i], val)
+	})
+	return i < len(r.keys) && !r.less(val, r.keys[i])
+}
+
+// Size returns the number of elements in the set.
+func (r ReadOnlySet[K]) Size() int {
+	if r.set != nil {
+		return r.set.Size()
+	}
+	return len(r.keys)
+}
+
+// Each calls 'fn' on every item in the set, in sorted order if the set was
+// made by FinalizeSorted, and in no particular order otherwise.
+func (r ReadOnlySet[K]) Each(fn func(key K)) {
+	if r.set != nil {
+		r.set.Each(fn)
+		return
+	}
+	for _, k := range r.keys {
+		fn(k)
+	}
+}
+
+// Keys returns a new slice of the elements of the set, in the same order as
+// Each.
+func (r ReadOnlySet[K]) Keys() []K {
+	if r.set == nil {
+		return slices.Clone(r.keys)
+	}
+	keys := make([]K, 0, r.set.Size())
+	r.set.Each(func(key K) {
+		keys = append(keys, key)
+	})
+	return keys
+}
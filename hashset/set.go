@@ -8,13 +8,19 @@ import (
 
 // Set implements a hashset, using the hashmap as the underlying storage.
 type Set[K any] struct {
-	m *hashmap.Map[K, struct{}]
+	m      *hashmap.Map[K, struct{}]
+	frozen bool
+
+	equals g.EqualsFn[K]
+	hash   g.HashFn[K]
 }
 
 // New returns an empty hashset.
 func New[K any](capacity uint64, equals g.EqualsFn[K], hash g.HashFn[K]) *Set[K] {
 	return &Set[K]{
-		m: hashmap.New[K, struct{}](capacity, equals, hash),
+		m:      hashmap.New[K, struct{}](capacity, equals, hash),
+		equals: equals,
+		hash:   hash,
 	}
 }
 
@@ -29,6 +35,7 @@ func Of[K any](capacity uint64, equals g.EqualsFn[K], hash g.HashFn[K], vals ...
 
 // Put adds 'val' to the set.
 func (s *Set[K]) Put(val K) {
+	s.checkFrozen("Put")
 	s.m.Put(val, struct{}{})
 }
 
@@ -40,14 +47,36 @@ func (s *Set[K]) Has(val K) bool {
 
 // Remove removes 'val' from the set.
 func (s *Set[K]) Remove(val K) {
+	s.checkFrozen("Remove")
 	s.m.Remove(val)
 }
 
 // Clear removes all elements from the set.
 func (s *Set[K]) Clear() {
+	s.checkFrozen("Clear")
 	s.m.Clear()
 }
 
+// Freeze makes the set immutable: any later call to a method that would modify
+// it panics. Reads, including Copy, do not modify a frozen set, so it may be
+// read from multiple goroutines concurrently. Copies of a frozen set are not
+// frozen.
+func (s *Set[K]) Freeze() {
+	s.frozen = true
+	s.m.Freeze()
+}
+
+// Frozen returns true if Freeze has been called on the set.
+func (s *Set[K]) Frozen() bool {
+	return s.frozen
+}
+
+func (s *Set[K]) checkFrozen(op string) {
+	if s.frozen {
+		panic("hashset: " + op + " called on a frozen hashset.Set")
+	}
+}
+
 // Size returns the number of elements in the set.
 func (s *Set[K]) Size() int {
 	return s.m.Size()
@@ -63,6 +92,32 @@ func (s *Set[K]) Each(fn func(key K)) {
 // Copy returns a copy of this set.
 func (s *Set[K]) Copy() *Set[K] {
 	return &Set[K]{
-		m: s.m.Copy(),
+		m:      s.m.Copy(),
+		equals: s.equals,
+		hash:   s.hash,
 	}
 }
+
+// Clone is like Copy, but returns the copy as an 'any', so that the set
+// implements set.Cloner.
+func (s *Set[K]) Clone() any {
+	return s.Copy()
+}
+
+// Intersection returns a new set containing the elements that are in both 's'
+// and 'other'. It iterates over the smaller of the two sets and looks each
+// element up in the larger one, so its cost is proportional to the size of the
+// smaller set.
+func (s *Set[K]) Intersection(other *Set[K]) *Set[K] {
+	small, large := s, other
+	if other.Size() < s.Size() {
+		small, large = other, s
+	}
+	result := New[K](uint64(small.Size()), s.equals, s.hash)
+	small.Each(func(key K) {
+		if large.Has(key) {
+			result.Put(key)
+		}
+	})
+	return result
+}
@@ -1,12 +1,15 @@
 package hashset_test
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
+	"sync"
 	"testing"
 
 	g "github.com/zyedidia/generic"
 	"github.com/zyedidia/generic/hashset"
+	"github.com/zyedidia/generic/internal/testutil"
 )
 
 func checkeq[K any](set *hashset.Set[K], get func(k K) bool, t *testing.T) {
@@ -70,6 +73,87 @@ func TestOf(t *testing.T) {
 	}
 }
 
+func TestBytes(t *testing.T) {
+	// []byte is not comparable, but works with an explicit equals and hash
+	set := hashset.New[[]byte](1, bytes.Equal, g.HashBytes)
+	for i := 0; i < 100; i++ {
+		set.Put([]byte(fmt.Sprint(i)))
+	}
+	set.Put([]byte("7"))
+	if set.Size() != 100 {
+		t.Fatalf("size %d, want 100", set.Size())
+	}
+	if !set.Has([]byte("42")) || set.Has([]byte("100")) {
+		t.Fatal("wrong membership")
+	}
+
+	set.Remove([]byte("42"))
+	if set.Has([]byte("42")) || set.Size() != 99 {
+		t.Fatal("element not removed")
+	}
+	n := 0
+	set.Each(func(key []byte) {
+		if bytes.Equal(key, []byte("42")) {
+			t.Fatal("removed element in Each")
+		}
+		n++
+	})
+	if n != 99 {
+		t.Fatalf("Each visited %d elements, want 99", n)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	s := hashset.New[int](1, g.Equals[int], g.HashInt)
+	for i := 0; i < 100; i++ {
+		s.Put(i)
+	}
+	s.Freeze()
+	if !s.Frozen() {
+		t.Fatal("set should be frozen")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				if !s.Has(k) {
+					t.Errorf("Has(%d) = false", k)
+				}
+			}
+			s.Each(func(key int) {})
+			c := s.Copy()
+			c.Put(1000)
+		}()
+	}
+	wg.Wait()
+
+	testutil.MustPanic(t, "hashset.Set", func() { s.Put(1) })
+	testutil.MustPanic(t, "hashset.Set", func() { s.Remove(1) })
+	testutil.MustPanic(t, "hashset.Set", func() { s.Clear() })
+}
+
+func TestIntersection(t *testing.T) {
+	small := hashset.Of[int](1, g.Equals[int], g.HashInt, 1, 2, 3, 100)
+	big := hashset.New[int](1, g.Equals[int], g.HashInt)
+	for i := 0; i < 1000; i += 2 {
+		big.Put(i)
+	}
+
+	for _, inter := range []*hashset.Set[int]{small.Intersection(big), big.Intersection(small)} {
+		if inter.Size() != 2 || !inter.Has(2) || !inter.Has(100) {
+			var got []int
+			inter.Each(func(key int) { got = append(got, key) })
+			t.Fatalf("intersection = %v, want [2 100]", got)
+		}
+	}
+	if small.Size() != 4 || big.Size() != 500 {
+		t.Fatal("operands modified by Intersection")
+	}
+}
+
 func Example() {
 	set := hashset.New[string](3, g.Equals[string], g.HashString)
 	set.Put("foo")
@@ -96,3 +180,52 @@ func Example() {
 	// false
 	// false
 }
+
+func TestFinalize(t *testing.T) {
+	const n = 1000
+	s := hashset.New[int](1, g.Equals[int], g.HashInt)
+	for i := 0; i < n; i++ {
+		s.Put(2 * i)
+	}
+
+	for _, ro := range []hashset.ReadOnlySet[int]{s.Finalize(), s.FinalizeSorted(g.Less[int])} {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := -1; k < 2*n+1; k++ {
+					if ro.Has(k) != (k >= 0 && k < 2*n && k%2 == 0) {
+						t.Errorf("Has(%d) = %v", k, ro.Has(k))
+					}
+				}
+				count := 0
+				ro.Each(func(key int) { count++ })
+				if count != n || ro.Size() != n || len(ro.Keys()) != n {
+					t.Errorf("Each visited %d, Size() = %d", count, ro.Size())
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	sorted := s.FinalizeSorted(g.Less[int])
+	keys := sorted.Keys()
+	for i, k := range keys {
+		if k != 2*i {
+			t.Fatalf("sorted keys out of order at %d: %d", i, k)
+		}
+	}
+
+	// the snapshots are unaffected by later changes, and the original set
+	// is not frozen
+	ro := s.Finalize()
+	s.Put(1)
+	s.Remove(0)
+	if ro.Has(1) || !ro.Has(0) || ro.Size() != n || sorted.Has(1) || !sorted.Has(0) {
+		t.Fatal("snapshot changed with the original set")
+	}
+	if !s.Has(1) || s.Has(0) {
+		t.Fatal("original set not modified")
+	}
+}
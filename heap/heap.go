@@ -21,6 +21,12 @@ func New[T any](less g.LessFn[T]) *Heap[T] {
 	}
 }
 
+// NewOrdered returns a new heap ordered by the elements' CompareTo method, so
+// that Pop returns the least element.
+func NewOrdered[T g.Comparable[T]]() *Heap[T] {
+	return New(g.LessComparable[T])
+}
+
 // From returns a new heap with the given less function and initial data.
 func From[T any](less g.LessFn[T], t ...T) *Heap[T] {
 	return FromSlice(less, t)
@@ -40,6 +46,20 @@ func FromSlice[T any](less g.LessFn[T], data []T) *Heap[T] {
 	}
 }
 
+// FromMapValues returns a new heap of the key-value pairs in 'm', ordered by
+// value with the given less function, so that Pop returns the pair with the
+// minimum value. Pairs with equal values are popped in an unspecified order.
+// Complexity: O(n).
+func FromMapValues[K comparable, V any](less g.LessFn[V], m map[K]V) *Heap[g.KV[K, V]] {
+	data := make([]g.KV[K, V], 0, len(m))
+	for k, v := range m {
+		data = append(data, g.KV[K, V]{Key: k, Val: v})
+	}
+	return FromSlice(func(a, b g.KV[K, V]) bool {
+		return less(a.Val, b.Val)
+	}, data)
+}
+
 // Push pushes the given element onto the heap.
 func (h *Heap[T]) Push(x T) {
 	h.data = append(h.data, x)
@@ -63,6 +83,32 @@ func (h *Heap[T]) Pop() (T, bool) {
 	return x, true
 }
 
+// PushPop pushes 'x' onto the heap and then removes and returns the minimum
+// element, using at most one sift. If 'x' is not greater than the minimum, it
+// is returned immediately and the heap is left unchanged.
+func (h *Heap[T]) PushPop(x T) T {
+	if h.Size() == 0 || !h.less(h.data[0], x) {
+		return x
+	}
+	x, h.data[0] = h.data[0], x
+	down(h.data, 0, h.less)
+	return x
+}
+
+// Replace removes and returns the minimum element from the heap and pushes
+// 'x', using a single sift. If the heap is empty, 'x' is pushed and Replace
+// returns zero value and false.
+func (h *Heap[T]) Replace(x T) (T, bool) {
+	if h.Size() == 0 {
+		h.Push(x)
+		var zero T
+		return zero, false
+	}
+	x, h.data[0] = h.data[0], x
+	down(h.data, 0, h.less)
+	return x, true
+}
+
 // Peek returns the minimum element from the heap without removing it. if the
 // heap is empty, it returns zero value and false.
 func (h *Heap[T]) Peek() (T, bool) {
@@ -79,6 +125,32 @@ func (h *Heap[T]) Size() int {
 	return len(h.data)
 }
 
+// Sort sorts 'data' in place in increasing order according to the given less
+// function, using heapsort. The sort is not stable.
+func Sort[T any](less g.LessFn[T], data []T) {
+	greater := func(a, b T) bool {
+		return less(b, a)
+	}
+	for i := len(data)/2 - 1; i >= 0; i-- {
+		down(data, i, greater)
+	}
+	for end := len(data) - 1; end > 0; end-- {
+		data[0], data[end] = data[end], data[0]
+		down(data[:end], 0, greater)
+	}
+}
+
+// IsHeap returns true if 'data' satisfies the heap property for the given less
+// function, that is, no element is less than its parent.
+func IsHeap[T any](data []T, less g.LessFn[T]) bool {
+	for i := 1; i < len(data); i++ {
+		if less(data[i], data[(i-1)/2]) {
+			return false
+		}
+	}
+	return true
+}
+
 func down[T any](h []T, i int, less g.LessFn[T]) {
 	for {
 		left, right := 2*i+1, 2*i+2
@@ -2,8 +2,11 @@ package heap_test
 
 import (
 	"fmt"
+	"math/rand"
+	"sort"
 	"testing"
 
+	g "github.com/zyedidia/generic"
 	"github.com/zyedidia/generic/heap"
 )
 
@@ -184,6 +187,234 @@ func TestPopAndPeekOnEmpty(t *testing.T) {
 	}
 }
 
+func TestIsHeap(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+
+	data := []int{5, 3, 6, 2, 4, 1, 1, 8}
+	heap.FromSlice(less, data)
+	if !heap.IsHeap(data, less) {
+		t.Fatalf("%v should be a heap", data)
+	}
+
+	data[len(data)-1] = -1
+	if heap.IsHeap(data, less) {
+		t.Fatalf("%v should not be a heap", data)
+	}
+
+	if !heap.IsHeap([]int{}, less) || !heap.IsHeap([]int{1}, less) {
+		t.Fatal("empty and single-element slices should be heaps")
+	}
+
+	// Using a slice with enough capacity keeps it as the heap's inside array.
+	data = make([]int, 0, 1000)
+	h := heap.FromSlice(less, data)
+	for i := 0; i < 1000; i++ {
+		if rand.Intn(3) == 0 {
+			h.Pop()
+		} else {
+			h.Push(rand.Intn(100))
+		}
+		if !heap.IsHeap(data[:h.Size()], less) {
+			t.Fatalf("%v should be a heap", data[:h.Size()])
+		}
+	}
+}
+
+func drain(h *heap.Heap[int]) []int {
+	var out []int
+	for h.Size() > 0 {
+		x, _ := h.Pop()
+		out = append(out, x)
+	}
+	return out
+}
+
+func TestPushPopReplace(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	for i := 0; i < 100; i++ {
+		data := make([]int, rand.Intn(20))
+		for j := range data {
+			data[j] = rand.Intn(10)
+		}
+		x := rand.Intn(10)
+
+		// From uses the slice it is given as the heap's inside array
+		clone := func() []int { return append([]int(nil), data...) }
+
+		h1 := heap.From(less, clone()...)
+		h2 := heap.From(less, clone()...)
+		got := h1.PushPop(x)
+		h2.Push(x)
+		want, _ := h2.Pop()
+		if got != want {
+			t.Fatalf("PushPop(%d) on %v = %d, want %d", x, data, got, want)
+		}
+		if a, b := fmt.Sprint(drain(h1)), fmt.Sprint(drain(h2)); a != b {
+			t.Fatalf("PushPop(%d) on %v left %v, want %v", x, data, a, b)
+		}
+
+		h1 = heap.From(less, clone()...)
+		h2 = heap.From(less, clone()...)
+		got, ok1 := h1.Replace(x)
+		want, ok2 := h2.Pop()
+		h2.Push(x)
+		if got != want || ok1 != ok2 {
+			t.Fatalf("Replace(%d) on %v = %d, %v, want %d, %v", x, data, got, ok1, want, ok2)
+		}
+		if a, b := fmt.Sprint(drain(h1)), fmt.Sprint(drain(h2)); a != b {
+			t.Fatalf("Replace(%d) on %v left %v, want %v", x, data, a, b)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		data := make([]int, rand.Intn(50))
+		for j := range data {
+			data[j] = rand.Intn(20)
+		}
+		want := append([]int(nil), data...)
+		sort.Slice(want, func(i, j int) bool { return want[i] > want[j] })
+
+		heap.Sort(func(a, b int) bool { return a > b }, data)
+		if fmt.Sprint(data) != fmt.Sprint(want) {
+			t.Fatalf("got %v, want %v", data, want)
+		}
+	}
+}
+
+func BenchmarkTopK(b *testing.B) {
+	const k = 100
+	data := make([]int, 100000)
+	for i := range data {
+		data[i] = rand.Int()
+	}
+	less := func(a, b int) bool { return a < b }
+
+	b.Run("PushPop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h := heap.From(less, append([]int(nil), data[:k]...)...)
+			for _, x := range data[k:] {
+				h.PushPop(x)
+			}
+		}
+	})
+	b.Run("Push+Pop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h := heap.From(less, append([]int(nil), data[:k]...)...)
+			for _, x := range data[k:] {
+				h.Push(x)
+				h.Pop()
+			}
+		}
+	})
+}
+
+func TestIndexed(t *testing.T) {
+	h := heap.NewIndexed[int](func(a, b int) bool { return a < b })
+	prio := make(map[int]int)
+
+	for i := 0; i < 2000; i++ {
+		key := rand.Intn(100)
+		switch rand.Intn(4) {
+		case 0, 1:
+			p := rand.Intn(1000)
+			h.Push(key, p)
+			prio[key] = p
+		case 2:
+			if h.Remove(key) != hasKey(prio, key) {
+				t.Fatalf("Remove(%d) disagrees with the reference", key)
+			}
+			delete(prio, key)
+		case 3:
+			k, p, ok := h.Pop()
+			if !ok {
+				if len(prio) != 0 {
+					t.Fatal("Pop failed on a non-empty heap")
+				}
+				continue
+			}
+			for rk, rp := range prio {
+				if rp < p {
+					t.Fatalf("Pop returned %d:%d, but %d:%d is smaller", k, p, rk, rp)
+				}
+			}
+			if prio[k] != p {
+				t.Fatalf("Pop returned stale priority %d for %d", p, k)
+			}
+			delete(prio, k)
+		}
+		if h.Size() != len(prio) {
+			t.Fatalf("size %d, want %d", h.Size(), len(prio))
+		}
+	}
+	for k, p := range prio {
+		if got, ok := h.Priority(k); !ok || got != p {
+			t.Fatalf("Priority(%d) = %d, %v, want %d", k, got, ok, p)
+		}
+	}
+}
+
+func hasKey(m map[int]int, k int) bool {
+	_, ok := m[k]
+	return ok
+}
+
+func TestFromMapValues(t *testing.T) {
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		counts[fmt.Sprintf("k%d", i)] = rand.Intn(1000)
+	}
+
+	for _, less := range []g.LessFn[int]{g.Less[int], func(a, b int) bool { return a > b }} {
+		h := heap.FromMapValues(less, counts)
+		if h.Size() != len(counts) {
+			t.Fatalf("size %d, want %d", h.Size(), len(counts))
+		}
+		seen := make(map[string]bool)
+		prev, ok := h.Peek()
+		for h.Size() > 0 {
+			kv, _ := h.Pop()
+			if less(kv.Val, prev.Val) {
+				t.Fatalf("popped %v after %v", kv, prev)
+			}
+			if counts[kv.Key] != kv.Val || seen[kv.Key] {
+				t.Fatalf("popped unexpected pair %v", kv)
+			}
+			seen[kv.Key] = true
+			prev = kv
+		}
+		if !ok || len(seen) != len(counts) {
+			t.Fatalf("popped %d pairs, want %d", len(seen), len(counts))
+		}
+	}
+
+	if h := heap.FromMapValues(g.Less[int], map[string]int{}); h.Size() != 0 {
+		t.Fatal("heap from an empty map should be empty")
+	}
+}
+
+func ExampleFromMapValues() {
+	counts := map[string]int{"a": 3, "b": 1, "c": 2}
+	h := heap.FromMapValues(func(a, b int) bool { return a > b }, counts)
+	for h.Size() > 0 {
+		kv, _ := h.Pop()
+		fmt.Println(kv.Key, kv.Val)
+	}
+	// Output:
+	// a 3
+	// c 2
+	// b 1
+}
+
+func ExampleSort() {
+	data := []int{5, 2, 8, 1, 9}
+	heap.Sort(func(a, b int) bool { return a < b }, data)
+	fmt.Println(data)
+	// Output:
+	// [1 2 5 8 9]
+}
+
 func Example() {
 	heap := heap.New(func(a, b int) bool { return a < b })
 
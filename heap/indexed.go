@@ -0,0 +1,164 @@
+package heap
+
+import (
+	g "github.com/zyedidia/generic"
+)
+
+type item[K comparable, P any] struct {
+	key      K
+	priority P
+}
+
+// Indexed is a binary heap of distinct keys, each with a priority. It keeps
+// track of the position of every key, so that the priority of a key already in
+// the heap can be changed or the key removed in O(lg n) time.
+type Indexed[K comparable, P any] struct {
+	data  []item[K, P]
+	index map[K]int
+	less  g.LessFn[P]
+}
+
+// NewIndexed returns a new indexed heap, where the key with the minimum
+// priority according to 'less' is at the top.
+func NewIndexed[K comparable, P any](less g.LessFn[P]) *Indexed[K, P] {
+	return &Indexed[K, P]{
+		index: make(map[K]int),
+		less:  less,
+	}
+}
+
+// Push adds 'key' to the heap with the given priority. If 'key' is already in
+// the heap, its priority is updated instead.
+func (h *Indexed[K, P]) Push(key K, priority P) {
+	if h.Update(key, priority) {
+		return
+	}
+	h.data = append(h.data, item[K, P]{key, priority})
+	h.index[key] = len(h.data) - 1
+	h.up(len(h.data) - 1)
+}
+
+// Pop removes and returns the key with the minimum priority, along with its
+// priority. If the heap is empty, it returns zero values and false.
+func (h *Indexed[K, P]) Pop() (K, P, bool) {
+	if len(h.data) == 0 {
+		var k K
+		var p P
+		return k, p, false
+	}
+	top := h.data[0]
+	h.removeAt(0)
+	return top.key, top.priority, true
+}
+
+// Peek returns the key with the minimum priority, along with its priority,
+// without removing it. If the heap is empty, it returns zero values and false.
+func (h *Indexed[K, P]) Peek() (K, P, bool) {
+	if len(h.data) == 0 {
+		var k K
+		var p P
+		return k, p, false
+	}
+	return h.data[0].key, h.data[0].priority, true
+}
+
+// Update changes the priority of 'key' and restores the heap order. It
+// returns false if 'key' is not in the heap.
+func (h *Indexed[K, P]) Update(key K, priority P) bool {
+	i, ok := h.index[key]
+	if !ok {
+		return false
+	}
+	h.data[i].priority = priority
+	h.fix(i)
+	return true
+}
+
+// Remove removes 'key' from the heap. It returns false if 'key' is not in the
+// heap.
+func (h *Indexed[K, P]) Remove(key K) bool {
+	i, ok := h.index[key]
+	if !ok {
+		return false
+	}
+	h.removeAt(i)
+	return true
+}
+
+// Priority returns the priority of 'key', or false if it is not in the heap.
+func (h *Indexed[K, P]) Priority(key K) (P, bool) {
+	i, ok := h.index[key]
+	if !ok {
+		var p P
+		return p, false
+	}
+	return h.data[i].priority, true
+}
+
+// Size returns the number of keys in the heap.
+func (h *Indexed[K, P]) Size() int {
+	return len(h.data)
+}
+
+func (h *Indexed[K, P]) removeAt(i int) {
+	last := len(h.data) - 1
+	delete(h.index, h.data[i].key)
+	if i != last {
+		h.data[i] = h.data[last]
+		h.index[h.data[i].key] = i
+	}
+	h.data[last] = item[K, P]{}
+	h.data = h.data[:last]
+	if i < len(h.data) {
+		h.fix(i)
+	}
+}
+
+func (h *Indexed[K, P]) fix(i int) {
+	if !h.down(i) {
+		h.up(i)
+	}
+}
+
+func (h *Indexed[K, P]) swap(i, j int) {
+	h.data[i], h.data[j] = h.data[j], h.data[i]
+	h.index[h.data[i].key] = i
+	h.index[h.data[j].key] = j
+}
+
+// down sifts the item at 'i' down, and returns whether it moved.
+func (h *Indexed[K, P]) down(i int) bool {
+	start := i
+	for {
+		left, right := 2*i+1, 2*i+2
+		if left >= len(h.data) || left < 0 { // `left < 0` in case of overflow
+			break
+		}
+
+		// find the smallest child
+		j := left
+		if right < len(h.data) && h.less(h.data[right].priority, h.data[left].priority) {
+			j = right
+		}
+
+		if !h.less(h.data[j].priority, h.data[i].priority) {
+			break
+		}
+
+		h.swap(i, j)
+		i = j
+	}
+	return i != start
+}
+
+func (h *Indexed[K, P]) up(i int) {
+	for {
+		parent := (i - 1) / 2
+		if i == 0 || !h.less(h.data[i].priority, h.data[parent].priority) {
+			break
+		}
+
+		h.swap(i, parent)
+		i = parent
+	}
+}
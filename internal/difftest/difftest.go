@@ -0,0 +1,196 @@
+// Package difftest provides a harness that applies the same random sequence
+// of operations to several ordered map implementations, and checks that they
+// all behave identically to a simple reference implementation.
+package difftest
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// OrderedMap is the common API of the ordered maps under test.
+type OrderedMap interface {
+	Put(key, val int)
+	Get(key int) (int, bool)
+	Remove(key int)
+	Each(fn func(key, val int))
+	Size() int
+}
+
+// RangeCounter is implemented by ordered maps that support counting the keys
+// in a range.
+type RangeCounter interface {
+	CountRange(lo, hi int) int
+}
+
+// reference is an ordered map built from a Go map and a sort of its keys.
+type reference map[int]int
+
+func (r reference) Put(key, val int) {
+	r[key] = val
+}
+
+func (r reference) Get(key int) (int, bool) {
+	v, ok := r[key]
+	return v, ok
+}
+
+func (r reference) Remove(key int) {
+	delete(r, key)
+}
+
+func (r reference) Each(fn func(key, val int)) {
+	keys := make([]int, 0, len(r))
+	for k := range r {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	for _, k := range keys {
+		fn(k, r[k])
+	}
+}
+
+func (r reference) Size() int {
+	return len(r)
+}
+
+func (r reference) CountRange(lo, hi int) int {
+	n := 0
+	for k := range r {
+		if k >= lo && k < hi {
+			n++
+		}
+	}
+	return n
+}
+
+// Failure describes the first operation at which an implementation diverged
+// from the reference. Since operations are generated deterministically from
+// the seed, replaying the seed for Op+1 operations reproduces it.
+type Failure struct {
+	Seed int64
+	Op   int
+	Impl string
+	Msg  string
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("seed %d, op %d: %s: %s", f.Seed, f.Op, f.Impl, f.Msg)
+}
+
+// Run applies 'nops' random operations generated from 'seed' to fresh maps
+// returned by the constructors in 'impls', over keys in [0, keyspace). It
+// returns the first divergence from the reference, or nil.
+func Run(seed int64, nops, keyspace int, impls map[string]func() OrderedMap) *Failure {
+	rng := rand.New(rand.NewSource(seed))
+	ref := reference{}
+
+	names := make([]string, 0, len(impls))
+	for name := range impls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	maps := make([]OrderedMap, len(names))
+	for i, name := range names {
+		maps[i] = impls[name]()
+	}
+
+	for op := 0; op < nops; op++ {
+		key := rng.Intn(keyspace)
+		var describe string
+		var check func(m OrderedMap) string
+
+		switch rng.Intn(6) {
+		case 0, 1:
+			val := rng.Int()
+			describe = fmt.Sprintf("Put(%d, %d)", key, val)
+			ref.Put(key, val)
+			for _, m := range maps {
+				m.Put(key, val)
+			}
+		case 2:
+			describe = fmt.Sprintf("Remove(%d)", key)
+			ref.Remove(key)
+			for _, m := range maps {
+				m.Remove(key)
+			}
+		case 3:
+			describe = fmt.Sprintf("Get(%d)", key)
+			rv, rok := ref.Get(key)
+			check = func(m OrderedMap) string {
+				if v, ok := m.Get(key); v != rv || ok != rok {
+					return fmt.Sprintf("got %d, %v, want %d, %v", v, ok, rv, rok)
+				}
+				return ""
+			}
+		case 4:
+			hi := rng.Intn(keyspace + 1)
+			describe = fmt.Sprintf("CountRange(%d, %d)", key, hi)
+			want := ref.CountRange(key, hi)
+			check = func(m OrderedMap) string {
+				rc, ok := m.(RangeCounter)
+				if !ok {
+					return ""
+				}
+				if got := rc.CountRange(key, hi); got != want {
+					return fmt.Sprintf("got %d, want %d", got, want)
+				}
+				return ""
+			}
+		case 5:
+			describe = "Each"
+			want := contents(ref)
+			check = func(m OrderedMap) string {
+				got := contents(m)
+				if !equal(got, want) {
+					return fmt.Sprintf("visited %v, want %v", got, want)
+				}
+				return ""
+			}
+		}
+
+		for i, m := range maps {
+			msg := ""
+			if check != nil {
+				msg = check(m)
+			}
+			if msg == "" && m.Size() != ref.Size() {
+				msg = fmt.Sprintf("Size() = %d, want %d", m.Size(), ref.Size())
+			}
+			if msg != "" {
+				return &Failure{
+					Seed: seed,
+					Op:   op,
+					Impl: names[i],
+					Msg:  describe + ": " + msg,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+type pair struct {
+	key, val int
+}
+
+func contents(m OrderedMap) []pair {
+	var kvs []pair
+	m.Each(func(key, val int) {
+		kvs = append(kvs, pair{key, val})
+	})
+	return kvs
+}
+
+func equal(a, b []pair) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package difftest
+
+import (
+	"flag"
+	"testing"
+
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/avl"
+	"github.com/zyedidia/generic/btree"
+)
+
+var (
+	slow = flag.Bool("slow", false, "run many more and longer operation sequences")
+	seed = flag.Int64("seed", 0, "replay a single seed")
+)
+
+var impls = map[string]func() OrderedMap{
+	"avl": func() OrderedMap {
+		return avl.New[int, int](g.Less[int])
+	},
+	"btree": func() OrderedMap {
+		return btree.New[int, int](g.Less[int])
+	},
+}
+
+func TestDifferential(t *testing.T) {
+	nseeds, nops := 50, 500
+	if *slow {
+		nseeds, nops = 500, 5000
+	}
+
+	seeds := make([]int64, 0, nseeds)
+	if *seed != 0 {
+		seeds = append(seeds, *seed)
+	} else {
+		for s := int64(1); s <= int64(nseeds); s++ {
+			seeds = append(seeds, s)
+		}
+	}
+
+	for _, s := range seeds {
+		// small keyspaces exercise overwrites and removals, large ones
+		// exercise node splits
+		for _, keyspace := range []int{16, 1024} {
+			if f := Run(s, nops, keyspace, impls); f != nil {
+				t.Fatalf("%v (keyspace %d; replay with -seed=%d)", f, keyspace, f.Seed)
+			}
+		}
+	}
+}
+
+type broken struct {
+	OrderedMap
+}
+
+func (b broken) Size() int {
+	return b.OrderedMap.Size() + 1
+}
+
+func TestDetectsDivergence(t *testing.T) {
+	f := Run(1, 100, 16, map[string]func() OrderedMap{
+		"broken": func() OrderedMap {
+			return broken{reference{}}
+		},
+	})
+	if f == nil || f.Impl != "broken" || f.Op != 0 {
+		t.Fatalf("expected divergence at the first op, got %v", f)
+	}
+}
@@ -0,0 +1,49 @@
+// Package testutil provides helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// MustPanic calls 'fn' and fails the test unless it panics with a value whose
+// string form mentions 'want'.
+func MustPanic(t testing.TB, want string, fn func()) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), want) {
+			t.Fatalf("got panic %v, want one mentioning %q", r, want)
+		}
+	}()
+	fn()
+}
+
+// HeapAlloc runs a garbage collection and returns the number of bytes of
+// live heap objects. The difference between two calls measures the memory
+// retained by what was allocated in between, but it depends on the garbage
+// collector and the allocator, so it is only suitable for benchmarks.
+func HeapAlloc() uint64 {
+	var ms runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+// ReportMemory calls 'build' b.N times, and reports the average number of
+// heap bytes retained by the value it returns as the metric "heap-B", and the
+// average of 'estimate' for the value as "estimate-B", so that a Memory
+// method can be compared with what is actually allocated.
+func ReportMemory[T any](b *testing.B, build func() T, estimate func(T) int64) {
+	var heap, est int64
+	for i := 0; i < b.N; i++ {
+		before := HeapAlloc()
+		v := build()
+		heap += int64(HeapAlloc()) - int64(before)
+		est += estimate(v)
+		runtime.KeepAlive(v)
+	}
+	b.ReportMetric(float64(heap)/float64(b.N), "heap-B")
+	b.ReportMetric(float64(est)/float64(b.N), "estimate-B")
+}
@@ -0,0 +1,132 @@
+package interval
+
+import "golang.org/x/exp/constraints"
+
+// An Iter points to an interval in a Tree, and steps through the intervals in
+// order of their starting positions. Each step takes amortized O(1) time.
+//
+// An iterator is only valid until the tree is modified: any use of it after a
+// call to Add, Put, Remove, Coalesce or Clear panics. An iterator holds no
+// resources, so it may be abandoned at any point.
+type Iter[I constraints.Ordered, V any] struct {
+	tree *Tree[I, V]
+	mods uint64
+
+	// stack holds the current node on top, and below it the ancestors whose
+	// intervals come after it and remain to be visited.
+	stack []*node[I, V]
+
+	// overlap is set for iterators returned by IterOverlaps, which skip
+	// intervals that do not overlap with 'query'.
+	overlap bool
+	query   intrvl[I]
+}
+
+// Iter returns an iterator pointing to the interval with the lowest starting
+// position. If the tree is empty, the iterator is not valid.
+func (t *Tree[I, V]) Iter() *Iter[I, V] {
+	it := t.newIter()
+	it.pushLeft(t.root)
+	return it
+}
+
+// Seek returns an iterator pointing to the first interval whose starting
+// position is at least 'low'. If there is none, the iterator is not valid.
+// Complexity: O(lg n).
+func (t *Tree[I, V]) Seek(low I) *Iter[I, V] {
+	it := t.newIter()
+	for n := t.root; n != nil; {
+		if n.key.low >= low {
+			it.stack = append(it.stack, n)
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return it
+}
+
+// IterOverlaps returns an iterator over the intervals that overlap with
+// [low, high), in the order of Overlaps. Unlike Overlaps, it finds each
+// interval only when the iterator reaches it, so stepping through the first
+// few of many overlapping intervals is cheap.
+func (t *Tree[I, V]) IterOverlaps(low, high I) *Iter[I, V] {
+	it := t.newIter()
+	it.overlap = true
+	it.query = newIntrvl(low, high)
+	it.pushLeft(t.root)
+	it.skip()
+	return it
+}
+
+func (t *Tree[I, V]) newIter() *Iter[I, V] {
+	return &Iter[I, V]{
+		tree: t,
+		mods: t.mods,
+	}
+}
+
+// pushLeft pushes 'n' and its chain of left children. For an overlap
+// iterator, the chain stops at a subtree whose intervals all end before the
+// query starts.
+func (it *Iter[I, V]) pushLeft(n *node[I, V]) {
+	for n != nil && (!it.overlap || n.max > it.query.low) {
+		it.stack = append(it.stack, n)
+		n = n.left
+	}
+}
+
+// pop removes the current node and makes its successor current.
+func (it *Iter[I, V]) pop() {
+	n := it.stack[len(it.stack)-1]
+	it.stack = it.stack[:len(it.stack)-1]
+	it.pushLeft(n.right)
+}
+
+// skip advances an overlap iterator to the next interval that overlaps with
+// the query, if the current one does not.
+func (it *Iter[I, V]) skip() {
+	for it.overlap && len(it.stack) > 0 {
+		n := it.stack[len(it.stack)-1]
+		if n.key.low >= it.query.high {
+			// every later interval starts after the query ends
+			it.stack = it.stack[:0]
+			return
+		}
+		if overlaps(n.key, it.query) {
+			return
+		}
+		it.pop()
+	}
+}
+
+func (it *Iter[I, V]) check() {
+	if it.mods != it.tree.mods {
+		panic("interval: iterator used after the tree was modified")
+	}
+}
+
+// IsValid returns true if the iterator points to an interval.
+func (it *Iter[I, V]) IsValid() bool {
+	it.check()
+	return len(it.stack) > 0
+}
+
+// Get returns the interval the iterator points to, and its value. It should
+// only be called when IsValid returns true.
+func (it *Iter[I, V]) Get() KV[I, V] {
+	it.check()
+	return newKV(it.stack[len(it.stack)-1])
+}
+
+// Next moves the iterator to the next interval and returns true if the
+// iterator is still valid.
+func (it *Iter[I, V]) Next() bool {
+	it.check()
+	if len(it.stack) == 0 {
+		return false
+	}
+	it.pop()
+	it.skip()
+	return len(it.stack) > 0
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/zyedidia/generic"
 	"golang.org/x/exp/constraints"
+	"golang.org/x/exp/slices"
 )
 
 type KV[I constraints.Ordered, V any] struct {
@@ -57,6 +58,8 @@ func overlaps[I constraints.Ordered](i1 intrvl[I], i2 intrvl[I]) bool {
 // exclusive.
 type Tree[I constraints.Ordered, V any] struct {
 	root *node[I, V]
+	// mods counts modifications, so that iterators can detect them.
+	mods uint64
 }
 
 // New returns an empty interval tree.
@@ -69,6 +72,7 @@ func New[I constraints.Ordered, V any]() *Tree[I, V] {
 // If an interval starting at low already exists in t, this method doesn't
 // perform any change of the tree, but returns the conflicting interval.
 func (t *Tree[I, V]) Add(low, high I, value V) (KV[I, V], bool) {
+	t.mods++
 	newRoot, kv, ok := t.root.insert(newIntrvl(low, high), value, false)
 	t.root = newRoot
 	return kv, ok
@@ -79,6 +83,7 @@ func (t *Tree[I, V]) Add(low, high I, value V) (KV[I, V], bool) {
 // If an interval starting at low already exists, this method will replace it.
 // In such a case the conflicting (replaced) interval is returned.
 func (t *Tree[I, V]) Put(low, high I, value V) (KV[I, V], bool) {
+	t.mods++
 	newRoot, kv, ok := t.root.insert(newIntrvl(low, high), value, true)
 	t.root = newRoot
 	return kv, ok
@@ -93,6 +98,7 @@ func (t *Tree[I, V]) Overlaps(low, high I) []KV[I, V] {
 // Remove deletes the interval starting at low. The removed interval is
 // returned. If no such interval existed in a tree, the returned value is false.
 func (t *Tree[I, V]) Remove(low I) (KV[I, V], bool) {
+	t.mods++
 	newRoot, kv, ok := t.root.remove(low)
 	t.root = newRoot
 	return kv, ok
@@ -114,6 +120,139 @@ func (t *Tree[I, V]) Each(fn func(low, high I, val V)) {
 	t.root.each(fn)
 }
 
+// MaxOverlap returns the largest number of intervals in the tree that contain
+// a common position, and the lowest such position. Since intervals are
+// half-open, an interval ending at a position does not overlap one starting
+// there, and empty intervals contain no positions. It returns 0 if the tree
+// has no non-empty intervals. Complexity: O(n lg n).
+func (t *Tree[I, V]) MaxOverlap() (count int, at I) {
+	var lows, highs []I
+	t.Each(func(low, high I, val V) {
+		if low < high {
+			lows = append(lows, low)
+			highs = append(highs, high)
+		}
+	})
+	slices.Sort(highs)
+
+	// sweep over the starts, which Each visits in order, ending the
+	// intervals whose ends are at or before each start
+	active, ended := 0, 0
+	for _, low := range lows {
+		for highs[ended] <= low {
+			ended++
+			active--
+		}
+		active++
+		if active > count {
+			count, at = active, low
+		}
+	}
+	return count, at
+}
+
+// EachNode calls 'fn' on every node in the tree in order, with the node's
+// interval, the maximum upper bound stored in the subtree rooted at the node,
+// and the height of that subtree. It exposes the tree's augmentation for
+// debugging.
+func (t *Tree[I, V]) EachNode(fn func(low, high I, max I, height int)) {
+	t.root.eachNode(fn)
+}
+
+func (n *node[I, V]) eachNode(fn func(low, high I, max I, height int)) {
+	if n == nil {
+		return
+	}
+	n.left.eachNode(fn)
+	fn(n.key.low, n.key.high, n.max, n.height)
+	n.right.eachNode(fn)
+}
+
+// validate checks the invariants of the tree: starting positions are in
+// order, and every node has the correct max, the correct height, and a
+// balance factor between -1 and 1.
+func (t *Tree[I, V]) validate() error {
+	_, err := t.root.validate(nil, nil)
+	return err
+}
+
+// validate checks the subtree rooted at 'n', whose starting positions must lie
+// strictly between 'lo' and 'hi' if they are not nil, and returns its true
+// maximum upper bound.
+func (n *node[I, V]) validate(lo, hi *I) (I, error) {
+	var max I
+	if n == nil {
+		return max, nil
+	}
+	if (lo != nil && n.key.low <= *lo) || (hi != nil && n.key.low >= *hi) {
+		return max, fmt.Errorf("interval: node at %v is out of order", n.key.low)
+	}
+	max = n.key.high
+	if n.left != nil {
+		lmax, err := n.left.validate(lo, &n.key.low)
+		if err != nil {
+			return max, err
+		}
+		max = generic.Max(max, lmax)
+	}
+	if n.right != nil {
+		rmax, err := n.right.validate(&n.key.low, hi)
+		if err != nil {
+			return max, err
+		}
+		max = generic.Max(max, rmax)
+	}
+
+	if n.max != max {
+		return max, fmt.Errorf("interval: node at %v has max %v, want %v", n.key.low, n.max, max)
+	}
+	lh, rh := n.left.getHeight(), n.right.getHeight()
+	if h := 1 + generic.Max(lh, rh); n.height != h {
+		return max, fmt.Errorf("interval: node at %v has height %d, want %d", n.key.low, n.height, h)
+	}
+	if d := lh - rh; d < -1 || d > 1 {
+		return max, fmt.Errorf("interval: node at %v has balance factor %d", n.key.low, d)
+	}
+	return max, nil
+}
+
+// Coalesce merges stored intervals that overlap or are adjacent. Intervals
+// are visited in order of their starting positions, and each interval is
+// compared with the result of merging its predecessors: if they overlap or
+// touch, and canMerge returns true, they are replaced by a single interval
+// spanning both, associated with the value returned by canMerge. The tree is
+// rebuilt balanced from the merged intervals. Complexity: O(n).
+func (t *Tree[I, V]) Coalesce(canMerge func(a, b KV[I, V]) (V, bool)) {
+	t.mods++
+	var merged []KV[I, V]
+	t.root.each(func(low, high I, val V) {
+		kv := KV[I, V]{low, high, val}
+		if n := len(merged); n > 0 && low <= merged[n-1].High {
+			if v, ok := canMerge(merged[n-1], kv); ok {
+				merged[n-1].High = generic.Max(merged[n-1].High, high)
+				merged[n-1].Val = v
+				return
+			}
+		}
+		merged = append(merged, kv)
+	})
+	t.root = fromSorted(merged)
+}
+
+// MergeTouching merges stored intervals that overlap or are adjacent and have
+// equal values. See Tree.Coalesce.
+func MergeTouching[I constraints.Ordered, V comparable](t *Tree[I, V]) {
+	t.Coalesce(func(a, b KV[I, V]) (V, bool) {
+		return a.Val, a.Val == b.Val
+	})
+}
+
+// Clear removes all intervals from the tree.
+func (t *Tree[I, V]) Clear() {
+	t.mods++
+	t.root = nil
+}
+
 // Height returns the height of the tree.
 func (t *Tree[I, V]) Height() int {
 	return t.root.getHeight()
@@ -137,6 +276,24 @@ type node[I constraints.Ordered, V any] struct {
 	max I
 }
 
+// fromSorted builds a balanced tree from intervals sorted by their starting
+// positions.
+func fromSorted[I constraints.Ordered, V any](kvs []KV[I, V]) *node[I, V] {
+	if len(kvs) == 0 {
+		return nil
+	}
+	mid := len(kvs) / 2
+	n := &node[I, V]{
+		key:   intrvl[I]{kvs[mid].Low, kvs[mid].High},
+		value: kvs[mid].Val,
+		left:  fromSorted(kvs[:mid]),
+		right: fromSorted(kvs[mid+1:]),
+	}
+	n.recalculateHeight()
+	n.updateMax()
+	return n
+}
+
 // insert inserts interval key associated with value value to the tree.
 //
 // If interval starting at key.low already exists in a tree, behaviour of this
@@ -2,6 +2,7 @@ package interval
 
 import (
 	"fmt"
+	"math/rand"
 	"testing"
 )
 
@@ -52,6 +53,8 @@ func TestPut(t *testing.T) {
 		vals: []string{"foo3", "foo2"},
 	}}
 
+	checkValid(t, tree)
+
 	for i, tt := range tests {
 		tt := tt
 		t.Run(fmt.Sprintf("test_%d", i), func(t *testing.T) {
@@ -73,6 +76,269 @@ func TestPut(t *testing.T) {
 	}
 }
 
+func intervals[V any](tree *Tree[int, V]) string {
+	s := ""
+	tree.Each(func(low, high int, val V) {
+		s += fmt.Sprintf("[%d,%d):%v ", low, high, val)
+	})
+	return s
+}
+
+func checkValid[V any](t *testing.T, tree *Tree[int, V]) {
+	t.Helper()
+	if err := tree.validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeTouching(t *testing.T) {
+	tree := New[int, string]()
+	tree.Put(0, 2, "a")
+	tree.Put(2, 4, "a")   // adjacent to [0,2)
+	tree.Put(3, 6, "a")   // overlaps [2,4)
+	tree.Put(6, 7, "b")   // adjacent, but a different value
+	tree.Put(7, 9, "a")   // adjacent, but separated by "b"
+	tree.Put(10, 11, "a") // not touching
+	tree.Put(11, 12, "a")
+	tree.Put(12, 20, "c")
+	tree.Put(13, 14, "c") // contained in [12,20)
+
+	MergeTouching(tree)
+	want := "[0,6):a [6,7):b [7,9):a [10,12):a [12,20):c "
+	if got := intervals(tree); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if tree.Size() != 5 {
+		t.Fatalf("size mismatch: %d != 5", tree.Size())
+	}
+	checkValid(t, tree)
+
+	// coalescing again changes nothing
+	MergeTouching(tree)
+	if got := intervals(tree); got != want {
+		t.Fatalf("not idempotent: got %s, want %s", got, want)
+	}
+
+	ov := tree.Overlaps(5, 11)
+	if len(ov) != 4 || ov[0].Low != 0 || ov[3].Low != 10 {
+		t.Fatalf("overlaps after coalescing: %v", ov)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	tree := New[int, int]()
+	for i := 0; i < 100; i++ {
+		tree.Put(i, i+1, 1)
+	}
+	tree.Put(50, 51, -1) // blocks merging
+
+	tree.Coalesce(func(a, b KV[int, int]) (int, bool) {
+		return a.Val + b.Val, a.Val > 0 && b.Val > 0
+	})
+	want := "[0,50):50 [50,51):-1 [51,100):49 "
+	if got := intervals(tree); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	checkValid(t, tree)
+
+	empty := New[int, int]()
+	MergeTouching(empty)
+	if empty.Size() != 0 {
+		t.Fatal("empty tree should stay empty")
+	}
+}
+
+func TestValidateRotations(t *testing.T) {
+	// Each sequence ends by inserting into the inner subtree of a child of
+	// the root, which needs a double rotation that makes that subtree's root
+	// the new root of the tree. The last interval has the largest upper
+	// bound, so max must move up through both rotations.
+	tests := []struct {
+		lows []int
+		root int
+	}{
+		{[]int{30, 10, 20}, 20}, // left-right
+		{[]int{10, 30, 20}, 20}, // right-left
+		{[]int{50, 20, 70, 10, 30, 25}, 30},
+		{[]int{50, 20, 70, 60, 80, 65}, 60},
+	}
+	for _, tt := range tests {
+		tree := New[int, int]()
+		for i, low := range tt.lows {
+			high := low + 1
+			if i == len(tt.lows)-1 {
+				high = 1000
+			}
+			tree.Put(low, high, i)
+			checkValid(t, tree)
+		}
+		var root []int
+		tree.EachNode(func(low, high, max, height int) {
+			if height == tree.Height() {
+				root = append(root, low, max)
+			}
+		})
+		if want := []int{tt.root, 1000}; fmt.Sprint(root) != fmt.Sprint(want) {
+			t.Fatalf("%v: root (low, max) = %v, want %v", tt.lows, root, want)
+		}
+	}
+}
+
+func TestValidateRandom(t *testing.T) {
+	tree := New[int, int]()
+	for i := 0; i < 2000; i++ {
+		low := rand.Intn(500)
+		if rand.Intn(3) == 0 {
+			tree.Remove(low)
+		} else {
+			tree.Put(low, low+rand.Intn(100), i)
+		}
+		checkValid(t, tree)
+	}
+
+	// validate catches a stale augmentation
+	tree.root.max--
+	if tree.validate() == nil {
+		t.Fatal("corrupted max not detected")
+	}
+}
+
+func TestClear(t *testing.T) {
+	tree := New[int, int]()
+	for i := 0; i < 10; i++ {
+		tree.Put(i, i+1, i)
+	}
+	tree.Clear()
+	if tree.Size() != 0 || len(tree.Overlaps(0, 10)) != 0 {
+		t.Fatal("tree not empty after Clear")
+	}
+	tree.Put(3, 4, 3)
+	if intervals(tree) != "[3,4):3 " {
+		t.Fatalf("got %s after reinsertion", intervals(tree))
+	}
+}
+
+func randomTree(n int) *Tree[int, int] {
+	tree := New[int, int]()
+	for i := 0; i < n; i++ {
+		low := rand.Intn(10 * n)
+		tree.Put(low, low+1+rand.Intn(50), i)
+	}
+	return tree
+}
+
+func TestMaxOverlap(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals [][2]int
+		count, at int
+	}{
+		{"empty", nil, 0, 0},
+		{"single", [][2]int{{3, 5}}, 1, 3},
+		{"nested", [][2]int{{0, 100}, {10, 50}, {20, 30}, {60, 70}}, 3, 20},
+		{"staggered", [][2]int{{0, 10}, {5, 15}, {8, 20}, {12, 25}, {18, 30}}, 3, 8},
+		{"touching", [][2]int{{0, 5}, {5, 10}, {10, 15}}, 1, 0},
+		{"empty intervals", [][2]int{{0, 10}, {2, 2}, {12, 12}}, 1, 0},
+	}
+	for _, tt := range tests {
+		tree := New[int, int]()
+		for i, iv := range tt.intervals {
+			tree.Put(iv[0], iv[1], i)
+		}
+		if count, at := tree.MaxOverlap(); count != tt.count || at != tt.at {
+			t.Errorf("%s: MaxOverlap() = %d at %d, want %d at %d", tt.name, count, at, tt.count, tt.at)
+		}
+	}
+
+	// check against the number of intervals containing each position
+	for i := 0; i < 20; i++ {
+		tree := randomTree(100)
+		count, at := tree.MaxOverlap()
+		contains := func(p int) int {
+			return len(tree.Overlaps(p, p+1))
+		}
+		if contains(at) != count {
+			t.Fatalf("MaxOverlap() = %d at %d, but %d intervals contain it", count, at, contains(at))
+		}
+		for p := 0; p < 1100; p++ {
+			if c := contains(p); c > count {
+				t.Fatalf("%d intervals contain %d, more than MaxOverlap() = %d", c, p, count)
+			}
+		}
+	}
+}
+
+func iterated(it *Iter[int, int]) []KV[int, int] {
+	var kvs []KV[int, int]
+	for ; it.IsValid(); it.Next() {
+		kvs = append(kvs, it.Get())
+	}
+	return kvs
+}
+
+func TestIter(t *testing.T) {
+	tree := randomTree(500)
+	var want []KV[int, int]
+	tree.Each(func(low, high, val int) {
+		want = append(want, KV[int, int]{low, high, val})
+	})
+	if got := iterated(tree.Iter()); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatal("Iter and Each differ")
+	}
+	if New[int, int]().Iter().IsValid() {
+		t.Fatal("iterator over an empty tree is valid")
+	}
+
+	// paginate with Seek, starting each page after the last interval of the
+	// previous one
+	var paged []KV[int, int]
+	for it := tree.Seek(-1); it.IsValid(); {
+		for i := 0; i < 7 && it.IsValid(); i++ {
+			paged = append(paged, it.Get())
+			it.Next()
+		}
+		if !it.IsValid() {
+			break
+		}
+		it = tree.Seek(paged[len(paged)-1].Low + 1)
+	}
+	if fmt.Sprint(paged) != fmt.Sprint(want) {
+		t.Fatal("paginating with Seek differs from Each")
+	}
+	last := want[len(want)-1].Low
+	if tree.Seek(last + 1).IsValid() {
+		t.Fatal("Seek past the last interval is valid")
+	}
+	if it := tree.Seek(last); !it.IsValid() || it.Get().Low != last || it.Next() {
+		t.Fatal("Seek to the last interval")
+	}
+}
+
+func TestIterOverlaps(t *testing.T) {
+	tree := randomTree(500)
+	for i := 0; i < 200; i++ {
+		low := rand.Intn(5200) - 100
+		high := low + rand.Intn(200)
+		got := iterated(tree.IterOverlaps(low, high))
+		if want := tree.Overlaps(low, high); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("IterOverlaps(%d, %d) = %v, want %v", low, high, got, want)
+		}
+	}
+}
+
+func TestIterModified(t *testing.T) {
+	tree := randomTree(10)
+	it := tree.Iter()
+	it.Next()
+	tree.Put(-1, 0, 0)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("using an iterator after a modification did not panic")
+		}
+	}()
+	it.Next()
+}
+
 func Example() {
 	tree := New[int, string]()
 	tree.Put(0, 10, "foo")
@@ -0,0 +1,22 @@
+package generic
+
+import "sync"
+
+// Lazy returns a function that calls 'init' the first time it is called and
+// returns the result, and returns the same result on every later call without
+// calling 'init' again. It is safe to call the returned function from
+// multiple goroutines: concurrent first callers wait for the single call to
+// 'init' to complete. If 'init' panics, later calls return the zero value.
+func Lazy[T any](init func() T) func() T {
+	var (
+		once sync.Once
+		v    T
+	)
+	return func() T {
+		once.Do(func() {
+			v = init()
+			init = nil // release anything captured by 'init'
+		})
+		return v
+	}
+}
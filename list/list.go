@@ -4,8 +4,15 @@
 package list
 
 // List implements a doubly-linked list.
+//
+// The list counts its nodes, so Len takes constant time. The count is kept by
+// the methods of List, so it is only correct if every node is added and
+// removed with them, rather than by setting Front, Back, Prev or Next
+// directly.
 type List[V any] struct {
 	Front, Back *Node[V]
+
+	length int
 }
 
 // Node is a node in the linked list.
@@ -33,7 +40,14 @@ func (l *List[V]) PushFront(v V) {
 	})
 }
 
-// PushBackNode adds the node 'n' to the back of the list.
+// Len returns the number of nodes in the list.
+func (l *List[V]) Len() int {
+	return l.length
+}
+
+// PushBackNode adds the node 'n' to the back of the list. Only 'n' itself is
+// added: its Prev and Next pointers are overwritten, so it must not be the
+// start of a chain of nodes, nor already be in a list.
 func (l *List[V]) PushBackNode(n *Node[V]) {
 	n.Next = nil
 	n.Prev = l.Back
@@ -43,9 +57,11 @@ func (l *List[V]) PushBackNode(n *Node[V]) {
 		l.Front = n
 	}
 	l.Back = n
+	l.length++
 }
 
-// PushFrontNode adds the node 'n' to the front of the list.
+// PushFrontNode adds the node 'n' to the front of the list. As with
+// PushBackNode, only 'n' itself is added.
 func (l *List[V]) PushFrontNode(n *Node[V]) {
 	n.Next = l.Front
 	n.Prev = nil
@@ -55,9 +71,11 @@ func (l *List[V]) PushFrontNode(n *Node[V]) {
 		l.Back = n
 	}
 	l.Front = n
+	l.length++
 }
 
-// InsertAfter adds 'next' into the list after 'n'. Returns the added node.
+// InsertAfter adds 'next' into the list after 'n'. Returns the added node. As
+// with PushBackNode, only 'next' itself is added.
 func (l *List[V]) InsertAfter(n *Node[V], next *Node[V]) *Node[V] {
 	next.Next = n.Next
 	next.Prev = n
@@ -67,10 +85,12 @@ func (l *List[V]) InsertAfter(n *Node[V], next *Node[V]) *Node[V] {
 		l.Back = next
 	}
 	n.Next = next
+	l.length++
 	return next
 }
 
 // InsertBefore adds 'prev' into the list before 'n'. Returns the added node.
+// As with PushBackNode, only 'prev' itself is added.
 func (l *List[V]) InsertBefore(n *Node[V], prev *Node[V]) *Node[V] {
 	prev.Next = n
 	prev.Prev = n.Prev
@@ -80,10 +100,16 @@ func (l *List[V]) InsertBefore(n *Node[V], prev *Node[V]) *Node[V] {
 		l.Front = prev
 	}
 	n.Prev = prev
+	l.length++
 	return prev
 }
 
-// Remove removes the node 'n' from the list.
+// Remove removes the node 'n' from the list. The node keeps its Value and its
+// Prev and Next pointers, so that it can be pushed or inserted again, or used
+// to continue an iteration that removes nodes as it goes. While a removed
+// node is still referenced, it therefore keeps its value and its former
+// neighbours reachable; a caller that keeps the node but no longer needs them
+// should clear those fields. 'n' must be in this list.
 func (l *List[V]) Remove(n *Node[V]) {
 	if n.Next != nil {
 		n.Next.Prev = n.Prev
@@ -95,6 +121,36 @@ func (l *List[V]) Remove(n *Node[V]) {
 	} else {
 		l.Front = n.Next
 	}
+	l.length--
+}
+
+// Concat moves all nodes of 'other' to the back of this list in constant
+// time. 'other' is left empty. It panics if 'other' is the list itself, since
+// its nodes would form a cycle.
+func (l *List[V]) Concat(other *List[V]) {
+	if other == l {
+		panic("list: tried to concatenate a list with itself")
+	}
+	if other.Front == nil {
+		return
+	}
+	if l.Back == nil {
+		l.Front = other.Front
+	} else {
+		l.Back.Next = other.Front
+		other.Front.Prev = l.Back
+	}
+	l.Back = other.Back
+	l.length += other.length
+	other.Front, other.Back, other.length = nil, nil, 0
+}
+
+// Reverse reverses the order of the nodes in the list in place.
+func (l *List[V]) Reverse() {
+	for n := l.Front; n != nil; n = n.Prev {
+		n.Next, n.Prev = n.Prev, n.Next
+	}
+	l.Front, l.Back = l.Back, l.Front
 }
 
 // Each calls 'fn' on every element from this node onward in the list.
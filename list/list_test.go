@@ -2,10 +2,124 @@ package list_test
 
 import (
 	"fmt"
+	"reflect"
+	"testing"
 
 	"github.com/zyedidia/generic/list"
 )
 
+func of(vals ...int) *list.List[int] {
+	l := list.New[int]()
+	for _, v := range vals {
+		l.PushBack(v)
+	}
+	return l
+}
+
+func check(t *testing.T, l *list.List[int], want []int) {
+	t.Helper()
+	var got, rev []int
+	l.Front.Each(func(i int) {
+		got = append(got, i)
+	})
+	l.Back.EachReverse(func(i int) {
+		rev = append([]int{i}, rev...)
+	})
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(rev, want) {
+		t.Fatalf("got %v (reversed %v), want %v", got, rev, want)
+	}
+	if l.Len() != len(want) {
+		t.Fatalf("Len() = %d, want %d", l.Len(), len(want))
+	}
+}
+
+func TestConcat(t *testing.T) {
+	tests := []struct {
+		a, b []int
+	}{
+		{nil, nil},
+		{[]int{1, 2}, nil},
+		{nil, []int{3}},
+		{[]int{1, 2}, []int{3, 4, 5}},
+	}
+	for _, tt := range tests {
+		a, b := of(tt.a...), of(tt.b...)
+		a.Concat(b)
+		check(t, a, append(append([]int(nil), tt.a...), tt.b...))
+		check(t, b, nil)
+	}
+
+	l := of(1, 2)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("concatenating a list with itself did not panic")
+		}
+		check(t, l, []int{1, 2})
+	}()
+	l.Concat(l)
+}
+
+func TestReverse(t *testing.T) {
+	for _, vals := range [][]int{nil, {1}, {1, 2}, {1, 2, 3, 4, 5}} {
+		l := of(vals...)
+		l.Reverse()
+		want := make([]int, 0, len(vals))
+		for i := len(vals) - 1; i >= 0; i-- {
+			want = append(want, vals[i])
+		}
+		if len(want) == 0 {
+			want = nil
+		}
+		check(t, l, want)
+		l.PushBack(42)
+		check(t, l, append(want, 42))
+	}
+}
+
+func TestLen(t *testing.T) {
+	var l list.List[int] // the zero value is an empty list
+	check(t, &l, nil)
+
+	l.PushBack(2)
+	l.PushFront(1)
+	check(t, &l, []int{1, 2})
+
+	three := &list.Node[int]{Value: 3}
+	l.PushBackNode(three)
+	zero := &list.Node[int]{Value: 0}
+	l.PushFrontNode(zero)
+	check(t, &l, []int{0, 1, 2, 3})
+
+	l.InsertAfter(three, &list.Node[int]{Value: 5})
+	l.InsertBefore(l.Back, &list.Node[int]{Value: 4})
+	l.InsertAfter(zero, &list.Node[int]{Value: -1})
+	l.InsertBefore(zero, &list.Node[int]{Value: -2})
+	check(t, &l, []int{-2, 0, -1, 1, 2, 3, 4, 5})
+
+	// moving a node to the front, as the cache package does, keeps the count
+	l.Remove(three)
+	l.PushFrontNode(three)
+	check(t, &l, []int{3, -2, 0, -1, 1, 2, 4, 5})
+
+	l.Remove(l.Front)
+	l.Remove(l.Back)
+	l.Remove(zero)
+	check(t, &l, []int{-2, -1, 1, 2, 4})
+
+	l.Reverse()
+	check(t, &l, []int{4, 2, 1, -1, -2})
+
+	other := of(7, 8)
+	l.Concat(other)
+	check(t, &l, []int{4, 2, 1, -1, -2, 7, 8})
+	check(t, other, nil)
+
+	for l.Front != nil {
+		l.Remove(l.Front)
+	}
+	check(t, &l, nil)
+}
+
 func Example() {
 	l := list.New[int]()
 	l.PushBack(0)
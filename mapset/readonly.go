@@ -0,0 +1,91 @@
+package mapset
+
+import (
+	"sort"
+
+	g "github.com/zyedidia/generic"
+	"golang.org/x/exp/slices"
+)
+
+// A ReadOnlySet is an immutable snapshot of a Set, made by Finalize or
+// FinalizeSorted. It has no methods that modify it, and nothing else refers
+// to its storage, so it is safe for concurrent use by multiple goroutines.
+type ReadOnlySet[K comparable] struct {
+	m map[K]struct{}
+	// keys holds the elements in sorted order, and less the order, if the
+	// set was made by FinalizeSorted. Otherwise the elements are in m.
+	keys []K
+	less g.LessFn[K]
+}
+
+// Finalize returns a read-only snapshot of the elements of the set, stored in
+// a new map. Later changes to the set do not affect the snapshot. It takes
+// O(n) time.
+func (s Set[K]) Finalize() ReadOnlySet[K] {
+	return ReadOnlySet[K]{
+		m: s.Copy().m,
+	}
+}
+
+// FinalizeSorted is like Finalize, but stores the elements in a slice sorted
+// according to 'less', which uses less memory than a map. Has then takes
+// O(lg n) time using binary search, and Each and Keys visit the elements in
+// sorted order. It takes O(n lg n) time.
+func (s Set[K]) FinalizeSorted(less g.LessFn[K]) ReadOnlySet[K] {
+	keys := make([]K, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, less)
+	return ReadOnlySet[K]{
+		keys: keys,
+		less: less,
+	}
+}
+
+// Has returns true only if 'val' is in the set.
+func (r ReadOnlySet[K]) Has(val K) bool {
+	if r.less == nil {
+		_, ok := r.m[val]
+		return ok
+	}
+	i := sort.Search(len(r.keys), func(i int) bool {
+		return !r.less(r.keys[i], val)
+	})
+	return i < len(r.keys) && r.keys[i] == val
+}
+
+// Size returns the number of elements in the set.
+func (r ReadOnlySet[K]) Size() int {
+	if r.less == nil {
+		return len(r.m)
+	}
+	return len(r.keys)
+}
+
+// Each calls 'fn' on every item in the set, in sorted order if the set was
+// made by FinalizeSorted, and in no particular order otherwise.
+func (r ReadOnlySet[K]) Each(fn func(key K)) {
+	if r.less == nil {
+		for k := range r.m {
+			fn(k)
+		}
+		return
+	}
+	for _, k := range r.keys {
+		fn(k)
+	}
+}
+
+// Keys returns a new slice of the elements of the set, in the same order as
+// Each.
+func (r ReadOnlySet[K]) Keys() []K {
+	if r.less != nil {
+		return slices.Clone(r.keys)
+	}
+	keys := make([]K, 0, len(r.m))
+	for k := range r.m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -6,12 +6,15 @@ import "golang.org/x/exp/maps"
 // Set implements a hashset, using the hashmap as the underlying storage.
 type Set[K comparable] struct {
 	m map[K]struct{}
+	// frozen is shared by all copies of the Set value.
+	frozen *bool
 }
 
 // New returns an empty hashset.
 func New[K comparable]() Set[K] {
 	return Set[K]{
-		m: make(map[K]struct{}),
+		m:      make(map[K]struct{}),
+		frozen: new(bool),
 	}
 }
 
@@ -26,6 +29,7 @@ func Of[K comparable](vals ...K) Set[K] {
 
 // Put adds 'val' to the set.
 func (s Set[K]) Put(val K) {
+	s.checkFrozen("Put")
 	s.m[val] = struct{}{}
 }
 
@@ -37,14 +41,40 @@ func (s Set[K]) Has(val K) bool {
 
 // Remove removes 'val' from the set.
 func (s Set[K]) Remove(val K) {
+	s.checkFrozen("Remove")
 	delete(s.m, val)
 }
 
 // Clear removes all elements from the set.
 func (s Set[K]) Clear() {
+	s.checkFrozen("Clear")
 	maps.Clear(s.m)
 }
 
+// Freeze makes the set immutable: any later call to a method that would modify
+// it panics, including calls through other copies of the Set value. Reads do
+// not modify the set, so a frozen set may be read from multiple goroutines
+// concurrently. Copies made with Copy are not frozen. The zero Set has no
+// state for its copies to share, so it cannot be frozen; make the set with New
+// instead.
+func (s Set[K]) Freeze() {
+	if s.frozen == nil {
+		panic("mapset: Freeze called on a zero mapset.Set")
+	}
+	*s.frozen = true
+}
+
+// Frozen returns true if Freeze has been called on the set.
+func (s Set[K]) Frozen() bool {
+	return s.frozen != nil && *s.frozen
+}
+
+func (s Set[K]) checkFrozen(op string) {
+	if s.Frozen() {
+		panic("mapset: " + op + " called on a frozen mapset.Set")
+	}
+}
+
 // Size returns the number of elements in the set.
 func (s Set[K]) Size() int {
 	return len(s.m)
@@ -56,3 +86,21 @@ func (s Set[K]) Each(fn func(key K)) {
 		fn(k)
 	}
 }
+
+// Copy returns a copy of this set.
+func (s Set[K]) Copy() Set[K] {
+	m := make(map[K]struct{}, len(s.m))
+	for k := range s.m {
+		m[k] = struct{}{}
+	}
+	return Set[K]{
+		m:      m,
+		frozen: new(bool),
+	}
+}
+
+// Clone is like Copy, but returns the copy as an 'any', so that the set
+// implements set.Cloner.
+func (s Set[K]) Clone() any {
+	return s.Copy()
+}
@@ -3,9 +3,14 @@ package mapset_test
 import (
 	"fmt"
 	"math/rand"
+	"runtime"
+	"sync"
 	"testing"
 
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/internal/testutil"
 	"github.com/zyedidia/generic/mapset"
+	"golang.org/x/exp/slices"
 )
 
 func checkeq[K comparable](set mapset.Set[K], get func(k K) bool, t *testing.T) {
@@ -69,6 +74,37 @@ func TestOf(t *testing.T) {
 	}
 }
 
+func TestFreeze(t *testing.T) {
+	s := mapset.New[int]()
+	for i := 0; i < 100; i++ {
+		s.Put(i)
+	}
+	alias := s
+	s.Freeze()
+	if !alias.Frozen() {
+		t.Fatal("copies of the set value should be frozen")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				if !s.Has(k) {
+					t.Errorf("Has(%d) = false", k)
+				}
+			}
+			s.Each(func(key int) {})
+		}()
+	}
+	wg.Wait()
+
+	testutil.MustPanic(t, "mapset.Set", func() { alias.Put(1) })
+	testutil.MustPanic(t, "mapset.Set", func() { s.Remove(1) })
+	testutil.MustPanic(t, "mapset.Set", func() { s.Clear() })
+}
+
 func Example() {
 	set := mapset.New[string]()
 	set.Put("foo")
@@ -93,3 +129,105 @@ func Example() {
 	// foo false
 	// bar false
 }
+
+func TestZero(t *testing.T) {
+	// the zero value is an empty set that can be read and cleared
+	var s mapset.Set[int]
+	s.Remove(1)
+	s.Clear()
+	if s.Has(1) || s.Size() != 0 || s.Frozen() {
+		t.Fatal("zero set is not empty and unfrozen")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Freeze of a zero set did not panic")
+		}
+	}()
+	s.Freeze()
+}
+
+func TestCopy(t *testing.T) {
+	orig := mapset.Of(1, 2, 3)
+	cpy := orig.Copy()
+	cpy.Put(4)
+	cpy.Remove(1)
+
+	if orig.Size() != 3 || !orig.Has(1) || orig.Has(4) {
+		t.Fatal("original modified by copy")
+	}
+	if cpy.Size() != 3 || cpy.Has(1) || !cpy.Has(4) {
+		t.Fatal("copy mismatch")
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	const n = 1000
+	s := mapset.New[int]()
+	for i := 0; i < n; i++ {
+		s.Put(2 * i)
+	}
+
+	for _, ro := range []mapset.ReadOnlySet[int]{s.Finalize(), s.FinalizeSorted(g.Less[int])} {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := -1; k < 2*n+1; k++ {
+					if ro.Has(k) != (k >= 0 && k < 2*n && k%2 == 0) {
+						t.Errorf("Has(%d) = %v", k, ro.Has(k))
+					}
+				}
+				count := 0
+				ro.Each(func(key int) { count++ })
+				if count != n || ro.Size() != n || len(ro.Keys()) != n {
+					t.Errorf("Each visited %d, Size() = %d", count, ro.Size())
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	sorted := s.FinalizeSorted(g.Less[int])
+	keys := sorted.Keys()
+	if !slices.IsSorted(keys) || keys[0] != 0 || keys[n-1] != 2*(n-1) {
+		t.Fatalf("sorted keys out of order")
+	}
+	keys[0] = -1
+	if !sorted.Has(0) {
+		t.Fatal("modifying Keys modified the set")
+	}
+
+	// the snapshots are unaffected by later changes
+	ro := s.Finalize()
+	s.Put(1)
+	s.Remove(0)
+	if ro.Has(1) || !ro.Has(0) || ro.Size() != n || sorted.Has(1) || !sorted.Has(0) {
+		t.Fatal("snapshot changed with the original set")
+	}
+}
+
+// BenchmarkFinalizeMemory reports the heap memory used by the read-only
+// sets that Finalize and FinalizeSorted make of 100000 elements; the sorted
+// slice should take well under half the memory of the map.
+func BenchmarkFinalizeMemory(b *testing.B) {
+	const n = 100000
+	s := mapset.New[int]()
+	for i := 0; i < n; i++ {
+		s.Put(i)
+	}
+	var mapBytes, sortedBytes int64
+	for i := 0; i < b.N; i++ {
+		before := testutil.HeapAlloc()
+		ro := s.Finalize()
+		mapBytes += int64(testutil.HeapAlloc()) - int64(before)
+
+		before = testutil.HeapAlloc()
+		sorted := s.FinalizeSorted(g.Less[int])
+		sortedBytes += int64(testutil.HeapAlloc()) - int64(before)
+		runtime.KeepAlive(ro)
+		runtime.KeepAlive(sorted)
+	}
+	b.ReportMetric(float64(mapBytes)/float64(b.N), "map-B")
+	b.ReportMetric(float64(sortedBytes)/float64(b.N), "sorted-B")
+}
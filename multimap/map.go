@@ -101,6 +101,26 @@ func NewMapSlice[K, V comparable]() MultiMap[K, V] {
 	return m
 }
 
+// NewBounded creates a MultiMap using builtin map and builtin slice, that
+// keeps at most 'max' values per key.
+//   - Both key type and value type must be comparable.
+//   - Duplicate entries are permitted.
+//   - Keys are unsorted, and values are in insertion order.
+//   - Putting a value under a key that already has 'max' values drops the
+//     oldest value for that key.
+func NewBounded[K, V comparable](max int) MultiMap[K, V] {
+	if max <= 0 {
+		panic("multimap: NewBounded requires a positive maximum")
+	}
+	m := &mapMultiMap[K, V, *valuesBounded[V]]{
+		makeValues: func() *valuesBounded[V] {
+			return &valuesBounded[V]{max: max}
+		},
+	}
+	m.Clear()
+	return m
+}
+
 // NewMapSet creates a MultiMap using builtin map and AVL set.
 //   - Key type must be comparable.
 //   - Duplicate entries are not permitted.
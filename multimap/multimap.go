@@ -1,10 +1,11 @@
 // Package multimap provides an associative container that permits multiple entries with the same key.
 //
-// There are four implementations of the MultiMap data structure, identified by separate New* functions.
+// There are five implementations of the MultiMap data structure, identified by separate New* functions.
 // They differ in the following ways:
 //   - whether key type and value type must be comparable.
 //   - whether duplicate entries (same key and same value) are permitted.
 //   - whether keys and values are sorted or unsorted in Get, Each, and EachAssociation methods.
+//   - whether the number of values per key is bounded.
 package multimap
 
 // MultiMap is an associative container that contains a list of key-value pairs, while permitting multiple entries with the same key.
@@ -46,3 +47,25 @@ type baseMultiMap struct {
 func (m baseMultiMap) Size() int {
 	return m.size
 }
+
+// Invert returns a new MultiMap that maps each value in 'm' to the keys that
+// contained it. Each key appears at most once per value, even if 'm' contains
+// duplicate entries. The result is backed by a builtin map and builtin slice,
+// and lists keys in the order they are visited by m.Each.
+func Invert[K, V comparable](m MultiMap[K, V]) MultiMap[V, K] {
+	type pair struct {
+		key   K
+		value V
+	}
+	inv := NewMapSlice[V, K]()
+	seen := make(map[pair]struct{}, m.Size())
+	m.Each(func(key K, value V) {
+		p := pair{key, value}
+		if _, ok := seen[p]; ok {
+			return
+		}
+		seen[p] = struct{}{}
+		inv.Put(value, key)
+	})
+	return inv
+}
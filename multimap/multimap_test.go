@@ -183,3 +183,61 @@ func TestAvlSet(t *testing.T) {
 	m := multimap.NewAvlSet(g.Less[string], g.Less[int])
 	testMultiMap(t, m, false, true, true)
 }
+
+func TestBounded(t *testing.T) {
+	// below the bound it behaves like NewMapSlice
+	testMultiMap(t, multimap.NewBounded[string, int](8), true, false, false)
+
+	m := multimap.NewBounded[string, int](3)
+	for i := 1; i <= 10; i++ {
+		m.Put("recent", i)
+	}
+	m.Put("other", 1)
+	if got := m.Get("recent"); !slices.Equal(got, []int{8, 9, 10}) {
+		t.Fatalf("Get = %v, want [8 9 10]", got)
+	}
+	if m.Size() != 4 || m.Count("recent") != 3 || m.Dimension() != 2 {
+		t.Fatalf("size %d, count %d, dimension %d", m.Size(), m.Count("recent"), m.Dimension())
+	}
+
+	// removing a value keeps the others in insertion order
+	m.Remove("recent", 9)
+	m.Put("recent", 11)
+	m.Put("recent", 12)
+	if got := m.Get("recent"); !slices.Equal(got, []int{10, 11, 12}) {
+		t.Fatalf("Get = %v, want [10 11 12]", got)
+	}
+	if m.Size() != 4 {
+		t.Fatalf("size %d, want 4", m.Size())
+	}
+}
+
+func TestInvert(t *testing.T) {
+	for name, m := range map[string]multimap.MultiMap[string, int]{
+		"MapSlice": multimap.NewMapSlice[string, int](),
+		"AvlSet":   multimap.NewAvlSet(g.Less[string], g.Less[int]),
+	} {
+		m.Put("doc1", 1)
+		m.Put("doc1", 2)
+		m.Put("doc1", 2) // duplicate entry where permitted
+		m.Put("doc2", 2)
+		m.Put("doc2", 3)
+		m.Put("doc3", 3)
+
+		inv := multimap.Invert(m)
+		if inv.Dimension() != 3 || inv.Size() != 5 {
+			t.Fatalf("%s: dimension %d, size %d", name, inv.Dimension(), inv.Size())
+		}
+		for v, want := range map[int][]string{
+			1: {"doc1"},
+			2: {"doc1", "doc2"},
+			3: {"doc2", "doc3"},
+		} {
+			got := inv.Get(v)
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Fatalf("%s: Get(%d) = %v, want %v", name, v, got, want)
+			}
+		}
+	}
+}
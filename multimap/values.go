@@ -17,6 +17,7 @@ type valuesContainer[V any] interface {
 var (
 	_ valuesContainer[int] = valuesSet[int]{}
 	_ valuesContainer[int] = (*valuesSlice[int])(nil)
+	_ valuesContainer[int] = (*valuesBounded[int])(nil)
 )
 
 type valuesSet[V any] struct {
@@ -99,3 +100,47 @@ func (vs *valuesSlice[V]) Each(fn func(value V)) {
 		fn(value)
 	}
 }
+
+// valuesBounded is a slice of values in insertion order that holds at most
+// 'max' values, dropping the oldest to make room for a new one.
+type valuesBounded[V comparable] struct {
+	values []V
+	max    int
+}
+
+func (vb *valuesBounded[V]) Empty() bool {
+	return len(vb.values) == 0
+}
+
+func (vb *valuesBounded[V]) Size() int {
+	return len(vb.values)
+}
+
+func (vb *valuesBounded[V]) Put(value V) int {
+	if len(vb.values) < vb.max {
+		vb.values = append(vb.values, value)
+		return 1
+	}
+	copy(vb.values, vb.values[1:])
+	vb.values[len(vb.values)-1] = value
+	return 0
+}
+
+func (vb *valuesBounded[V]) Remove(value V) int {
+	i := slices.Index(vb.values, value)
+	if i < 0 {
+		return 0
+	}
+	vb.values = slices.Delete(vb.values, i, i+1)
+	return 1
+}
+
+func (vb *valuesBounded[V]) List() []V {
+	return vb.values
+}
+
+func (vb *valuesBounded[V]) Each(fn func(value V)) {
+	for _, value := range vb.values {
+		fn(value)
+	}
+}
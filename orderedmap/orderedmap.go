@@ -0,0 +1,180 @@
+// Package orderedmap provides a map whose keys are kept in increasing order.
+// It is a thin wrapper around an AVL tree ordered by the < operator, for the
+// common case of keys that are strings or numbers, so that no comparator
+// needs to be given.
+package orderedmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/avl"
+	"golang.org/x/exp/constraints"
+)
+
+// An OrderedMap maps keys to values, and iterates over them in increasing
+// order of the keys.
+type OrderedMap[K constraints.Ordered, V any] struct {
+	tree *avl.Tree[K, V]
+	// mods counts modifications, so that iterators can detect them.
+	mods uint64
+}
+
+// New returns an empty ordered map.
+func New[K constraints.Ordered, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		tree: avl.New[K, V](g.Less[K]),
+	}
+}
+
+// Get returns the value associated with 'key', or false if there is none.
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	return m.tree.Get(key)
+}
+
+// Put associates 'key' with 'val'.
+func (m *OrderedMap[K, V]) Put(key K, val V) {
+	m.mods++
+	m.tree.Put(key, val)
+}
+
+// Delete removes 'key' and its value from the map.
+func (m *OrderedMap[K, V]) Delete(key K) {
+	m.mods++
+	m.tree.Remove(key)
+}
+
+// Len returns the number of keys in the map.
+func (m *OrderedMap[K, V]) Len() int {
+	return m.tree.Size()
+}
+
+// Each calls 'fn' on every key-value pair in increasing order of the keys.
+func (m *OrderedMap[K, V]) Each(fn func(key K, val V)) {
+	m.tree.Each(fn)
+}
+
+// Range calls 'fn' on every key-value pair with a key in [lo, hi), in
+// increasing order of the keys.
+func (m *OrderedMap[K, V]) Range(lo, hi K, fn func(key K, val V)) {
+	m.tree.EachRange(lo, hi, fn)
+}
+
+// Keys returns the keys of the map in increasing order.
+func (m *OrderedMap[K, V]) Keys() []K {
+	keys := make([]K, 0, m.Len())
+	m.tree.Each(func(key K, val V) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// Values returns the values of the map in increasing order of their keys.
+func (m *OrderedMap[K, V]) Values() []V {
+	vals := make([]V, 0, m.Len())
+	m.tree.Each(func(key K, val V) {
+		vals = append(vals, val)
+	})
+	return vals
+}
+
+// Iter returns an iterator over the key-value pairs in increasing order of the
+// keys. Each call to the iterator returns the next pair, or false once there
+// are none left. Iter does not modify the map, so several iterators may be
+// used at once. An iterator is only valid until the map is modified: calling
+// it after a Put or Delete panics, and the map must not be modified through
+// Tree while it is in use.
+func (m *OrderedMap[K, V]) Iter() func() (K, V, bool) {
+	mods := m.mods
+	var stack []*avl.NodeView[K, V]
+	pushLeft := func(n *avl.NodeView[K, V]) {
+		for ; n != nil; n = n.Left() {
+			stack = append(stack, n)
+		}
+	}
+	pushLeft(m.tree.Root())
+	return func() (K, V, bool) {
+		if mods != m.mods {
+			panic("orderedmap: iterator used after the map was modified")
+		}
+		if len(stack) == 0 {
+			var k K
+			var v V
+			return k, v, false
+		}
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		pushLeft(n.Right())
+		return n.Key(), n.Val(), true
+	}
+}
+
+// Tree returns the AVL tree that holds the map, for operations the map does
+// not provide, such as CountRange or Snapshot.
+func (m *OrderedMap[K, V]) Tree() *avl.Tree[K, V] {
+	return m.tree
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in increasing
+// order. Keys that are numbers are written as strings, as encoding/json does
+// for Go maps.
+func (m *OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	var err error
+	m.tree.EachUntil(func(key K, val V) bool {
+		var k, v []byte
+		if k, err = json.Marshal(key); err != nil {
+			return false
+		}
+		if v, err = json.Marshal(val); err != nil {
+			return false
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		if k[0] != '"' {
+			// JSON object keys must be strings
+			k = append(append([]byte{'"'}, k...), '"')
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object, as produced by MarshalJSON, replacing
+// the contents of the map.
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	var zero K
+	isString := reflect.TypeOf(zero).Kind() == reflect.String
+
+	tree := avl.New[K, V](g.Less[K])
+	for s, raw := range obj {
+		var key K
+		if isString {
+			reflect.ValueOf(&key).Elem().SetString(s)
+		} else if err := json.Unmarshal([]byte(s), &key); err != nil {
+			return err
+		}
+		var val V
+		if err := json.Unmarshal(raw, &val); err != nil {
+			return err
+		}
+		tree.Put(key, val)
+	}
+	m.tree = tree
+	return nil
+}
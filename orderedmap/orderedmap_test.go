@@ -0,0 +1,186 @@
+package orderedmap_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/zyedidia/generic/orderedmap"
+	"golang.org/x/exp/constraints"
+)
+
+func TestOrderedMap(t *testing.T) {
+	m := orderedmap.New[int, string]()
+	ref := make(map[int]string)
+	for i := 0; i < 2000; i++ {
+		k := rand.Intn(500)
+		if rand.Intn(3) == 0 {
+			m.Delete(k)
+			delete(ref, k)
+		} else {
+			m.Put(k, fmt.Sprint(i))
+			ref[k] = fmt.Sprint(i)
+		}
+	}
+
+	keys := make([]int, 0, len(ref))
+	for k := range ref {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	vals := make([]string, len(keys))
+	for i, k := range keys {
+		vals[i] = ref[k]
+	}
+
+	if m.Len() != len(ref) || m.Tree().Size() != len(ref) {
+		t.Fatalf("Len() = %d, tree size %d, want %d", m.Len(), m.Tree().Size(), len(ref))
+	}
+	for k := -1; k <= 500; k++ {
+		v, ok := m.Get(k)
+		want, wantOk := ref[k]
+		if ok != wantOk || v != want {
+			t.Fatalf("Get(%d) = %q, %v, want %q, %v", k, v, ok, want, wantOk)
+		}
+	}
+	if got := m.Keys(); !reflect.DeepEqual(got, keys) {
+		t.Fatalf("Keys() = %v, want %v", got, keys)
+	}
+	if got := m.Values(); !reflect.DeepEqual(got, vals) {
+		t.Fatalf("Values() = %v, want %v", got, vals)
+	}
+
+	var got []int
+	next := m.Iter()
+	for k, v, ok := next(); ok; k, v, ok = next() {
+		if v != ref[k] {
+			t.Fatalf("Iter gave %d: %q, want %q", k, v, ref[k])
+		}
+		got = append(got, k)
+	}
+	if !reflect.DeepEqual(got, keys) {
+		t.Fatalf("Iter keys = %v, want %v", got, keys)
+	}
+
+	got = got[:0]
+	m.Range(100, 200, func(key int, val string) {
+		got = append(got, key)
+	})
+	lo, hi := sort.SearchInts(keys, 100), sort.SearchInts(keys, 200)
+	if !reflect.DeepEqual(got, keys[lo:hi]) {
+		t.Fatalf("Range(100, 200) = %v, want %v", got, keys[lo:hi])
+	}
+	if n := m.Tree().CountRange(100, 200); n != hi-lo {
+		t.Fatalf("CountRange(100, 200) = %d, want %d", n, hi-lo)
+	}
+}
+
+func roundTrip[K constraints.Ordered, V any](t *testing.T, m *orderedmap.OrderedMap[K, V], want string) {
+	t.Helper()
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Fatalf("Marshal = %s, want %s", data, want)
+	}
+	var got orderedmap.OrderedMap[K, V]
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Keys(), m.Keys()) || !reflect.DeepEqual(got.Values(), m.Values()) {
+		t.Fatalf("round trip gave %v: %v, want %v: %v", got.Keys(), got.Values(), m.Keys(), m.Values())
+	}
+}
+
+func TestIter(t *testing.T) {
+	m := orderedmap.New[int, int]()
+	for _, k := range rand.Perm(1000) {
+		m.Put(k, -k)
+	}
+
+	// iterating does not write to the map, so iterators may be used
+	// concurrently; run with -race
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			next := m.Iter()
+			for k := 0; k < 1000; k++ {
+				if key, val, ok := next(); !ok || key != k || val != -k {
+					t.Errorf("pair %d: got %d, %d, %v", k, key, val, ok)
+					return
+				}
+			}
+			if _, _, ok := next(); ok {
+				t.Error("iterator returned too many pairs")
+			}
+		}()
+	}
+	wg.Wait()
+
+	next := m.Iter()
+	next()
+	m.Delete(500)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("iterator used after Delete did not panic")
+		}
+	}()
+	next()
+}
+
+func TestJSON(t *testing.T) {
+	strs := orderedmap.New[string, []int]()
+	strs.Put("b", []int{2})
+	strs.Put("a", []int{1, 1})
+	strs.Put(`"q"`, nil)
+	roundTrip(t, strs, `{"\"q\"":null,"a":[1,1],"b":[2]}`)
+
+	type name string
+	names := orderedmap.New[name, int]()
+	names.Put("y", 2)
+	names.Put("x", 1)
+	roundTrip(t, names, `{"x":1,"y":2}`)
+
+	ints := orderedmap.New[int, string]()
+	ints.Put(10, "ten")
+	ints.Put(-2, "minus two")
+	ints.Put(3, "three")
+	roundTrip(t, ints, `{"-2":"minus two","3":"three","10":"ten"}`)
+
+	floats := orderedmap.New[float64, bool]()
+	floats.Put(2.5, true)
+	floats.Put(-0.25, false)
+	roundTrip(t, floats, `{"-0.25":false,"2.5":true}`)
+
+	roundTrip(t, orderedmap.New[int, int](), `{}`)
+
+	var bad orderedmap.OrderedMap[int, int]
+	if err := json.Unmarshal([]byte(`{"one":1}`), &bad); err == nil {
+		t.Fatal("decoding a non-numeric key into an int key did not fail")
+	}
+}
+
+func Example() {
+	m := orderedmap.New[string, int]()
+	m.Put("pear", 3)
+	m.Put("apple", 1)
+	m.Put("fig", 2)
+	m.Delete("pear")
+
+	m.Each(func(key string, val int) {
+		fmt.Println(key, val)
+	})
+	data, _ := json.Marshal(m)
+	fmt.Println(string(data))
+	// Output:
+	// apple 1
+	// fig 2
+	// {"apple":1,"fig":2}
+}
@@ -0,0 +1,72 @@
+package prope
+
+import "unsafe"
+
+// storage returns the approximate number of bytes used by the node itself,
+// not counting its children.
+func (n *Node[V]) storage() int {
+	var v V
+	return int(unsafe.Sizeof(*n)) + len(n.value)*int(unsafe.Sizeof(v))
+}
+
+// walk calls 'fn' on every node in the rope that is not already in 'seen',
+// adding each to 'seen'. Since a node's subtree never changes, the subtree of
+// a node that has been seen is skipped.
+func (n *Node[V]) walk(seen map[*Node[V]]bool, fn func(n *Node[V])) {
+	if n == nil || seen[n] {
+		return
+	}
+	seen[n] = true
+	fn(n)
+	n.left.walk(seen, fn)
+	n.right.walk(seen, fn)
+}
+
+// SharedBytes estimates the number of bytes of storage shared by the two
+// versions 'a' and 'b', by counting the nodes reachable from both.
+func SharedBytes[V any](a, b *Node[V]) int {
+	inA := make(map[*Node[V]]bool)
+	a.walk(inA, func(*Node[V]) {})
+
+	shared := 0
+	b.walk(make(map[*Node[V]]bool), func(n *Node[V]) {
+		if inA[n] {
+			shared += n.storage()
+		}
+	})
+	return shared
+}
+
+// Footprint estimates the total number of bytes of storage used by a set of
+// versions, counting each node shared between versions only once.
+func Footprint[V any](versions []*Node[V]) int {
+	seen := make(map[*Node[V]]bool)
+	total := 0
+	for _, v := range versions {
+		v.walk(seen, func(n *Node[V]) {
+			total += n.storage()
+		})
+	}
+	return total
+}
+
+// CompactHistory bounds the memory used by a history of versions, ordered from
+// oldest to newest, for example to implement undo. It returns the newest
+// 'keep' versions, so that nodes only referenced by the dropped versions can
+// be collected once the caller discards the original slice. The oldest
+// retained version is rebuilt into a fresh rope, so that it no longer
+// references leaves or leaf storage shared with the dropped versions. The
+// contents of the retained versions are unchanged, and the versions passed in
+// are not modified.
+func CompactHistory[V any](versions []*Node[V], keep int) []*Node[V] {
+	if keep <= 0 {
+		return nil
+	}
+	if keep >= len(versions) {
+		return versions
+	}
+	retained := make([]*Node[V], keep)
+	copy(retained, versions[len(versions)-keep:])
+	retained[0] = New(retained[0].Value())
+	return retained
+}
@@ -94,62 +94,109 @@ func (n *Node[V]) At(pos int) V {
 // Insert returns a new version of the rope with the given
 // value inserted at pos.
 func (n *Node[V]) Insert(pos int, value []V) *Node[V] {
-	if n.kind == tLeaf {
-		return New(insert(n.value, pos, value)) // Adjusting is done here
-	}
-
-	changedNode := &Node[V]{
-		kind:   tNode,
-		length: n.length + len(value),
-		left:   n.left,
-		right:  n.right,
-	}
-
-	if pos < n.left.length {
-		changedNode.left = n.left.Insert(pos, value)
-	} else {
-		changedNode.right = n.right.Insert(pos-n.left.length, value)
+	// copy the path to the leaf, sharing the subtrees off the path
+	var root *Node[V]
+	slot := &root
+	for n.kind != tLeaf {
+		changedNode := &Node[V]{
+			kind:   tNode,
+			length: n.length + len(value),
+			left:   n.left,
+			right:  n.right,
+		}
+		*slot = changedNode
+		if pos < n.left.length {
+			slot = &changedNode.left
+			n = n.left
+		} else {
+			pos -= n.left.length
+			slot = &changedNode.right
+			n = n.right
+		}
 	}
-	return changedNode
+	*slot = New(insert(n.value, pos, value)) // Adjusting is done here
+	return root
 }
 
 // Remove returns a new version of the rope with the elements
 // in the [start:end) range removed.
 func (n *Node[V]) Remove(start, end int) *Node[V] {
-	if n.kind == tLeaf {
-		return New(remove(n.value, start, end))
+	// The new nodes are built with an explicit stack, and each is adjusted
+	// after both of its children have been built.
+	type frame struct {
+		n           *Node[V]
+		start, end  int
+		slot        **Node[V]
+		changedNode *Node[V]
 	}
-
-	changedNode := &Node[V]{
-		kind: tNode,
+	var root *Node[V]
+	var buf [32]frame
+	stack := append(buf[:0], frame{n: n, start: start, end: end, slot: &root})
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		n := f.n
+		if n.kind == tLeaf {
+			*f.slot = New(remove(n.value, f.start, f.end))
+		} else if f.changedNode == nil {
+			changedNode := &Node[V]{
+				kind: tNode,
+			}
+			f.changedNode = changedNode
+			leftStart, leftEnd := bound(f.start, f.end, n.left.length)
+			rightStart, rightEnd := bound(f.start-n.left.length, f.end-n.left.length, n.right.length)
+			stack = append(stack,
+				frame{n: n.right, start: rightStart, end: rightEnd, slot: &changedNode.right},
+				frame{n: n.left, start: leftStart, end: leftEnd, slot: &changedNode.left},
+			)
+			continue
+		} else {
+			changedNode := f.changedNode
+			changedNode.length = changedNode.right.length + changedNode.left.length
+			changedNode.adjust()
+			*f.slot = changedNode
+		}
+		stack = stack[:len(stack)-1]
 	}
-
-	leftStart, leftEnd := bound(start, end, n.left.length)
-	changedNode.left = n.left.Remove(leftStart, leftEnd)
-
-	rightStart, rightEnd := bound(start-n.left.length, end-n.left.length, n.right.length)
-	changedNode.right = n.right.Remove(rightStart, rightEnd)
-
-	changedNode.length = changedNode.right.length + changedNode.left.length
-	changedNode.adjust()
-	return changedNode
+	return root
 }
 
 // SplitAt splits the node at the given index and returns two new ropes
 // corresponding to the left and right portions of the split.
 func (n *Node[V]) SplitAt(i int) (*Node[V], *Node[V]) {
-	if n.kind == tLeaf {
-		return New(n.value[:i]), New(n.value[i:])
+	// descend to the split point, then join the parts of each node on the
+	// path that are not split with the two halves on the way back up
+	type step struct {
+		n    *Node[V]
+		left bool
 	}
-	if i == n.left.length {
-		return n.left, n.right
-	} else if i < n.left.length {
-		l, r := n.left.SplitAt(i)
-		return l, Join(r, n.right)
-	} else {
-		l, r := n.right.SplitAt(i - n.left.length)
-		return Join(n.left, l), r
+	var buf [32]step
+	path := buf[:0]
+	var l, r *Node[V]
+	for {
+		if n.kind == tLeaf {
+			l, r = New(n.value[:i]), New(n.value[i:])
+			break
+		}
+		if i == n.left.length {
+			l, r = n.left, n.right
+			break
+		}
+		path = append(path, step{n, i < n.left.length})
+		if i < n.left.length {
+			n = n.left
+		} else {
+			i -= n.left.length
+			n = n.right
+		}
+	}
+	for j := len(path) - 1; j >= 0; j-- {
+		if p := path[j]; p.left {
+			r = Join(r, p.n.right)
+		} else {
+			l = Join(p.n.left, l)
+		}
 	}
+	return l, r
 }
 
 // Rebalance finds unbalanced nodes and rebuilds them.
@@ -198,29 +245,38 @@ func Join[V any](nodes ...*Node[V]) *Node[V] {
 }
 
 func (n *Node[V]) copy(dst []V) {
-	if n.kind == tLeaf {
-		copy(dst, n.value)
-		return
-	}
-	n.left.copy(dst)
-	n.right.copy(dst[n.left.length:])
+	n.copySlice(dst, 0, n.length)
 }
 
+// copySlice copies the range [start:end) of the rope to 'dst'. It uses an
+// explicit stack rather than recursion, since a rope built by many joins may
+// be very deep.
 func (n *Node[V]) copySlice(dst []V, start, end int) {
-	if start >= end {
-		return
+	type frame struct {
+		n          *Node[V]
+		start, end int
 	}
-
-	if n.kind == tLeaf {
-		copy(dst, n.value[start:end])
-		return
+	var buf [32]frame // enough for a balanced rope, without allocating
+	stack := append(buf[:0], frame{n, start, end})
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f.start >= f.end {
+			continue
+		}
+		n := f.n
+		if n.kind == tLeaf {
+			dst = dst[copy(dst, n.value[f.start:f.end]):]
+			continue
+		}
+		// push the right child first so that the left one is copied first
+		rightStart, rightEnd := bound(f.start-n.left.length, f.end-n.left.length, n.right.length)
+		leftStart, leftEnd := bound(f.start, f.end, n.left.length)
+		stack = append(stack,
+			frame{n.right, rightStart, rightEnd},
+			frame{n.left, leftStart, leftEnd},
+		)
 	}
-
-	leftStart, leftEnd := bound(start, end, n.left.length)
-	n.left.copySlice(dst, leftStart, leftEnd)
-
-	rightStart, rightEnd := bound(start-n.left.length, end-n.left.length, n.right.length)
-	n.right.copySlice(dst[leftEnd-leftStart:], rightStart, rightEnd)
 }
 
 func (n *Node[V]) adjust() {
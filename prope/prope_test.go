@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"runtime/debug"
 	"testing"
 
 	g "github.com/zyedidia/generic"
@@ -165,6 +166,56 @@ func TestSplit(t *testing.T) {
 	}
 }
 
+func TestHistory(t *testing.T) {
+	versions := []*prope.Node[byte]{prope.New(randbytes(datasz))}
+	for i := 0; i < 200; i++ {
+		cur := versions[len(versions)-1]
+		if rand.Intn(2) == 0 {
+			versions = append(versions, cur.Insert(rand.Intn(cur.Len()), randbytes(100)))
+		} else {
+			start, end := randrange(cur.Len())
+			versions = append(versions, cur.Remove(start, g.Min(end, start+100)))
+		}
+	}
+
+	// Insert shares the untouched subtrees with the previous version
+	prev := versions[len(versions)-1]
+	last := prev.Insert(prev.Len()/2, []byte("x"))
+	versions = append(versions, last)
+	if shared := prope.SharedBytes(prev, last); shared == 0 {
+		t.Fatal("consecutive versions should share storage")
+	} else if shared > prope.Footprint([]*prope.Node[byte]{last}) {
+		t.Fatal("shared storage exceeds the size of a version")
+	}
+	if prope.SharedBytes(prope.New(randbytes(10)), last) != 0 {
+		t.Fatal("unrelated ropes should not share storage")
+	}
+
+	const keep = 10
+	want := make([][]byte, keep)
+	for i := range want {
+		want[i] = versions[len(versions)-keep+i].Value()
+	}
+
+	before := prope.Footprint(versions)
+	retained := prope.CompactHistory(versions, keep)
+	after := prope.Footprint(retained)
+	if after >= before {
+		t.Fatalf("footprint did not drop: %d -> %d", before, after)
+	}
+	if len(retained) != keep {
+		t.Fatalf("retained %d versions, want %d", len(retained), keep)
+	}
+	for i, v := range retained {
+		if !bytes.Equal(v.Value(), want[i]) {
+			t.Fatalf("version %d changed", i)
+		}
+	}
+	if prope.SharedBytes(retained[0], versions[len(versions)-keep-1]) != 0 {
+		t.Fatal("rebuilt version still shares storage with dropped versions")
+	}
+}
+
 func Example() {
 	r := prope.New([]byte("hello world"))
 
@@ -182,3 +233,152 @@ func Example() {
 	// hello
 	// hello rope
 }
+
+func TestDeep(t *testing.T) {
+	// join many small ropes one at a time, so that the tree is a chain
+	const depth, leafLen = 200000, 10
+	data := randbytes(depth * leafLen)
+	leaves := make([]*prope.Node[byte], depth)
+	for i := range leaves {
+		leaves[i] = prope.New(data[i*leafLen : (i+1)*leafLen])
+	}
+	p := prope.Join(leaves...)
+
+	// A recursive implementation would need tens of megabytes of stack for
+	// a rope this deep; exceeding the limit crashes the test.
+	defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+
+	if !bytes.Equal(p.Value(), data) {
+		t.Fatal("incorrect value")
+	}
+	if !bytes.Equal(p.Slice(5, len(data)-5), data[5:len(data)-5]) || p.At(len(data)-1) != data[len(data)-1] {
+		t.Fatal("incorrect slice")
+	}
+
+	inserted := p.Insert(3, []byte("abc"))
+	want := append(append(append([]byte(nil), data[:3]...), "abc"...), data[3:]...)
+	if !bytes.Equal(inserted.Value(), want) {
+		t.Fatal("incorrect insert")
+	}
+	removed := inserted.Remove(1, len(want)-100)
+	want = append(want[:1], want[len(want)-100:]...)
+	if !bytes.Equal(removed.Value(), want) {
+		t.Fatal("incorrect remove")
+	}
+
+	l, r := p.SplitAt(15)
+	if !bytes.Equal(l.Value(), data[:15]) || !bytes.Equal(r.Value(), data[15:]) {
+		t.Fatal("incorrect split")
+	}
+	if !bytes.Equal(p.Value(), data) {
+		t.Fatal("original version modified")
+	}
+}
+
+func BenchmarkOps(b *testing.B) {
+	r := prope.New(randbytes(1024 * 1024))
+
+	b.Run("Value", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r.Value()
+		}
+	})
+	b.Run("Slice", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			start := rand.Intn(r.Len() - 1000)
+			r.Slice(start, start+1000)
+		}
+	})
+	b.Run("Insert", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r.Insert(rand.Intn(r.Len()), []byte("0123456789"))
+		}
+	})
+	b.Run("Remove", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			start := rand.Intn(r.Len() - 10)
+			r.Remove(start, start+10)
+		}
+	})
+	b.Run("SplitAt", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r.SplitAt(rand.Intn(r.Len()))
+		}
+	})
+}
+
+func TestTry(t *testing.T) {
+	p, r := data()
+	n := p.Len()
+
+	for _, pos := range []int{-1, 0, n - 1, n} {
+		v, err := p.TryAt(pos)
+		if ok := pos >= 0 && pos < n; ok != (err == nil) {
+			t.Errorf("TryAt(%d) error = %v", pos, err)
+		} else if ok && v != r.At(pos) {
+			t.Errorf("TryAt(%d) = %c", pos, v)
+		}
+	}
+
+	ranges := []struct {
+		start, end int
+		ok         bool
+	}{
+		{0, n, true},
+		{n, n, true},
+		{0, 0, true},
+		{10, 20, true},
+		{-1, 2, false},
+		{3, 2, false},
+		{0, n + 1, false},
+		{n + 1, n + 1, false},
+	}
+	for _, rg := range ranges {
+		s, err := p.TrySlice(rg.start, rg.end)
+		if rg.ok != (err == nil) {
+			t.Errorf("TrySlice(%d, %d) error = %v", rg.start, rg.end, err)
+		} else if rg.ok && !bytes.Equal(s, r.Slice(rg.start, rg.end)) {
+			t.Errorf("TrySlice(%d, %d) = %s", rg.start, rg.end, s)
+		}
+
+		p2, err := p.TryRemove(rg.start, rg.end)
+		if rg.ok != (err == nil) {
+			t.Errorf("TryRemove(%d, %d) error = %v", rg.start, rg.end, err)
+		} else if rg.ok {
+			if p2.Len() != n-(rg.end-rg.start) {
+				t.Errorf("TryRemove(%d, %d) has length %d", rg.start, rg.end, p2.Len())
+			}
+		} else if p2 != nil {
+			t.Errorf("TryRemove(%d, %d) returned a rope with an error", rg.start, rg.end)
+		}
+		check(p, r, t)
+	}
+
+	for _, pos := range []int{-1, 0, n, n + 1} {
+		p2, err := p.TryInsert(pos, []byte("xyz"))
+		if ok := pos >= 0 && pos <= n; ok != (err == nil) {
+			t.Errorf("TryInsert(%d) error = %v", pos, err)
+		} else if ok {
+			r2 := rope.New(r.Value())
+			r2.Insert(pos, []byte("xyz"))
+			check(p2, r2, t)
+		}
+		check(p, r, t)
+	}
+}
+
+func Example_try() {
+	r := prope.New([]byte("hello"))
+
+	// Remove panics on an out-of-range range, while TryRemove returns an
+	// error.
+	if _, err := r.TryRemove(2, 6); err != nil {
+		fmt.Println(err)
+	}
+	if r2, err := r.TryRemove(2, 5); err == nil {
+		fmt.Println(string(r2.Value()))
+	}
+	// Output:
+	// prope: slice bounds out of range [2:6] with length 5
+	// he
+}
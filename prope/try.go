@@ -0,0 +1,55 @@
+package prope
+
+import "fmt"
+
+// TryAt returns the element at the given position, or an error if the
+// position is out of range, rather than panicking like At.
+func (n *Node[V]) TryAt(pos int) (V, error) {
+	if err := checkIndex(pos, n.length); err != nil {
+		var v V
+		return v, err
+	}
+	return n.At(pos), nil
+}
+
+// TrySlice returns the range of the rope from [start:end), or an error if the
+// range is not within the rope.
+func (n *Node[V]) TrySlice(start, end int) ([]V, error) {
+	if err := checkRange(start, end, n.length); err != nil {
+		return nil, err
+	}
+	return n.Slice(start, end), nil
+}
+
+// TryRemove returns a new version of the rope with the elements in the
+// [start:end) range removed, or an error if the range is not within the rope.
+func (n *Node[V]) TryRemove(start, end int) (*Node[V], error) {
+	if err := checkRange(start, end, n.length); err != nil {
+		return nil, err
+	}
+	return n.Remove(start, end), nil
+}
+
+// TryInsert returns a new version of the rope with the given value inserted
+// at pos, or an error if pos is out of range. The value may be inserted at
+// Len() to append it.
+func (n *Node[V]) TryInsert(pos int, value []V) (*Node[V], error) {
+	if pos < 0 || pos > n.length {
+		return nil, fmt.Errorf("prope: insert position out of range [%d] with length %d", pos, n.length)
+	}
+	return n.Insert(pos, value), nil
+}
+
+func checkIndex(pos, length int) error {
+	if pos < 0 || pos >= length {
+		return fmt.Errorf("prope: index out of range [%d] with length %d", pos, length)
+	}
+	return nil
+}
+
+func checkRange(start, end, length int) error {
+	if start < 0 || end > length || start > end {
+		return fmt.Errorf("prope: slice bounds out of range [%d:%d] with length %d", start, end, length)
+	}
+	return nil
+}
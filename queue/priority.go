@@ -0,0 +1,82 @@
+package queue
+
+import (
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/heap"
+)
+
+// PriorityQueue is a queue that dequeues items in order of priority rather
+// than insertion order. It is backed by an indexed heap, so the priority of an
+// item already in the queue can be changed. Items must be distinct.
+type PriorityQueue[T comparable, P any] struct {
+	heap *heap.Indexed[T, P]
+}
+
+// NewPriority returns an empty priority queue. The item with the minimum
+// priority according to 'less' is dequeued first.
+func NewPriority[T comparable, P any](less g.LessFn[P]) *PriorityQueue[T, P] {
+	return &PriorityQueue[T, P]{
+		heap: heap.NewIndexed[T](less),
+	}
+}
+
+// Len returns the number of items currently in the queue.
+func (q *PriorityQueue[T, P]) Len() int {
+	return q.heap.Size()
+}
+
+// Empty returns true if the queue is empty.
+func (q *PriorityQueue[T, P]) Empty() bool {
+	return q.heap.Size() == 0
+}
+
+// Enqueue inserts 'item' into the queue with the given priority. If 'item' is
+// already in the queue, its priority is updated instead.
+func (q *PriorityQueue[T, P]) Enqueue(item T, priority P) {
+	q.heap.Push(item, priority)
+}
+
+// UpdatePriority changes the priority of 'item'. It returns false if 'item' is
+// not in the queue.
+func (q *PriorityQueue[T, P]) UpdatePriority(item T, priority P) bool {
+	return q.heap.Update(item, priority)
+}
+
+// Dequeue removes and returns the item with the minimum priority.
+//
+// A panic occurs if the queue is Empty.
+func (q *PriorityQueue[T, P]) Dequeue() T {
+	item, ok := q.TryDequeue()
+	if !ok {
+		panic("queue: tried to dequeue from an empty queue")
+	}
+	return item
+}
+
+// TryDequeue tries to remove and return the item with the minimum priority.
+//
+// If the queue is empty, then false is returned as the second return value.
+func (q *PriorityQueue[T, P]) TryDequeue() (T, bool) {
+	item, _, ok := q.heap.Pop()
+	return item, ok
+}
+
+// Peek returns the item with the minimum priority without removing it.
+//
+// A panic occurs if the queue is Empty.
+func (q *PriorityQueue[T, P]) Peek() T {
+	item, ok := q.TryPeek()
+	if !ok {
+		panic("queue: tried to peek an empty queue")
+	}
+	return item
+}
+
+// TryPeek tries to return the item with the minimum priority without removing
+// it.
+//
+// If the queue is empty, then false is returned as the second return value.
+func (q *PriorityQueue[T, P]) TryPeek() (T, bool) {
+	item, _, ok := q.heap.Peek()
+	return item, ok
+}
@@ -9,8 +9,7 @@ import (
 
 // Queue is a simple First In First Out (FIFO) queue.
 type Queue[T any] struct {
-	list   *list.List[T]
-	length int
+	list *list.List[T]
 }
 
 // New returns an empty First In First Out (FIFO) queue.
@@ -32,13 +31,12 @@ func Of[S ~[]E, E any](slice S) *Queue[E] {
 
 // Len returns the number of items currently in the queue.
 func (q *Queue[T]) Len() int {
-	return q.length
+	return q.list.Len()
 }
 
 // Enqueue inserts 'value' to the end of the queue.
 func (q *Queue[T]) Enqueue(value T) {
 	q.list.PushBack(value)
-	q.length++
 }
 
 // Dequeue removes and returns the item at the front of the queue.
@@ -60,21 +58,66 @@ func (q *Queue[T]) TryDequeue() (T, bool) {
 		var zero T
 		return zero, false
 	}
-	value := q.list.Front.Value
-	q.list.Remove(q.list.Front)
-	q.length--
-	return value, true
+	return q.popFront(), true
 }
 
-// DequeueAll removes and returns all the items in the queue.
+// popFront removes the front node and returns its value. The node is cleared
+// so that it does not keep the value or its neighbours reachable.
+func (q *Queue[T]) popFront() T {
+	n := q.list.Front
+	value := n.Value
+	q.list.Remove(n)
+
+	var zero T
+	n.Value = zero
+	n.Prev, n.Next = nil, nil
+	return value
+}
+
+// DequeueAll removes and returns all the items in the queue. The queue no
+// longer references the items, but the returned slice does, so every item
+// stays reachable as long as the slice does, even after the caller is done
+// with it. When processing a large queue whose items reference a lot of
+// memory, zero each element after using it, or dequeue in batches with
+// DequeueUpToAppend.
 func (q *Queue[T]) DequeueAll() []T {
-	slice := make([]T, q.length)
+	slice := make([]T, q.list.Len())
 	for i := 0; i < len(slice); i++ {
 		slice[i] = q.Dequeue()
 	}
 	return slice
 }
 
+// DequeueAppend removes all the items in the queue and appends them to 'dst',
+// returning the extended slice. If the queue is empty, 'dst' is returned
+// unchanged. Reusing 'dst' across calls avoids allocating a new slice each
+// time.
+func (q *Queue[T]) DequeueAppend(dst []T) []T {
+	return q.DequeueUpToAppend(dst, q.list.Len())
+}
+
+// DequeueUpTo removes and returns at most 'n' items from the front of the
+// queue. If the queue holds fewer than 'n' items, all of them are returned.
+func (q *Queue[T]) DequeueUpTo(n int) []T {
+	if n > q.list.Len() {
+		n = q.list.Len()
+	}
+	if n <= 0 {
+		return nil
+	}
+	return q.DequeueUpToAppend(make([]T, 0, n), n)
+}
+
+// DequeueUpToAppend removes at most 'n' items from the front of the queue and
+// appends them to 'dst', returning the extended slice. If the queue is empty
+// or 'n' is not positive, 'dst' is returned unchanged.
+func (q *Queue[T]) DequeueUpToAppend(dst []T, n int) []T {
+	for ; n > 0 && !q.Empty(); n-- {
+		dst = append(dst, q.popFront())
+	}
+	return dst
+}
+
 // Peek returns the item at the front of the queue without removing it.
 //
 // A panic occurs if the queue is Empty.
@@ -98,7 +141,7 @@ func (q *Queue[T]) TryPeek() (T, bool) {
 
 // PeekAll returns all the items in the queue without removing them.
 func (q *Queue[T]) PeekAll() []T {
-	slice := make([]T, q.length)
+	slice := make([]T, q.list.Len())
 	var index int
 	q.list.Front.Each(func(val T) {
 		slice[index] = val
@@ -107,6 +150,16 @@ func (q *Queue[T]) PeekAll() []T {
 	return slice
 }
 
+// AppendAll appends all the items in the queue to 'dst' without removing
+// them, and returns the extended slice. If the queue is empty, 'dst' is
+// returned unchanged.
+func (q *Queue[T]) AppendAll(dst []T) []T {
+	for n := q.list.Front; n != nil; n = n.Next {
+		dst = append(dst, n.Value)
+	}
+	return dst
+}
+
 // Empty returns true if the queue is empty.
 func (q *Queue[T]) Empty() bool {
 	return q.list.Front == nil
@@ -114,7 +167,6 @@ func (q *Queue[T]) Empty() bool {
 
 // Clear empties the queue, resetting it to zero elements.
 func (q *Queue[T]) Clear() {
-	q.length = 0
 	q.list = list.New[T]()
 }
 
@@ -2,7 +2,10 @@ package queue
 
 import (
 	"fmt"
+	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/zyedidia/generic/list"
 )
@@ -397,10 +400,8 @@ func emptyQueue() *Queue[int] {
 
 func nonEmptyQueue() *Queue[int] {
 	q := New[int]()
-	q.list.Front = &list.Node[int]{Value: 1}
-	q.list.Front.Next = &list.Node[int]{Value: 2, Prev: q.list.Front}
-	q.list.Back = q.list.Front.Next
-	q.length = 2
+	q.list.PushBackNode(&list.Node[int]{Value: 1})
+	q.list.PushBackNode(&list.Node[int]{Value: 2})
 	return q
 }
 
@@ -417,3 +418,146 @@ func assertSlices[T comparable](t *testing.T, got []T, want []T) {
 		}
 	}
 }
+
+func TestPriorityQueue(t *testing.T) {
+	q := NewPriority[string](func(a, b int) bool { return a < b })
+	if _, ok := q.TryDequeue(); ok {
+		t.Fatal("dequeued from an empty queue")
+	}
+
+	q.Enqueue("write docs", 3)
+	q.Enqueue("fix bug", 1)
+	q.Enqueue("review", 2)
+	q.Enqueue("lunch", 5)
+	if !q.UpdatePriority("lunch", 0) {
+		t.Fatal("UpdatePriority did not find a queued item")
+	}
+	if q.UpdatePriority("nap", 0) {
+		t.Fatal("UpdatePriority found an item that was never queued")
+	}
+
+	if q.Len() != 4 || q.Peek() != "lunch" {
+		t.Fatalf("len %d, peek %q", q.Len(), q.Peek())
+	}
+	var got []string
+	for !q.Empty() {
+		got = append(got, q.Dequeue())
+	}
+	assertSlices(t, got, []string{"lunch", "fix bug", "review", "write docs"})
+}
+
+func TestQueueAppendVariants(t *testing.T) {
+	q := Of([]int{1, 2, 3, 4, 5})
+	buf := []int{0}
+
+	buf = q.AppendAll(buf)
+	assertSlices(t, buf, []int{0, 1, 2, 3, 4, 5})
+	if q.Len() != 5 {
+		t.Fatalf("AppendAll removed items: len %d", q.Len())
+	}
+
+	assertSlices(t, q.DequeueUpTo(2), []int{1, 2})
+	if q.Len() != 3 {
+		t.Fatalf("len %d after DequeueUpTo(2), want 3", q.Len())
+	}
+	buf = q.DequeueUpToAppend(buf[:0], 2)
+	assertSlices(t, buf, []int{3, 4})
+	if q.Len() != 1 || q.Peek() != 5 {
+		t.Fatalf("len %d after DequeueUpToAppend, want 1", q.Len())
+	}
+
+	// a batch larger than the queue takes what is there
+	assertSlices(t, q.DequeueUpTo(10), []int{5})
+	if !q.Empty() || q.Len() != 0 {
+		t.Fatal("queue should be empty")
+	}
+
+	// empty queues and non-positive batches leave dst unchanged
+	if got := q.DequeueUpTo(3); got != nil {
+		t.Fatalf("DequeueUpTo on an empty queue = %v", got)
+	}
+	assertSlices(t, q.DequeueAppend(buf), []int{3, 4})
+	assertSlices(t, q.AppendAll(buf), []int{3, 4})
+	q.Enqueue(6)
+	assertSlices(t, q.DequeueUpToAppend(buf, 0), []int{3, 4})
+	assertSlices(t, q.DequeueAppend(buf[:0]), []int{6})
+	if !q.Empty() {
+		t.Fatal("DequeueAppend left items in the queue")
+	}
+}
+
+func BenchmarkDequeueAppend(b *testing.B) {
+	q := New[int]()
+	buf := make([]int, 0, 100)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for j := 0; j < 100; j++ {
+			q.Enqueue(j)
+		}
+		b.StartTimer()
+		buf = q.DequeueAppend(buf[:0])
+	}
+}
+
+type payload struct {
+	data [1024]byte
+}
+
+// newPayloads returns 'n' payloads and a function that waits for up to a
+// second for 'want' of them to be garbage collected.
+func newPayloads(n int) ([]*payload, func(want int) bool) {
+	var collected int32
+	ps := make([]*payload, n)
+	for i := range ps {
+		ps[i] = &payload{}
+		runtime.SetFinalizer(ps[i], func(*payload) { atomic.AddInt32(&collected, 1) })
+	}
+	return ps, func(want int) bool {
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+			runtime.GC()
+			if atomic.LoadInt32(&collected) >= int32(want) {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+}
+
+func TestDequeueReleasesValues(t *testing.T) {
+	ps, collectedAtLeast := newPayloads(100)
+	q := Of(ps)
+	ps = nil
+
+	// a stale reference to a removed node must not keep its value, or the
+	// values of the nodes after it, reachable
+	stale := q.list.Front
+	for i := 0; i < 50; i++ {
+		q.Dequeue()
+	}
+	for !q.Empty() {
+		q.DequeueUpTo(10)
+	}
+	if !collectedAtLeast(100) {
+		t.Fatal("dequeued values are still reachable")
+	}
+	runtime.KeepAlive(stale)
+	runtime.KeepAlive(q)
+}
+
+func TestDequeueAllRetention(t *testing.T) {
+	ps, collectedAtLeast := newPayloads(100)
+	q := Of(ps)
+	ps = nil
+
+	all := q.DequeueAll()
+	for i := range all[:60] {
+		all[i] = nil
+	}
+	if !collectedAtLeast(60) {
+		t.Fatal("zeroed values are still reachable")
+	}
+	runtime.KeepAlive(all)
+	runtime.KeepAlive(q)
+}
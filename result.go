@@ -0,0 +1,57 @@
+package generic
+
+// Result holds either a value or an error, as returned by an operation that
+// may fail.
+type Result[T any] struct {
+	val T
+	err error
+}
+
+// Ok returns a successful result holding 'val'.
+func Ok[T any](val T) Result[T] {
+	return Result[T]{
+		val: val,
+	}
+}
+
+// Err returns a failed result holding 'err'.
+func Err[T any](err error) Result[T] {
+	return Result[T]{
+		err: err,
+	}
+}
+
+// ToResult converts the (value, error) pair returned by a function into a
+// result. If 'err' is not nil the value is discarded.
+func ToResult[T any](val T, err error) Result[T] {
+	if err != nil {
+		return Err[T](err)
+	}
+	return Ok(val)
+}
+
+// Unwrap returns the value and error held by the result. The value is the
+// zero value if the result holds an error.
+func (r Result[T]) Unwrap() (T, error) {
+	return r.val, r.err
+}
+
+// IsOk returns true if the result holds a value rather than an error.
+func (r Result[T]) IsOk() bool {
+	return r.err == nil
+}
+
+// Map returns a result holding fn(val) if 'r' holds a value. If 'r' holds an
+// error, 'fn' is not called and the error is returned unchanged.
+func (r Result[T]) Map(fn func(val T) T) Result[T] {
+	return MapResult(r, fn)
+}
+
+// MapResult is like Result.Map, but allows 'fn' to change the type of the
+// value.
+func MapResult[T, U any](r Result[T], fn func(val T) U) Result[U] {
+	if r.err != nil {
+		return Err[U](r.err)
+	}
+	return Ok(fn(r.val))
+}
@@ -0,0 +1,72 @@
+// Package ringbuffer provides a buffer of fixed capacity that holds the most
+// recently pushed elements, overwriting the oldest element once it is full.
+// This makes it suitable for keeping rolling logs or windows of metrics.
+package ringbuffer
+
+import "fmt"
+
+// RingBuffer holds up to a fixed number of elements in a circular array.
+type RingBuffer[T any] struct {
+	buf []T
+	// start is the index in buf of the oldest element.
+	start  int
+	length int
+}
+
+// New returns an empty ring buffer that holds up to 'capacity' elements. It
+// panics if 'capacity' is less than 1.
+func New[T any](capacity int) *RingBuffer[T] {
+	if capacity < 1 {
+		panic(fmt.Sprintf("ringbuffer: capacity %d is less than 1", capacity))
+	}
+	return &RingBuffer[T]{
+		buf: make([]T, capacity),
+	}
+}
+
+// Push adds 'value' as the newest element. If the buffer is full, the oldest
+// element is overwritten.
+func (r *RingBuffer[T]) Push(value T) {
+	if r.length < len(r.buf) {
+		r.buf[r.index(r.length)] = value
+		r.length++
+		return
+	}
+	r.buf[r.start] = value
+	r.start = r.index(1)
+}
+
+// Get returns the element at position 'i', where 0 is the oldest element and
+// Len()-1 is the newest. It panics if 'i' is out of range.
+func (r *RingBuffer[T]) Get(i int) T {
+	if i < 0 || i >= r.length {
+		panic(fmt.Sprintf("ringbuffer: index out of range [%d] with length %d", i, r.length))
+	}
+	return r.buf[r.index(i)]
+}
+
+// index returns the index in buf of the element at position 'i'.
+func (r *RingBuffer[T]) index(i int) int {
+	i += r.start
+	if i >= len(r.buf) {
+		i -= len(r.buf)
+	}
+	return i
+}
+
+// Len returns the number of elements in the buffer.
+func (r *RingBuffer[T]) Len() int {
+	return r.length
+}
+
+// Cap returns the maximum number of elements the buffer holds.
+func (r *RingBuffer[T]) Cap() int {
+	return len(r.buf)
+}
+
+// Each calls 'fn' on every element in the buffer, from oldest to newest.
+func (r *RingBuffer[T]) Each(fn func(t T)) {
+	for i := 0; i < r.length; i++ {
+		fn(r.buf[r.index(i)])
+	}
+}
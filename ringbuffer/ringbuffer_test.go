@@ -0,0 +1,83 @@
+package ringbuffer_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zyedidia/generic/ringbuffer"
+)
+
+func contents(r *ringbuffer.RingBuffer[int]) []int {
+	var got []int
+	r.Each(func(t int) {
+		got = append(got, t)
+	})
+	return got
+}
+
+func TestRingBuffer(t *testing.T) {
+	const capacity = 4
+	r := ringbuffer.New[int](capacity)
+	if r.Len() != 0 || r.Cap() != capacity || len(contents(r)) != 0 {
+		t.Fatalf("new buffer has length %d and capacity %d", r.Len(), r.Cap())
+	}
+
+	for i := 0; i < 3*capacity+1; i++ {
+		r.Push(i)
+
+		// the buffer holds the last 'capacity' elements pushed
+		oldest := i - capacity + 1
+		if oldest < 0 {
+			oldest = 0
+		}
+		var want []int
+		for v := oldest; v <= i; v++ {
+			want = append(want, v)
+		}
+		if r.Len() != len(want) || r.Cap() != capacity {
+			t.Fatalf("after pushing %d: length %d, capacity %d", i, r.Len(), r.Cap())
+		}
+		if got := contents(r); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("after pushing %d: Each got %v, want %v", i, got, want)
+		}
+		for j, v := range want {
+			if got := r.Get(j); got != v {
+				t.Fatalf("after pushing %d: Get(%d) = %d, want %d", i, j, got, v)
+			}
+		}
+	}
+}
+
+func TestPanics(t *testing.T) {
+	mustPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s did not panic", name)
+			}
+		}()
+		fn()
+	}
+
+	r := ringbuffer.New[int](2)
+	r.Push(1)
+	mustPanic("Get(-1)", func() { r.Get(-1) })
+	mustPanic("Get(Len())", func() { r.Get(1) })
+	mustPanic("New(0)", func() { ringbuffer.New[int](0) })
+}
+
+func Example() {
+	r := ringbuffer.New[string](3)
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		r.Push(s)
+	}
+	r.Each(func(s string) {
+		fmt.Println(s)
+	})
+	fmt.Println(r.Get(0), r.Len(), r.Cap())
+	// Output:
+	// c
+	// d
+	// e
+	// c 3 3
+}
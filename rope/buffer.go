@@ -0,0 +1,147 @@
+package rope
+
+import "io"
+
+// A Buffer assembles a byte rope from a stream. It implements io.Writer and
+// io.ReaderFrom to append data, and io.WriterTo to stream the data back out.
+// Data is stored in chunks of at most SplitLength bytes, which become the
+// leaves of the rope returned by Rope, so a rope can be built from a stream
+// in linear time. The zero value is an empty buffer ready to use.
+type Buffer struct {
+	chunks [][]byte
+	length int
+}
+
+// Len returns the number of bytes stored in the buffer.
+func (b *Buffer) Len() int {
+	return b.length
+}
+
+// tail returns the last chunk if it has room for more data, or starts a new
+// chunk otherwise.
+func (b *Buffer) tail() []byte {
+	if n := len(b.chunks); n > 0 && len(b.chunks[n-1]) < cap(b.chunks[n-1]) {
+		return b.chunks[n-1]
+	}
+	b.chunks = append(b.chunks, make([]byte, 0, SplitLength))
+	return b.chunks[len(b.chunks)-1]
+}
+
+// Write appends the contents of 'p' to the buffer. It always returns len(p)
+// and a nil error.
+func (b *Buffer) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		chunk := b.tail()
+		m := copy(chunk[len(chunk):cap(chunk)], p)
+		b.chunks[len(b.chunks)-1] = chunk[:len(chunk)+m]
+		p = p[m:]
+	}
+	b.length += n
+	return n, nil
+}
+
+// ReadFrom reads data from 'r' until EOF and appends it to the buffer, reading
+// directly into chunks of at most SplitLength bytes. It returns the number of
+// bytes read, and any error other than io.EOF encountered while reading.
+func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	for {
+		chunk := b.tail()
+		m, err := r.Read(chunk[len(chunk):cap(chunk)])
+		b.chunks[len(b.chunks)-1] = chunk[:len(chunk)+m]
+		b.length += m
+		total += int64(m)
+		if err == io.EOF {
+			return total, nil
+		} else if err != nil {
+			return total, err
+		}
+	}
+}
+
+// WriteTo writes the contents of the buffer to 'w' one chunk at a time,
+// without concatenating them. The contents of the buffer are not consumed.
+func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
+	r := reader{
+		chunks: b.chunks,
+	}
+	return r.WriteTo(w)
+}
+
+// Reader returns a reader over the current contents of the buffer. Reading
+// does not consume the buffer. The returned reader also implements
+// io.WriterTo, so io.Copy streams out the chunks directly.
+func (b *Buffer) Reader() io.Reader {
+	return &reader{
+		chunks: b.chunks,
+	}
+}
+
+type reader struct {
+	chunks [][]byte
+	// off is the read offset within chunks[0].
+	off int
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n := 0
+	for len(r.chunks) > 0 && n < len(p) {
+		m := copy(p[n:], r.chunks[0][r.off:])
+		n += m
+		r.advance(m)
+	}
+	if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func (r *reader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for len(r.chunks) > 0 {
+		m, err := w.Write(r.chunks[0][r.off:])
+		total += int64(m)
+		r.advance(m)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// advance moves the read offset forward by 'n' bytes within the first chunk.
+func (r *reader) advance(n int) {
+	r.off += n
+	if r.off == len(r.chunks[0]) {
+		r.chunks = r.chunks[1:]
+		r.off = 0
+	}
+}
+
+// Bytes returns a copy of the contents of the buffer.
+func (b *Buffer) Bytes() []byte {
+	p := make([]byte, 0, b.length)
+	for _, chunk := range b.chunks {
+		p = append(p, chunk...)
+	}
+	return p
+}
+
+// Rope returns a balanced rope holding the contents of the buffer, using the
+// chunks of the buffer as its leaves. The rope shares memory with the buffer,
+// so the buffer must not be used once the rope has been modified.
+func (b *Buffer) Rope() *Node[byte] {
+	if len(b.chunks) == 0 {
+		return New([]byte{})
+	}
+	return build(b.chunks)
+}
+
+func build(chunks [][]byte) *Node[byte] {
+	if len(chunks) == 1 {
+		return New(chunks[0])
+	}
+	mid := len(chunks) / 2
+	return join(build(chunks[:mid]), build(chunks[mid:]))
+}
@@ -0,0 +1,108 @@
+package rope_test
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/zyedidia/generic/rope"
+)
+
+func withSplitLength(n int) func() {
+	split, join := rope.SplitLength, rope.JoinLength
+	rope.SplitLength, rope.JoinLength = n, n/2
+	return func() {
+		rope.SplitLength, rope.JoinLength = split, join
+	}
+}
+
+// chunkedReader returns the data of a reader in reads of random sizes.
+type chunkedReader struct {
+	r io.Reader
+}
+
+func (c chunkedReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1+rand.Intn(len(p)-1)]
+	}
+	return c.r.Read(p)
+}
+
+func TestBufferCopy(t *testing.T) {
+	defer withSplitLength(4096)()
+
+	src := randbytes(10 * 1024 * 1024)
+
+	var b rope.Buffer
+	n, err := io.Copy(&b, chunkedReader{bytes.NewReader(src)})
+	if err != nil || n != int64(len(src)) {
+		t.Fatalf("copy in: %d, %v", n, err)
+	}
+	if b.Len() != len(src) {
+		t.Fatalf("length mismatch: %d != %d", b.Len(), len(src))
+	}
+
+	var out bytes.Buffer
+	n, err = io.Copy(&out, b.Reader())
+	if err != nil || n != int64(len(src)) {
+		t.Fatalf("copy out: %d, %v", n, err)
+	}
+	if !bytes.Equal(out.Bytes(), src) {
+		t.Fatal("copied data not equal")
+	}
+	if !bytes.Equal(b.Bytes(), src) {
+		t.Fatal("Bytes not equal")
+	}
+	out.Reset()
+	if _, err := b.WriteTo(&out); err != nil || !bytes.Equal(out.Bytes(), src) {
+		t.Fatalf("WriteTo not equal: %v", err)
+	}
+	// read in small pieces, without the io.WriterTo fast path
+	read, err := io.ReadAll(chunkedReader{b.Reader()})
+	if err != nil || !bytes.Equal(read, src) {
+		t.Fatalf("Reader not equal: %v", err)
+	}
+
+	r := b.Rope()
+	if !bytes.Equal(r.Value(), src) {
+		t.Fatal("rope value not equal")
+	}
+	r.Each(func(n *rope.Node[byte]) {
+		if n.Len() > rope.SplitLength {
+			t.Fatalf("leaf of length %d exceeds %d", n.Len(), rope.SplitLength)
+		}
+	})
+}
+
+func TestBufferWrite(t *testing.T) {
+	var b rope.Buffer
+	if b.Rope().Len() != 0 || len(b.Bytes()) != 0 {
+		t.Fatal("zero buffer should be empty")
+	}
+
+	var want []byte
+	for i := 0; i < 100; i++ {
+		p := randbytes(rand.Intn(10))
+		want = append(want, p...)
+		if n, err := b.Write(p); n != len(p) || err != nil {
+			t.Fatalf("write: %d, %v", n, err)
+		}
+	}
+	if !bytes.Equal(b.Rope().Value(), want) {
+		t.Fatalf("rope value not equal: %s %s", b.Rope().Value(), want)
+	}
+}
+
+func BenchmarkBufferReadFrom(b *testing.B) {
+	defer withSplitLength(4096)()
+
+	src := randbytes(10 * 1024 * 1024)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(src)))
+	for i := 0; i < b.N; i++ {
+		var buf rope.Buffer
+		buf.ReadFrom(bytes.NewReader(src))
+		buf.Rope()
+	}
+}
@@ -0,0 +1,158 @@
+package rope
+
+// A Cursor is a position in a rope that remembers the path from the root to
+// the leaf containing it, so that a run of edits at or near the same position
+// does not search the tree for every edit. The path is only recomputed, in
+// O(lg n), when an edit splits or joins nodes, when the cursor moves out of its
+// leaf, or when the rope has been modified by other means. Otherwise an edit
+// only changes the leaf and adds to the cached lengths along the path.
+//
+// A cursor is bound to the root node it was created from: it detects
+// modifications made through that node, or through other cursors over it, but
+// not modifications made directly to its descendants.
+type Cursor[V any] struct {
+	root *Node[V]
+	pos  int
+
+	// path holds the nodes from the root to the leaf containing pos, and
+	// off is the position within that leaf. The path is valid if it is not
+	// empty and root.mods equals mods.
+	path []*Node[V]
+	off  int
+	mods uint64
+}
+
+// Cursor returns a cursor over the rope positioned at 'pos', which is clamped
+// to [0, Len()].
+func (n *Node[V]) Cursor(pos int) *Cursor[V] {
+	c := &Cursor[V]{
+		root: n,
+	}
+	c.pos = c.clamp(pos)
+	return c
+}
+
+func (c *Cursor[V]) clamp(pos int) int {
+	if pos < 0 {
+		return 0
+	} else if pos > c.root.length {
+		return c.root.length
+	}
+	return pos
+}
+
+// Pos returns the position of the cursor.
+func (c *Cursor[V]) Pos() int {
+	return c.pos
+}
+
+func (c *Cursor[V]) valid() bool {
+	return len(c.path) > 0 && c.mods == c.root.mods
+}
+
+// descend recomputes the path if it is not valid.
+func (c *Cursor[V]) descend() {
+	if c.valid() {
+		return
+	}
+	c.pos = c.clamp(c.pos)
+	c.path = c.path[:0]
+	n, off := c.root, c.pos
+	for n.kind == tNode {
+		c.path = append(c.path, n)
+		if off < n.left.length {
+			n = n.left
+		} else {
+			off -= n.left.length
+			n = n.right
+		}
+	}
+	c.path = append(c.path, n)
+	c.off = off
+	c.mods = c.root.mods
+}
+
+func (c *Cursor[V]) leaf() *Node[V] {
+	return c.path[len(c.path)-1]
+}
+
+// grow adds 'delta' to the lengths of the nodes on the path, and records the
+// edit as a modification of the root.
+func (c *Cursor[V]) grow(delta int) {
+	for _, n := range c.path {
+		n.length += delta
+	}
+	c.root.mods++
+	c.mods = c.root.mods
+}
+
+// Advance moves the cursor by 'delta', which may be negative. The position is
+// clamped to [0, Len()].
+func (c *Cursor[V]) Advance(delta int) {
+	pos := c.clamp(c.pos + delta)
+	if c.valid() {
+		if off := c.off + pos - c.pos; off >= 0 && off <= c.leaf().length {
+			c.off = off
+		} else {
+			c.path = c.path[:0]
+		}
+	}
+	c.pos = pos
+}
+
+// InsertHere inserts 'data' at the cursor, and moves the cursor past it.
+func (c *Cursor[V]) InsertHere(data []V) {
+	if len(data) == 0 {
+		return
+	}
+	c.descend()
+	leaf := c.leaf()
+	if len(leaf.value)+len(data) > cap(leaf.value) {
+		// grow the leaf geometrically rather than to the exact size, since a
+		// run of small insertions at the cursor is expected
+		grown := make([]V, len(leaf.value), 2*len(leaf.value)+len(data))
+		copy(grown, leaf.value)
+		leaf.value = grown
+	}
+	leaf.value = insert(leaf.value, c.off, data)
+	c.grow(len(data))
+	c.pos += len(data)
+	c.off += len(data)
+	if leaf.length > SplitLength {
+		leaf.adjust()
+		c.path = c.path[:0]
+	}
+}
+
+// RemoveNext removes the 'k' elements following the cursor, or as many as
+// there are if fewer than 'k' remain. The cursor does not move.
+func (c *Cursor[V]) RemoveNext(k int) {
+	if k > c.root.length-c.pos {
+		k = c.root.length - c.pos
+	}
+	if k <= 0 {
+		return
+	}
+	c.descend()
+	if c.off == c.leaf().length {
+		// at the end of a leaf, so move to the start of the next one
+		c.path = c.path[:0]
+		c.descend()
+	}
+	if c.off+k > c.leaf().length {
+		// the range spans several leaves
+		c.root.Remove(c.pos, c.pos+k)
+		return
+	}
+	leaf := c.leaf()
+	leaf.value = append(leaf.value[:c.off], leaf.value[c.off+k:]...)
+	c.grow(-k)
+	// join the highest node that has become small enough, as Remove would
+	for _, n := range c.path[:len(c.path)-1] {
+		if n.length < JoinLength {
+			n.adjust()
+			c.path = c.path[:0]
+			return
+		}
+	}
+}
@@ -18,7 +18,11 @@
 // case, which is often unacceptable.
 package rope
 
-import g "github.com/zyedidia/generic"
+import (
+	"unsafe"
+
+	g "github.com/zyedidia/generic"
+)
 
 var (
 	// SplitLength is the threshold above which slices will be split into
@@ -47,6 +51,10 @@ type Node[V any] struct {
 	value       []V
 	length      int
 	left, right *Node[V]
+	// mods counts the calls to Insert, Remove, Rebuild and Rebalance on
+	// this node, and the edits made by cursors over it, so that a cursor can
+	// tell when its cached path may have changed.
+	mods uint64
 }
 
 // New returns a new rope node from the given byte slice. The underlying
@@ -93,57 +101,144 @@ func (n *Node[V]) adjust() {
 // return the underlying slice without copying, so do not modify the returned
 // slice.
 func (n *Node[V]) Value() []V {
-	switch n.kind {
-	case tLeaf:
+	if n.kind == tLeaf {
 		return n.value
-	case tNode:
-		return concat(n.left.Value(), n.right.Value())
 	}
-	panic("unreachable")
+	value := make([]V, 0, n.length)
+	n.leaves(0, n.length, func(s []V) {
+		value = append(value, s...)
+	})
+	return value
+}
+
+// leaves calls 'fn' on the part of each leaf that lies in the range
+// [start:end) of the rope, in order. It uses an explicit stack rather than
+// recursion, since a rope built by many joins may be very deep.
+func (n *Node[V]) leaves(start, end int, fn func(s []V)) {
+	type frame struct {
+		n          *Node[V]
+		start, end int
+	}
+	var buf [32]frame // enough for a balanced rope, without allocating
+	stack := append(buf[:0], frame{n, start, end})
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f.start >= f.end {
+			continue
+		}
+		if f.n.kind == tLeaf {
+			fn(f.n.value[f.start:f.end])
+			continue
+		}
+		m := f.n.left.length
+		// push the right child first so that the left one is visited first
+		if f.end > m {
+			stack = append(stack, frame{f.n.right, g.Max(f.start-m, 0), f.end - m})
+		}
+		if f.start < m {
+			stack = append(stack, frame{f.n.left, f.start, g.Min(f.end, m)})
+		}
+	}
 }
 
 // Remove deletes the range [start:end) (exclusive bound) from the rope.
 func (n *Node[V]) Remove(start, end int) {
-	switch n.kind {
-	case tLeaf:
-		// slice tricks delete
-		n.value = append(n.value[:start], n.value[end:]...)
-		n.length = len(n.value)
-	case tNode:
-		leftLength := n.left.length
-		leftStart := g.Min(start, leftLength)
-		leftEnd := g.Min(end, leftLength)
-		rightLength := n.right.length
-		rightStart := g.Max(0, g.Min(start-leftLength, rightLength))
-		rightEnd := g.Max(0, g.Min(end-leftLength, rightLength))
-		if leftStart < leftLength {
-			n.left.Remove(leftStart, leftEnd)
-		}
-		if rightEnd > 0 {
-			n.right.Remove(rightStart, rightEnd)
+	// descend while the range lies within a single child, then update the
+	// lengths on the path on the way back up
+	var buf [32]*Node[V]
+	path := buf[:0]
+	for n.kind == tNode {
+		m := n.left.length
+		next := n.left
+		if start >= m && end > m {
+			start = g.Min(start-m, n.right.length)
+			end = g.Min(end-m, n.right.length)
+			next = n.right
+		} else if start >= m || end > m {
+			break
 		}
+		n.mods++
+		path = append(path, n)
+		n = next
+	}
+	n.remove(start, end)
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
 		n.length = n.left.length + n.right.length
+		n.adjust()
+	}
+}
+
+// remove deletes the range [start:end) from the subtree rooted at 'n'.
+func (n *Node[V]) remove(start, end int) {
+	// The nodes overlapping the range are visited with an explicit stack,
+	// and each internal node is adjusted after both of its children.
+	type frame struct {
+		n          *Node[V]
+		start, end int
+		visited    bool
+	}
+	var buf [32]frame
+	stack := append(buf[:0], frame{n: n, start: start, end: end})
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		n := f.n
+		switch {
+		case n.kind == tLeaf:
+			n.mods++
+			// slice tricks delete
+			n.value = append(n.value[:f.start], n.value[f.end:]...)
+			n.length = len(n.value)
+		case !f.visited:
+			f.visited = true
+			n.mods++
+			leftLength := n.left.length
+			leftStart := g.Min(f.start, leftLength)
+			leftEnd := g.Min(f.end, leftLength)
+			rightLength := n.right.length
+			rightStart := g.Max(0, g.Min(f.start-leftLength, rightLength))
+			rightEnd := g.Max(0, g.Min(f.end-leftLength, rightLength))
+			if rightEnd > 0 {
+				stack = append(stack, frame{n: n.right, start: rightStart, end: rightEnd})
+			}
+			if leftStart < leftLength {
+				stack = append(stack, frame{n: n.left, start: leftStart, end: leftEnd})
+			}
+			continue
+		default:
+			n.length = n.left.length + n.right.length
+		}
+		n.adjust()
+		stack = stack[:len(stack)-1]
 	}
-	n.adjust()
 }
 
 // Insert inserts the given value at pos.
 func (n *Node[V]) Insert(pos int, value []V) {
-	switch n.kind {
-	case tLeaf:
-		// slice tricks insert
-		n.value = insert(n.value, pos, value)
-		n.length = len(n.value)
-	case tNode:
-		leftLength := n.left.length
-		if pos < leftLength {
-			n.left.Insert(pos, value)
+	// descend to the leaf, then update the lengths on the way back up
+	var buf [32]*Node[V]
+	path := buf[:0]
+	for n.kind == tNode {
+		n.mods++
+		path = append(path, n)
+		if pos < n.left.length {
+			n = n.left
 		} else {
-			n.right.Insert(pos-leftLength, value)
+			pos -= n.left.length
+			n = n.right
 		}
-		n.length = n.left.length + n.right.length
 	}
+	n.mods++
+	// slice tricks insert
+	n.value = insert(n.value, pos, value)
+	n.length = len(n.value)
 	n.adjust()
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
+		n.length = n.left.length + n.right.length
+		n.adjust()
+	}
 }
 
 // Slice returns the range of the rope from [start:end). The returned slice
@@ -153,32 +248,29 @@ func (n *Node[V]) Slice(start, end int) []V {
 		return []V{}
 	}
 
-	switch n.kind {
-	case tLeaf:
-		return n.value[start:end]
-	case tNode:
-		leftLength := n.left.length
-		leftStart := g.Min(start, leftLength)
-		leftEnd := g.Min(end, leftLength)
-		rightLength := n.right.length
-		rightStart := g.Max(0, g.Min(start-leftLength, rightLength))
-		rightEnd := g.Max(0, g.Min(end-leftLength, rightLength))
-
-		if leftStart != leftEnd {
-			if rightStart != rightEnd {
-				return concat(n.left.Slice(leftStart, leftEnd), n.right.Slice(rightStart, rightEnd))
-			} else {
-				return n.left.Slice(leftStart, leftEnd)
-			}
+	// find the lowest node containing the whole range
+	for n.kind == tNode {
+		end = g.Min(end, n.length)
+		if start >= end {
+			return []V{}
+		}
+		if m := n.left.length; end <= m {
+			n = n.left
+		} else if start >= m {
+			start, end = start-m, end-m
+			n = n.right
 		} else {
-			if rightStart != rightEnd {
-				return n.right.Slice(rightStart, rightEnd)
-			} else {
-				return []V{}
-			}
+			break
 		}
 	}
-	panic("unreachable")
+	if n.kind == tLeaf {
+		return n.value[start:end]
+	}
+	slice := make([]V, 0, end-start)
+	n.leaves(start, end, func(s []V) {
+		slice = append(slice, s...)
+	})
+	return slice
 }
 
 // At returns the element at the given position.
@@ -190,21 +282,41 @@ func (n *Node[V]) At(pos int) V {
 // SplitAt splits the node at the given index and returns two new ropes
 // corresponding to the left and right portions of the split.
 func (n *Node[V]) SplitAt(i int) (*Node[V], *Node[V]) {
-	switch n.kind {
-	case tLeaf:
-		return New(n.value[:i]), New(n.value[i:])
-	case tNode:
+	// descend to the split point, then join the parts of each node on the
+	// path that are not split with the two halves on the way back up
+	type step struct {
+		n    *Node[V]
+		left bool
+	}
+	var buf [32]step
+	path := buf[:0]
+	var l, r *Node[V]
+	for {
+		if n.kind == tLeaf {
+			l, r = New(n.value[:i]), New(n.value[i:])
+			break
+		}
 		m := n.left.length
 		if i == m {
-			return n.left, n.right
-		} else if i < m {
-			l, r := n.left.SplitAt(i)
-			return l, join(r, n.right)
+			l, r = n.left, n.right
+			break
+		}
+		path = append(path, step{n, i < m})
+		if i < m {
+			n = n.left
+		} else {
+			i -= m
+			n = n.right
+		}
+	}
+	for j := len(path) - 1; j >= 0; j-- {
+		if p := path[j]; p.left {
+			r = join(r, p.n.right)
+		} else {
+			l = join(p.n.left, l)
 		}
-		l, r := n.right.SplitAt(i - m)
-		return join(n.left, l), r
 	}
-	panic("unreachable")
+	return l, r
 }
 
 func join[V any](l, r *Node[V]) *Node[V] {
@@ -229,9 +341,10 @@ func Join[V any](a, b *Node[V], more ...*Node[V]) *Node[V] {
 
 // Rebuild rebuilds the entire rope structure, resulting in a balanced tree.
 func (n *Node[V]) Rebuild() {
+	n.mods++
 	switch n.kind {
 	case tNode:
-		n.value = concat(n.left.Value(), n.right.Value())
+		n.value = n.Value()
 		n.left = nil
 		n.right = nil
 		n.adjust()
@@ -240,6 +353,7 @@ func (n *Node[V]) Rebuild() {
 
 // Rebalance finds unbalanced nodes and rebuilds them.
 func (n *Node[V]) Rebalance() {
+	n.mods++
 	switch n.kind {
 	case tNode:
 		lratio := float64(n.left.length) / float64(n.right.length)
@@ -253,15 +367,95 @@ func (n *Node[V]) Rebalance() {
 	}
 }
 
+// Memory returns an estimate of the number of bytes used by the rope: its
+// nodes and the elements stored in its leaves. Memory referenced by pointers
+// in the elements is not counted; use MemoryFunc to include it.
+func (n *Node[V]) Memory() int64 {
+	var v V
+	var total int64
+	// walk the nodes with an explicit stack, like Each, so that a deep,
+	// unbalanced rope cannot overflow the goroutine stack
+	var buf [64]*Node[V]
+	stack := append(buf[:0], n)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		total += int64(unsafe.Sizeof(*n)) + int64(len(n.value))*int64(unsafe.Sizeof(v))
+		if n.kind == tNode {
+			stack = append(stack, n.right, n.left)
+		}
+	}
+	return total
+}
+
+// MemoryFunc is like Memory, but also adds 'sizeOf' for every element, to
+// account for memory referenced by the elements.
+func (n *Node[V]) MemoryFunc(sizeOf func(v V) int64) int64 {
+	total := n.Memory()
+	n.Each(func(leaf *Node[V]) {
+		for _, v := range leaf.value {
+			total += sizeOf(v)
+		}
+	})
+	return total
+}
+
 // Each applies the given function to every leaf node in order.
 func (n *Node[V]) Each(fn func(n *Node[V])) {
-	switch n.kind {
-	case tLeaf:
-		fn(n)
-	default: // case tNode
-		n.left.Each(fn)
-		n.right.Each(fn)
+	var buf [64]*Node[V]
+	stack := append(buf[:0], n)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n.kind == tLeaf {
+			fn(n)
+		} else {
+			stack = append(stack, n.right, n.left)
+		}
+	}
+}
+
+// FindAll returns the starting positions of every occurrence of 'needle' in
+// the rope, in increasing order. Occurrences may overlap, and may span any
+// number of leaves. An empty needle matches nowhere. The leaves are scanned
+// once, carrying the partial match at the end of each leaf over to the next,
+// so the complexity is O(n + m), where m is the length of the needle.
+func (n *Node[V]) FindAll(needle []V, eq g.EqualsFn[V]) []int {
+	if len(needle) == 0 {
+		return nil
+	}
+
+	// fail[i] is the length of the longest proper prefix of needle[:i+1]
+	// that is also a suffix of it (Knuth-Morris-Pratt).
+	fail := make([]int, len(needle))
+	for i, k := 1, 0; i < len(needle); i++ {
+		for k > 0 && !eq(needle[i], needle[k]) {
+			k = fail[k-1]
+		}
+		if eq(needle[i], needle[k]) {
+			k++
+		}
+		fail[i] = k
 	}
+
+	var found []int
+	pos, k := 0, 0 // k is the length of the match ending at pos
+	n.Each(func(leaf *Node[V]) {
+		for _, v := range leaf.value {
+			for k > 0 && !eq(v, needle[k]) {
+				k = fail[k-1]
+			}
+			if eq(v, needle[k]) {
+				k++
+			}
+			pos++
+			if k == len(needle) {
+				found = append(found, pos-len(needle))
+				k = fail[k-1]
+			}
+		}
+	})
+	return found
 }
 
 // from slice tricks
@@ -278,10 +472,3 @@ func insert[V any](s []V, k int, vs []V) []V {
 	copy(s2[k+len(vs):], s[k:])
 	return s2
 }
-
-func concat[V any](a, b []V) []V {
-	c := make([]V, 0, len(a)+len(b))
-	c = append(c, a...)
-	c = append(c, b...)
-	return c
-}
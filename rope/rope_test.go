@@ -5,8 +5,13 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"reflect"
+	"runtime/debug"
 	"testing"
+	"unsafe"
 
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/internal/testutil"
 	"github.com/zyedidia/generic/rope"
 )
 
@@ -172,6 +177,204 @@ func insert(s []byte, k int, vs []byte) []byte {
 	return s2
 }
 
+func TestMemory(t *testing.T) {
+	for _, n := range []int{0, 10, 100000, 1000000} {
+		r := rope.New(make([]byte, n))
+		leaves := 0
+		r.Each(func(*rope.Node[byte]) { leaves++ })
+		// every internal node has two children
+		nodes := 2*leaves - 1
+		want := int64(nodes)*int64(unsafe.Sizeof(*r)) + int64(n)
+		if got := r.Memory(); got != want {
+			t.Fatalf("Memory() = %d for %d elements in %d leaves, want %d", got, n, leaves, want)
+		}
+	}
+}
+
+func BenchmarkMemory(b *testing.B) {
+	testutil.ReportMemory(b, func() *rope.Node[byte] {
+		return rope.New(make([]byte, 1000000))
+	}, (*rope.Node[byte]).Memory)
+}
+
+func findAllNaive(b, needle []byte) []int {
+	var found []int
+	for i := 0; i+len(needle) <= len(b); i++ {
+		if bytes.Equal(b[i:i+len(needle)], needle) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+func TestFindAll(t *testing.T) {
+	// with a SplitLength of 4, most occurrences straddle leaf boundaries
+	r := rope.New([]byte("abababcabababab"))
+	if got, want := r.FindAll([]byte("abab"), g.Equals[byte]), []int{0, 2, 7, 9, 11}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindAll = %v, want %v", got, want)
+	}
+	if got := r.FindAll(nil, g.Equals[byte]); got != nil {
+		t.Fatalf("FindAll of empty needle = %v", got)
+	}
+
+	for i := 0; i < 100; i++ {
+		b := make([]byte, 200)
+		for j := range b {
+			b[j] = "ab"[rand.Intn(2)]
+		}
+		r := rope.New(b)
+		for _, n := range []int{1, 2, 3, 5, 9} {
+			needle := randbytes(n)
+			for j := range needle {
+				needle[j] = "ab"[rand.Intn(2)]
+			}
+			got := r.FindAll(needle, g.Equals[byte])
+			if want := findAllNaive(r.Value(), needle); !reflect.DeepEqual(got, want) {
+				t.Fatalf("FindAll(%q) = %v, want %v", needle, got, want)
+			}
+		}
+	}
+}
+
+func tokens(r *rope.Node[byte], isDelim func(byte) bool) [][]byte {
+	var toks [][]byte
+	next := rope.Tokenize(r, isDelim)
+	for tok, ok := next(); ok; tok, ok = next() {
+		toks = append(toks, tok)
+	}
+	return toks
+}
+
+func TestTokenize(t *testing.T) {
+	isSpace := func(b byte) bool { return b == ' ' }
+
+	// with a SplitLength of 4, tokens and runs of delimiters straddle leaf
+	// boundaries
+	r := rope.New([]byte("  one two   three fourteen  "))
+	if got, want := tokens(r, isSpace), [][]byte{[]byte("one"), []byte("two"), []byte("three"), []byte("fourteen")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Tokenize = %q, want %q", got, want)
+	}
+	if got := tokens(rope.New([]byte("   ")), isSpace); got != nil {
+		t.Fatalf("Tokenize of delimiters = %q", got)
+	}
+	if got := tokens(rope.New([]byte{}), isSpace); got != nil {
+		t.Fatalf("Tokenize of empty rope = %q", got)
+	}
+
+	for i := 0; i < 100; i++ {
+		b := make([]byte, 200)
+		for j := range b {
+			b[j] = "ab "[rand.Intn(3)]
+		}
+		r := rope.New(b)
+		// edit the rope so that the leaves have uneven lengths
+		for j := 0; j < 10; j++ {
+			pos := rand.Intn(r.Len())
+			r.Insert(pos, []byte(" ab"[:rand.Intn(4)]))
+			start, end := randrange(r.Len())
+			r.Remove(start, g.Min(end, start+3))
+		}
+		value := append([]byte(nil), r.Value()...)
+
+		got := tokens(r, isSpace)
+		if want := bytes.FieldsFunc(value, func(r rune) bool { return r == ' ' }); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Tokenize(%q) = %q, want %q", value, got, want)
+		}
+		if !bytes.Equal(r.Value(), value) {
+			t.Fatalf("Tokenize modified the rope: %q, was %q", r.Value(), value)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	r, b := data()
+	c := r.Cursor(r.Len() / 2)
+	pos := c.Pos()
+
+	for i := 0; i < 2000; i++ {
+		switch op := rand.Intn(10); {
+		case op < 4:
+			s := randbytes(1 + rand.Intn(3))
+			c.InsertHere(s)
+			b.insert(pos, s)
+			pos += len(s)
+		case op < 6:
+			k := rand.Intn(4)
+			c.RemoveNext(k)
+			b.remove(pos, min(pos+k, b.length()))
+		case op < 8:
+			delta := rand.Intn(9) - 4
+			c.Advance(delta)
+			pos = max(0, min(pos+delta, b.length()))
+		case op == 8:
+			// an edit made without the cursor forces it to descend again
+			at := rand.Intn(b.length() + 1)
+			s := randbytes(1 + rand.Intn(10))
+			r.Insert(at, s)
+			b.insert(at, s)
+			if at <= pos {
+				c.Advance(len(s))
+				pos += len(s)
+			}
+		default:
+			low := rand.Intn(b.length() + 1)
+			high := min(low+rand.Intn(10), b.length())
+			r.Remove(low, high)
+			b.remove(low, high)
+			if pos > high {
+				c.Advance(low - high)
+				pos -= high - low
+			} else if pos > low {
+				c.Advance(low - pos)
+				pos = low
+			}
+		}
+		if c.Pos() != pos {
+			t.Fatalf("cursor at %d, want %d", c.Pos(), pos)
+		}
+		check(r, b, t)
+	}
+
+	// two cursors over the same rope
+	c2 := r.Cursor(0)
+	c2.InsertHere([]byte("xy"))
+	b.insert(0, []byte("xy"))
+	c.Advance(2)
+	c.InsertHere([]byte("z"))
+	b.insert(pos+2, []byte("z"))
+	check(r, b, t)
+}
+
+func BenchmarkTyping(b *testing.B) {
+	defer withSplitLength(4096)()
+	text := randbytes(1024 * 1024)
+	const ntyped = 10000
+
+	b.Run("Insert", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			r := rope.New(append([]byte(nil), text...))
+			b.StartTimer()
+			pos := len(text) / 3
+			for j := 0; j < ntyped; j++ {
+				r.Insert(pos, []byte{'a'})
+				pos++
+			}
+		}
+	})
+	b.Run("Cursor", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			r := rope.New(append([]byte(nil), text...))
+			b.StartTimer()
+			c := r.Cursor(len(text) / 3)
+			for j := 0; j < ntyped; j++ {
+				c.InsertHere([]byte{'a'})
+			}
+		}
+	})
+}
+
 func Example() {
 	r := rope.New[byte]([]byte("hello world"))
 
@@ -185,3 +388,159 @@ func Example() {
 	// h
 	// hello rope
 }
+
+// deepRope returns a rope of 'depth' leaves of 'leafLen' elements each,
+// joined one at a time so that the tree is a chain of the given depth.
+func deepRope(depth, leafLen int) (*rope.Node[byte], *basicText) {
+	data := randbytes(depth * leafLen)
+	leaves := make([]*rope.Node[byte], depth)
+	for i := range leaves {
+		leaves[i] = rope.New(append([]byte(nil), data[i*leafLen:(i+1)*leafLen]...))
+	}
+	return rope.Join(leaves[0], leaves[1], leaves[2:]...), newBasicText(data)
+}
+
+func TestDeep(t *testing.T) {
+	defer withSplitLength(16)()
+	r, b := deepRope(200000, 10)
+
+	// A recursive implementation would need tens of megabytes of stack for
+	// a rope this deep; exceeding the limit crashes the test.
+	defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+
+	check(r, b, t)
+	if got := r.Slice(5, r.Len()-5); !bytes.Equal(got, b.slice(5, b.length()-5)) {
+		t.Fatal("incorrect slice")
+	}
+	if r.At(r.Len()-1) != b.value()[b.length()-1] {
+		t.Fatal("incorrect last element")
+	}
+	leaves := 0
+	r.Each(func(*rope.Node[byte]) { leaves++ })
+	if leaves < 200000 {
+		t.Fatalf("%d leaves, want at least 200000", leaves)
+	}
+	mem := r.Memory()
+	if mem < 200000*10 {
+		t.Fatalf("Memory() = %d, want at least the %d elements", mem, 200000*10)
+	}
+	if got := r.MemoryFunc(func(byte) int64 { return 1 }); got != mem+int64(r.Len()) {
+		t.Fatalf("MemoryFunc() = %d, want %d", got, mem+int64(r.Len()))
+	}
+
+	r.Insert(3, []byte("abc"))
+	b.insert(3, []byte("abc"))
+	r.Remove(1, b.length()-100)
+	b.remove(1, b.length()-100)
+	check(r, b, t)
+
+	r, b = deepRope(200000, 10)
+	left, right := r.SplitAt(15)
+	if !bytes.Equal(left.Value(), b.slice(0, 15)) || !bytes.Equal(right.Value(), b.slice(15, b.length())) {
+		t.Fatal("incorrect split")
+	}
+}
+
+func BenchmarkOps(b *testing.B) {
+	defer withSplitLength(256)()
+	text := randbytes(1024 * 1024)
+	r := rope.New(append([]byte(nil), text...))
+
+	b.Run("Value", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r.Value()
+		}
+	})
+	b.Run("Slice", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			start := rand.Intn(r.Len() - 1000)
+			r.Slice(start, start+1000)
+		}
+	})
+	b.Run("InsertRemove", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pos := rand.Intn(r.Len() - 10)
+			r.Insert(pos, []byte("0123456789"))
+			r.Remove(pos, pos+10)
+		}
+	})
+	b.Run("SplitAt", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r.SplitAt(rand.Intn(r.Len()))
+		}
+	})
+}
+
+func TestTry(t *testing.T) {
+	r, b := data()
+	n := r.Len()
+
+	for _, pos := range []int{-1, 0, n - 1, n} {
+		v, err := r.TryAt(pos)
+		if ok := pos >= 0 && pos < n; ok != (err == nil) {
+			t.Errorf("TryAt(%d) error = %v", pos, err)
+		} else if ok && v != b.slice(pos, pos+1)[0] {
+			t.Errorf("TryAt(%d) = %c", pos, v)
+		}
+	}
+
+	ranges := []struct {
+		start, end int
+		ok         bool
+	}{
+		{10, 20, true},
+		{0, 0, true},
+		{n, n, true},
+		{-1, 2, false},
+		{3, 2, false},
+		{0, n + 1, false},
+		{n + 1, n + 1, false},
+		{0, n, true},
+	}
+	for _, rg := range ranges {
+		s, err := r.TrySlice(rg.start, rg.end)
+		if rg.ok != (err == nil) {
+			t.Errorf("TrySlice(%d, %d) error = %v", rg.start, rg.end, err)
+		} else if rg.ok && !bytes.Equal(s, b.slice(rg.start, rg.end)) {
+			t.Errorf("TrySlice(%d, %d) = %s", rg.start, rg.end, s)
+		}
+	}
+	for _, rg := range ranges {
+		// removing shortens the rope, so check against its current length
+		ok := rg.start >= 0 && rg.start <= rg.end && rg.end <= r.Len()
+		if err := r.TryRemove(rg.start, rg.end); ok != (err == nil) {
+			t.Errorf("TryRemove(%d, %d) error = %v", rg.start, rg.end, err)
+		} else if ok {
+			b.remove(rg.start, rg.end)
+		}
+		check(r, b, t)
+	}
+
+	n = r.Len()
+	for _, pos := range []int{-1, 0, n, n + 1} {
+		if err := r.TryInsert(pos, []byte("xyz")); (pos >= 0 && pos <= n) != (err == nil) {
+			t.Errorf("TryInsert(%d) error = %v", pos, err)
+		} else if err == nil {
+			b.insert(pos, []byte("xyz"))
+		}
+		check(r, b, t)
+		n = r.Len()
+	}
+}
+
+func Example_try() {
+	r := rope.New([]byte("hello"))
+
+	// At panics on an out-of-range position, while TryAt returns an error.
+	fmt.Println(string(r.At(4)))
+	if _, err := r.TryAt(5); err != nil {
+		fmt.Println(err)
+	}
+	if err := r.TryInsert(5, []byte(" rope")); err == nil {
+		fmt.Println(string(r.Value()))
+	}
+	// Output:
+	// o
+	// rope: index out of range [5] with length 5
+	// hello rope
+}
@@ -0,0 +1,62 @@
+package rope
+
+// Tokenize returns an iterator over the tokens of the rope 'r': the maximal
+// non-empty runs of bytes for which 'isDelim' returns false. Each call to the
+// iterator returns the next token, or false once there are none left. The
+// leaves are visited lazily, so the rope is never flattened; only tokens that
+// span several leaves are copied. Other tokens may share memory with the
+// rope, so do not modify them, and do not modify the rope while iterating.
+func Tokenize(r *Node[byte], isDelim func(byte) bool) func() ([]byte, bool) {
+	stack := []*Node[byte]{r}
+	var leaf []byte // the unscanned part of the current leaf
+
+	// nextLeaf advances to the next leaf, and returns false if there are no
+	// more leaves.
+	nextLeaf := func() bool {
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if n.kind == tLeaf {
+				leaf = n.value
+				return true
+			}
+			stack = append(stack, n.right, n.left)
+		}
+		return false
+	}
+
+	return func() ([]byte, bool) {
+		var tok []byte
+		for len(leaf) > 0 || nextLeaf() {
+			if tok == nil {
+				// skip the delimiters before the token
+				i := 0
+				for i < len(leaf) && isDelim(leaf[i]) {
+					i++
+				}
+				leaf = leaf[i:]
+				if len(leaf) == 0 {
+					continue
+				}
+			}
+
+			j := 0
+			for j < len(leaf) && !isDelim(leaf[j]) {
+				j++
+			}
+			if tok == nil {
+				// limit the capacity so that a token continuing into the
+				// next leaf is copied rather than appended to the rope
+				tok = leaf[:j:j]
+			} else {
+				tok = append(tok, leaf[:j]...)
+			}
+			if j < len(leaf) {
+				leaf = leaf[j+1:]
+				return tok, true
+			}
+			leaf = nil
+		}
+		return tok, tok != nil
+	}
+}
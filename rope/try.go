@@ -0,0 +1,58 @@
+package rope
+
+import "fmt"
+
+// TryAt returns the element at the given position, or an error if the
+// position is out of range, rather than panicking like At.
+func (n *Node[V]) TryAt(pos int) (V, error) {
+	if err := checkIndex(pos, n.length); err != nil {
+		var v V
+		return v, err
+	}
+	return n.At(pos), nil
+}
+
+// TrySlice returns the range of the rope from [start:end), or an error if the
+// range is not within the rope. Unlike Slice, it does not clamp 'end' to the
+// length of the rope.
+func (n *Node[V]) TrySlice(start, end int) ([]V, error) {
+	if err := checkRange(start, end, n.length); err != nil {
+		return nil, err
+	}
+	return n.Slice(start, end), nil
+}
+
+// TryRemove deletes the range [start:end) from the rope, or returns an error
+// and leaves the rope unchanged if the range is not within the rope.
+func (n *Node[V]) TryRemove(start, end int) error {
+	if err := checkRange(start, end, n.length); err != nil {
+		return err
+	}
+	n.Remove(start, end)
+	return nil
+}
+
+// TryInsert inserts the given value at pos, or returns an error and leaves
+// the rope unchanged if pos is out of range. The value may be inserted at
+// Len() to append it.
+func (n *Node[V]) TryInsert(pos int, value []V) error {
+	if pos < 0 || pos > n.length {
+		return fmt.Errorf("rope: insert position out of range [%d] with length %d", pos, n.length)
+	}
+	n.Insert(pos, value)
+	return nil
+}
+
+func checkIndex(pos, length int) error {
+	if pos < 0 || pos >= length {
+		return fmt.Errorf("rope: index out of range [%d] with length %d", pos, length)
+	}
+	return nil
+}
+
+func checkRange(start, end, length int) error {
+	if start < 0 || end > length || start > end {
+		return fmt.Errorf("rope: slice bounds out of range [%d:%d] with length %d", start, end, length)
+	}
+	return nil
+}
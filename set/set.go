@@ -1,3 +1,11 @@
+// Package set provides set algebra over any set backing, such as a mapset or
+// a hashset. The elements of a Set need not be comparable, as long as its
+// backing does not require it, so a hashset backing can hold, for example,
+// []byte elements.
+//
+// Earlier versions required comparable elements and had a Map method. Since a
+// method cannot add that requirement, it is now the function Map: replace
+// s.Map() with set.Map(s).
 package set
 
 import (
@@ -15,13 +23,16 @@ func NewMapset[K comparable](in ...K) Set[K] {
 	return set
 }
 
-func NewHashset[K comparable](cap uint64, equals generic.EqualsFn[K], hash generic.HashFn[K], in ...K) Set[K] {
+// NewHashset returns a set backed by a hashset. Since the hashset uses
+// 'equals' and 'hash' rather than ==, K need not be comparable, so that, for
+// example, a set of []byte can use bytes.Equal and generic.HashBytes.
+func NewHashset[K any](cap uint64, equals generic.EqualsFn[K], hash generic.HashFn[K], in ...K) Set[K] {
 	con := func() SetOf[K] { return hashset.New(cap, equals, hash) }
 	set := NewSet(con, in...)
 	return set
 }
 
-func NewSet[K comparable, S func() SetOf[K]](con S, in ...K) Set[K] {
+func NewSet[K any, S func() SetOf[K]](con S, in ...K) Set[K] {
 	set := con()
 	for _, v := range in {
 		set.Put(v)
@@ -32,7 +43,7 @@ func NewSet[K comparable, S func() SetOf[K]](con S, in ...K) Set[K] {
 	}
 }
 
-type SetOf[K comparable] interface {
+type SetOf[K any] interface {
 	Put(val K)
 	Has(val K) bool
 	Remove(val K)
@@ -41,13 +52,51 @@ type SetOf[K comparable] interface {
 	Each(fn func(key K))
 }
 
-type Set[K comparable] struct {
+// Cloner is an optional interface for backings of a Set that can copy
+// themselves more efficiently than by inserting every element into a new,
+// empty backing. Clone must return a backing of the same element type. It
+// returns an 'any' so that backings need not import this package, as hashset
+// and mapset do not.
+type Cloner interface {
+	Clone() any
+}
+
+// Set wraps a backing SetOf with set algebra. Its elements need not be
+// comparable, as long as the backing does not require it; only Map is
+// restricted to comparable elements.
+type Set[K any] struct {
 	SetOf[K]
 	new func() SetOf[K]
 }
 
+// empty returns a new, empty set with the same kind of backing as 's'.
+func (s Set[K]) empty() Set[K] {
+	return NewSet(s.new)
+}
+
+// Intersection returns a new set containing the elements that are in 's' and
+// in all of 'others'. It iterates over the smallest of the sets, so its cost is
+// proportional to the size of the smallest set.
 func (s Set[K]) Intersection(others ...SetOf[K]) Set[K] {
-	return s.Clone().InPlaceIntersection(others...)
+	smallest := s.SetOf
+	for _, other := range others {
+		if other.Size() < smallest.Size() {
+			smallest = other
+		}
+	}
+	new := s.empty()
+	smallest.Each(func(key K) {
+		if !s.Has(key) {
+			return
+		}
+		for _, other := range others {
+			if !other.Has(key) {
+				return
+			}
+		}
+		new.Put(key)
+	})
+	return new
 }
 func (s Set[K]) Difference(others ...SetOf[K]) Set[K] {
 	return s.Clone().InPlaceDifference(others...)
@@ -69,8 +118,19 @@ func (s Set[K]) ConstUnion(with ...K) Set[K] {
 	return s.Clone().InPlaceUnion(NewSet(s.new, with...))
 }
 
+// Clone returns a copy of the set. Backings that implement Cloner, such as
+// hashset and mapset, are copied directly; other backings are copied by
+// inserting every element into a new backing.
 func (s Set[K]) Clone() Set[K] {
-	new := NewSet(s.new)
+	if b, ok := s.SetOf.(Cloner); ok {
+		if clone, ok := b.Clone().(SetOf[K]); ok {
+			return Set[K]{
+				new:   s.new,
+				SetOf: clone,
+			}
+		}
+	}
+	new := s.empty()
 	s.Each(func(key K) { new.Put(key) })
 	return new
 }
@@ -82,7 +142,10 @@ func (s Set[K]) String() string {
 	return fmt.Sprintf("%v", out)
 }
 
-func (s Set[K]) Map() map[K]struct{} {
+// Map returns the elements of 's' as the keys of a Go map. Unlike the methods
+// of Set, it requires comparable elements, which is why it is a function
+// rather than the method it was in earlier versions.
+func Map[K comparable](s SetOf[K]) map[K]struct{} {
 	out := make(map[K]struct{}, s.Size())
 	s.Each(func(key K) {
 		out[key] = struct{}{}
@@ -106,13 +169,36 @@ func (s Set[K]) SymmetricDifference(others ...SetOf[K]) Set[K] {
 	return new
 }
 
+// InPlaceIntersection removes the elements of 's' that are not in all of
+// 'others'. For each other set, it iterates over whichever of the two sets is
+// smaller.
 func (s Set[K]) InPlaceIntersection(others ...SetOf[K]) Set[K] {
 	for _, other := range others {
+		if other.Size() < s.Size() {
+			// rebuild s from the elements of the smaller set that it has
+			keep := make([]K, 0, other.Size())
+			other.Each(func(key K) {
+				if s.Has(key) {
+					keep = append(keep, key)
+				}
+			})
+			s.Clear()
+			for _, key := range keep {
+				s.Put(key)
+			}
+			continue
+		}
+
+		// collect first, since not every backing allows removal during Each
+		var remove []K
 		s.Each(func(key K) {
 			if !other.Has(key) {
-				s.Remove(key)
+				remove = append(remove, key)
 			}
 		})
+		for _, key := range remove {
+			s.Remove(key)
+		}
 	}
 	return s
 }
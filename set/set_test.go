@@ -1,11 +1,14 @@
 package set
 
 import (
+	"bytes"
 	"fmt"
+	"math/rand"
 	"sort"
 	"testing"
 
 	"github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/mapset"
 )
 
 func ExampleSet_ConstUnion() {
@@ -112,3 +115,230 @@ func FuzzDifference(f *testing.F) {
 		}
 	})
 }
+
+func TestClone(t *testing.T) {
+	backings := map[string]func(in ...int) Set[int]{
+		"mapset": NewMapset[int],
+		"hashset": func(in ...int) Set[int] {
+			return NewHashset(1, generic.Equals[int], generic.HashInt, in...)
+		},
+	}
+	for name, newSet := range backings {
+		t.Run(name, func(t *testing.T) {
+			orig := newSet(1, 2, 3)
+			clone := orig.Clone()
+			if fmt.Sprintf("%T", clone.SetOf) != fmt.Sprintf("%T", orig.SetOf) {
+				t.Fatalf("clone has backing %T, expected %T", clone.SetOf, orig.SetOf)
+			}
+
+			clone.Put(4)
+			clone.Remove(1)
+			if got := orig.String(); got != "[1 2 3]" {
+				t.Fatalf("original modified by clone: %s", got)
+			}
+			if got := clone.String(); got != "[2 3 4]" {
+				t.Fatalf("clone mismatch: %s", got)
+			}
+
+			orig.Put(5)
+			clone.Clear()
+			if got := orig.String(); got != "[1 2 3 5]" {
+				t.Fatalf("original modified by clone: %s", got)
+			}
+			if clone.Size() != 0 {
+				t.Fatalf("clone not cleared: %s", clone)
+			}
+		})
+	}
+}
+
+// listSet is a backing without a native clone. It embeds the interface
+// rather than the mapset, which would make it a Cloner.
+type listSet struct {
+	SetOf[int]
+}
+
+func benchmarkClone(b *testing.B, s Set[int]) {
+	const n = 1000000
+	for i := 0; i < n; i++ {
+		s.Put(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Clone()
+	}
+}
+
+func BenchmarkClone(b *testing.B) {
+	b.Run("mapset", func(b *testing.B) {
+		benchmarkClone(b, NewMapset[int]())
+	})
+	b.Run("hashset", func(b *testing.B) {
+		benchmarkClone(b, NewHashset(1, generic.Equals[int], generic.HashInt))
+	})
+	b.Run("fallback", func(b *testing.B) {
+		benchmarkClone(b, NewSet(func() SetOf[int] {
+			return listSet{mapset.New[int]()}
+		}))
+	})
+}
+
+func TestIntersection(t *testing.T) {
+	backings := map[string]func(in ...int) Set[int]{
+		"mapset": NewMapset[int],
+		"hashset": func(in ...int) Set[int] {
+			return NewHashset(1, generic.Equals[int], generic.HashInt, in...)
+		},
+	}
+	random := func(n int) []int {
+		vals := make([]int, n)
+		for i := range vals {
+			vals[i] = rand.Intn(200)
+		}
+		return vals
+	}
+
+	for name, newSet := range backings {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				a, b, c := random(rand.Intn(150)), random(rand.Intn(150)), random(rand.Intn(20))
+				want := NewMapset[int]()
+				for _, v := range a {
+					if NewMapset(b...).Has(v) && NewMapset(c...).Has(v) {
+						want.Put(v)
+					}
+				}
+
+				got := newSet(a...).Intersection(newSet(b...), newSet(c...))
+				if got.String() != want.String() {
+					t.Fatalf("Intersection = %s, want %s", got, want)
+				}
+				got = newSet(a...).InPlaceIntersection(newSet(b...), newSet(c...))
+				if got.String() != want.String() {
+					t.Fatalf("InPlaceIntersection = %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkIntersection(b *testing.B) {
+	const n = 1000000
+	small := NewMapset[int]()
+	big := NewMapset[int]()
+	for i := 0; i < 10; i++ {
+		small.Put(i * 1000)
+	}
+	for i := 0; i < n; i++ {
+		big.Put(i)
+	}
+
+	b.Run("small-big", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			small.Intersection(big)
+		}
+	})
+	b.Run("big-small", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			big.Intersection(small)
+		}
+	})
+}
+
+func TestBytes(t *testing.T) {
+	newSet := func(in ...string) Set[[]byte] {
+		s := NewHashset(1, bytes.Equal, generic.HashBytes)
+		for _, v := range in {
+			s.Put([]byte(v))
+		}
+		return s
+	}
+
+	a := newSet("a", "b", "c")
+	b := newSet("b", "c", "d")
+	if !a.Has([]byte("a")) || a.Has([]byte("d")) {
+		t.Fatal("wrong membership")
+	}
+
+	// String sorts the formatted elements as strings, so [100] comes first
+	cases := []struct {
+		name string
+		got  Set[[]byte]
+		want string
+	}{
+		{"Union", a.Union(b), "[[100] [97] [98] [99]]"},
+		{"Intersection", a.Intersection(b), "[[98] [99]]"},
+		{"Difference", a.Difference(b), "[[97]]"},
+		{"SymmetricDifference", a.SymmetricDifference(b), "[[100] [97]]"},
+		{"ConstUnion", a.ConstUnion([]byte("e")), "[[101] [97] [98] [99]]"},
+		{"Clone", a.Clone(), "[[97] [98] [99]]"},
+	}
+	for _, c := range cases {
+		if got := c.got.String(); got != c.want {
+			t.Errorf("%s = %s, want %s", c.name, got, c.want)
+		}
+	}
+	if a.String() != "[[97] [98] [99]]" || b.String() != "[[100] [98] [99]]" {
+		t.Fatalf("operands modified: %s, %s", a, b)
+	}
+
+	if !a.IsSuperset(newSet("a", "c")) || !newSet("a").IsProperSubset(a) {
+		t.Fatal("wrong subset relation")
+	}
+	if !a.Equal(newSet("c", "b", "a")) || a.Equal(b) {
+		t.Fatal("wrong equality")
+	}
+
+	a.Remove([]byte("a"))
+	if a.Has([]byte("a")) || a.Size() != 2 {
+		t.Fatal("element not removed")
+	}
+}
+
+func TestMap(t *testing.T) {
+	m := Map[int](NewMapset(1, 2, 3))
+	if len(m) != 3 {
+		t.Fatalf("len %d, want 3", len(m))
+	}
+	for i := 1; i <= 3; i++ {
+		if _, ok := m[i]; !ok {
+			t.Fatalf("%d missing from map", i)
+		}
+	}
+}
+
+// countingSet is a backing that counts the calls to its Clone method.
+type countingSet struct {
+	mapset.Set[int]
+	clones *int
+}
+
+func (s countingSet) Clone() any {
+	*s.clones++
+	return countingSet{s.Set.Copy(), s.clones}
+}
+
+func TestCloneDerived(t *testing.T) {
+	// sets derived from a mapset set keep its native clone
+	s := NewMapset(1, 2, 3).Intersection(NewMapset(2, 3))
+	if got := fmt.Sprintf("%T", s.Clone().SetOf); got != "mapset.Set[int]" {
+		t.Fatalf("clone has backing %s", got)
+	}
+
+	var clones int
+	c := NewSet(func() SetOf[int] {
+		return countingSet{mapset.New[int](), &clones}
+	}, 1, 2, 3)
+	clone := c.Clone()
+	clone.Put(4)
+	if clones != 1 || c.String() != "[1 2 3]" || clone.String() != "[1 2 3 4]" {
+		t.Fatalf("Cloner called %d times, giving %s from %s", clones, clone, c)
+	}
+
+	fallback := NewSet(func() SetOf[int] {
+		return listSet{mapset.New[int]()}
+	}, 1, 2)
+	if got := fmt.Sprintf("%T", fallback.Clone().SetOf); got != "set.listSet" {
+		t.Fatalf("clone has backing %s", got)
+	}
+}
@@ -0,0 +1,109 @@
+// Package sketch provides a count-min sketch, which estimates how often each
+// key has been seen using a fixed amount of memory, no matter how many distinct
+// keys there are. Estimates are never lower than the true count, and are
+// higher by at most a small fraction of the total count with high
+// probability. The counters decay by halving periodically, so that the
+// estimates track recent frequency rather than all-time frequency, as in the
+// TinyLFU cache admission policy.
+package sketch
+
+import (
+	"math"
+
+	g "github.com/zyedidia/generic"
+)
+
+// depth is the number of rows of counters. Each key is counted in one counter
+// per row, and its estimate is the minimum of those counters.
+const depth = 4
+
+// A CountMin is a count-min sketch of keys.
+type CountMin[K any] struct {
+	rows [depth][]uint32
+	mask uint64
+	hash g.HashFn[K]
+
+	// adds counts the calls to Add since the last Reset, which happens
+	// automatically once it reaches period.
+	adds   int
+	period int
+}
+
+// New returns a new count-min sketch with 'width' counters per row, rounded up
+// to a power of two. With 'n' keys added since the last decay, an estimate is
+// within e*n/width of the true count with probability at least 1-e^-4 (about
+// 98%). Every 10*width calls to Add, the counters are halved with Reset. The
+// hash function should mix its input well, like the hash functions in the
+// generic package.
+func New[K any](width int, hash g.HashFn[K]) *CountMin[K] {
+	w := 1
+	for w < width {
+		w *= 2
+	}
+	s := &CountMin[K]{
+		mask:   uint64(w - 1),
+		hash:   hash,
+		period: 10 * w,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint32, w)
+	}
+	return s
+}
+
+// index returns the counter for the key with hash 'h' in row 'i'. The indices
+// of each row are derived from the two halves of the hash.
+func (s *CountMin[K]) index(h uint64, i int) uint64 {
+	return (h + uint64(i)*(h>>32|1)) & s.mask
+}
+
+// Add records an occurrence of 'k'.
+func (s *CountMin[K]) Add(k K) {
+	h := s.hash(k)
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < math.MaxUint32 {
+			*c++
+		}
+	}
+	s.adds++
+	if s.adds >= s.period {
+		s.Reset()
+	}
+}
+
+// Estimate returns an estimate of the number of occurrences of 'k' since the
+// sketch was created or cleared, accounting for decay.
+func (s *CountMin[K]) Estimate(k K) uint32 {
+	h := s.hash(k)
+	est := uint32(math.MaxUint32)
+	for i := range s.rows {
+		est = g.Min(est, s.rows[i][s.index(h, i)])
+	}
+	return est
+}
+
+// Reset halves every counter, so that older occurrences count for half as
+// much as newer ones. It is called automatically every 10*width additions.
+func (s *CountMin[K]) Reset() {
+	for _, row := range s.rows {
+		for j := range row {
+			row[j] /= 2
+		}
+	}
+	s.adds = 0
+}
+
+// Clear sets every counter to zero.
+func (s *CountMin[K]) Clear() {
+	for _, row := range s.rows {
+		for j := range row {
+			row[j] = 0
+		}
+	}
+	s.adds = 0
+}
+
+// Width returns the number of counters per row.
+func (s *CountMin[K]) Width() int {
+	return int(s.mask + 1)
+}
@@ -0,0 +1,95 @@
+package sketch_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/sketch"
+)
+
+func TestErrorBound(t *testing.T) {
+	const width = 1024
+	s := sketch.New[int](width, g.HashInt)
+	if s.Width() != width {
+		t.Fatalf("width %d, want %d", s.Width(), width)
+	}
+
+	// stay below the decay period, so that the counts are exact
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.2, 1, 5000)
+	const n = 9 * width
+	counts := make(map[int]uint32)
+	for i := 0; i < n; i++ {
+		k := int(zipf.Uint64())
+		counts[k]++
+		s.Add(k)
+	}
+
+	// count-min estimates are within e*n/width of the true count
+	bound := uint32(math.Ceil(math.E * n / width))
+	over := 0
+	for k := 0; k <= 5000; k++ {
+		est := s.Estimate(k)
+		if est < counts[k] {
+			t.Fatalf("Estimate(%d) = %d, below the true count %d", k, est, counts[k])
+		}
+		if est > counts[k]+bound {
+			over++
+		}
+	}
+	// each estimate exceeds the bound with probability at most e^-4
+	if limit := int(5001 * math.Exp(-4)); over > limit {
+		t.Fatalf("%d estimates exceed the error bound, want at most %d", over, limit)
+	}
+}
+
+func TestDecay(t *testing.T) {
+	s := sketch.New[int](100, g.HashInt)
+	if s.Width() != 128 {
+		t.Fatalf("width %d, want 128", s.Width())
+	}
+
+	for i := 0; i < 64; i++ {
+		s.Add(1)
+	}
+	if est := s.Estimate(1); est != 64 {
+		t.Fatalf("Estimate(1) = %d, want 64", est)
+	}
+	s.Reset()
+	if est := s.Estimate(1); est != 32 {
+		t.Fatalf("after Reset, Estimate(1) = %d, want 32", est)
+	}
+
+	// the counters halve automatically every 10*width additions
+	for i := 0; i < 10*128-1; i++ {
+		s.Add(2)
+	}
+	if est := s.Estimate(2); est != 10*128-1 {
+		t.Fatalf("Estimate(2) = %d, want %d", est, 10*128-1)
+	}
+	s.Add(2)
+	if est := s.Estimate(2); est != 10*128/2 {
+		t.Fatalf("after decay, Estimate(2) = %d, want %d", est, 10*128/2)
+	}
+	if est := s.Estimate(1); est != 16 {
+		t.Fatalf("after decay, Estimate(1) = %d, want 16", est)
+	}
+
+	s.Clear()
+	if s.Estimate(1) != 0 || s.Estimate(2) != 0 {
+		t.Fatal("Clear did not zero the counters")
+	}
+}
+
+func Example() {
+	s := sketch.New[string](64, g.HashString)
+	for _, w := range []string{"a", "b", "a", "c", "a", "b"} {
+		s.Add(w)
+	}
+	fmt.Println(s.Estimate("a"), s.Estimate("b"), s.Estimate("c"))
+	// Output:
+	// 3 2 1
+}
@@ -0,0 +1,72 @@
+// Package swapmap provides a hashmap for read-mostly workloads, where a writer
+// periodically builds a new version of the map and readers must always see a
+// complete version. Each version is published atomically, and readers get a
+// copy-on-write snapshot of the current version, so readers never block and
+// never observe a partial update.
+package swapmap
+
+import (
+	"sync"
+	"sync/atomic"
+
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/hashmap"
+)
+
+type version[K, V any] struct {
+	m *hashmap.Map[K, V]
+	n uint64
+}
+
+// A Map holds the published version of a hashmap.
+type Map[K, V any] struct {
+	// cur holds a *version[K, V]. The map in it is never modified once it
+	// is published, and is read-only so that copying it does not write to
+	// it.
+	cur atomic.Value
+	// mu serializes publishers.
+	mu sync.Mutex
+}
+
+// New returns a swapmap whose initial version is an empty map with the given
+// capacity.
+func New[K, V any](capacity uint64, equals g.EqualsFn[K], hash g.HashFn[K]) *Map[K, V] {
+	s := &Map[K, V]{}
+	s.cur.Store(&version[K, V]{
+		m: hashmap.New[K, V](capacity, equals, hash).Copy(),
+	})
+	return s
+}
+
+func (s *Map[K, V]) load() *version[K, V] {
+	return s.cur.Load().(*version[K, V])
+}
+
+// Reader returns a snapshot of the current version of the map. The snapshot is
+// a copy-on-write copy, so it is cheap to take and later publishes do not
+// affect it. Writes to the snapshot are private to the caller.
+func (s *Map[K, V]) Reader() *hashmap.Map[K, V] {
+	return s.load().m.Copy()
+}
+
+// Version returns the number of times the map has been published.
+func (s *Map[K, V]) Version() uint64 {
+	return s.load().n
+}
+
+// Publish calls 'build' on a copy of the current version of the map and then
+// publishes the result as the new version. Readers continue to see the
+// previous version until 'build' returns. Concurrent calls to Publish are
+// serialized, so no updates are lost.
+func (s *Map[K, V]) Publish(build func(m *hashmap.Map[K, V])) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur := s.load()
+	next := cur.m.Copy()
+	build(next)
+	s.cur.Store(&version[K, V]{
+		m: next.Copy(),
+		n: cur.n + 1,
+	})
+}
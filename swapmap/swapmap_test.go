@@ -0,0 +1,137 @@
+package swapmap_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/hashmap"
+	"github.com/zyedidia/generic/swapmap"
+)
+
+const nkeys = 64
+
+// publish sets every key to 'v', so that a complete version has the same
+// value for all keys.
+func publish(s *swapmap.Map[int, int], v int) {
+	s.Publish(func(m *hashmap.Map[int, int]) {
+		for k := 0; k < nkeys; k++ {
+			m.Put(k, v)
+		}
+	})
+}
+
+func TestConsistentSnapshots(t *testing.T) {
+	s := swapmap.New[int, int](1, g.Equals[int], g.HashInt)
+	publish(s, 0)
+
+	const npublish = 200
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := 0
+			for last < npublish {
+				m := s.Reader()
+				if m.Size() != nkeys {
+					t.Errorf("snapshot has %d keys, want %d", m.Size(), nkeys)
+					return
+				}
+				v, _ := m.Get(0)
+				for k := 1; k < nkeys; k++ {
+					if kv, _ := m.Get(k); kv != v {
+						t.Errorf("partial update: key 0 = %d, key %d = %d", v, k, kv)
+						return
+					}
+				}
+				if v < last {
+					t.Errorf("went back from version %d to %d", last, v)
+					return
+				}
+				last = v
+				// writes to a snapshot are private
+				m.Put(0, -1)
+			}
+		}()
+	}
+
+	for v := 1; v <= npublish; v++ {
+		publish(s, v)
+	}
+	wg.Wait()
+
+	if s.Version() != npublish+1 {
+		t.Fatalf("version %d, want %d", s.Version(), npublish+1)
+	}
+	if v, _ := s.Reader().Get(0); v != npublish {
+		t.Fatalf("reader saw a private write: %d", v)
+	}
+}
+
+func TestConcurrentPublish(t *testing.T) {
+	s := swapmap.New[int, int](1, g.Equals[int], g.HashInt)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				s.Publish(func(m *hashmap.Map[int, int]) {
+					m.Put(w*50+i, i)
+				})
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if s.Version() != 400 || s.Reader().Size() != 400 {
+		t.Fatalf("lost updates: version %d, size %d", s.Version(), s.Reader().Size())
+	}
+}
+
+func BenchmarkReadDuringPublish(b *testing.B) {
+	s := swapmap.New[int, int](1, g.Equals[int], g.HashInt)
+	publish(s, 0)
+
+	done := make(chan struct{})
+	go func() {
+		for v := 1; ; v++ {
+			select {
+			case <-done:
+				return
+			default:
+				publish(s, v)
+			}
+		}
+	}()
+
+	b.RunParallel(func(pb *testing.PB) {
+		k := 0
+		for pb.Next() {
+			s.Reader().Get(k % nkeys)
+			k++
+		}
+	})
+	close(done)
+}
+
+func Example() {
+	config := swapmap.New[string, string](8, g.Equals[string], g.HashString)
+	config.Publish(func(m *hashmap.Map[string, string]) {
+		m.Put("host", "localhost")
+		m.Put("port", "8080")
+	})
+
+	r := config.Reader()
+	config.Publish(func(m *hashmap.Map[string, string]) {
+		m.Put("port", "9090")
+	})
+
+	old, _ := r.Get("port")
+	cur, _ := config.Reader().Get("port")
+	fmt.Println(old, cur, config.Version())
+	// Output: 8080 9090 2
+}
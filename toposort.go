@@ -0,0 +1,73 @@
+package generic
+
+import "container/heap"
+
+// TopoSort returns the nodes of a directed graph in topological order, so that
+// for every edge from a to b, a comes before b. The graph has the given
+// nodes, and edges[a] lists the nodes that a has edges to; edges from or to
+// nodes that are not in 'nodes' are ignored. Among nodes that could come next,
+// the one that comes first in 'nodes' is chosen, so the order of 'nodes' is
+// preserved wherever the edges allow. If the graph has a cycle, there is no
+// topological order, and TopoSort returns the nodes it could order and false.
+// Complexity: O((V + E) lg V).
+func TopoSort[T comparable](nodes []T, edges map[T][]T) ([]T, bool) {
+	// Kahn's algorithm: repeatedly output a node with no incoming edges
+	// from nodes not yet output, taking the earliest in 'nodes' first.
+	index := make(map[T]int, len(nodes))
+	for i, n := range nodes {
+		if _, ok := index[n]; !ok {
+			index[n] = i
+		}
+	}
+	indegree := make(map[T]int, len(index))
+	for n := range index {
+		for _, m := range edges[n] {
+			if _, ok := index[m]; ok {
+				indegree[m]++
+			}
+		}
+	}
+
+	// ready holds the indices in 'nodes' of the nodes with no remaining
+	// incoming edges; it starts out sorted, so it is already a heap
+	var ready indexHeap
+	for i, n := range nodes {
+		if index[n] == i && indegree[n] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	order := make([]T, 0, len(index))
+	for len(ready) > 0 {
+		n := nodes[heap.Pop(&ready).(int)]
+		order = append(order, n)
+		for _, m := range edges[n] {
+			i, ok := index[m]
+			if !ok {
+				continue
+			}
+			indegree[m]--
+			if indegree[m] == 0 {
+				heap.Push(&ready, i)
+			}
+		}
+	}
+	return order, len(order) == len(index)
+}
+
+// indexHeap is a min-heap of indices, for use with container/heap.
+type indexHeap []int
+
+func (h indexHeap) Len() int           { return len(h) }
+func (h indexHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h indexHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *indexHeap) Push(x any) {
+	*h = append(*h, x.(int))
+}
+
+func (h *indexHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
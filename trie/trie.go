@@ -9,6 +9,10 @@ package trie
 type Trie[V any] struct {
 	n    int
 	root *node[V]
+
+	// free is a list of zeroed nodes, linked through their mid pointers,
+	// that Put uses before allocating new ones.
+	free *node[V]
 }
 
 type node[V any] struct {
@@ -88,24 +92,37 @@ func (t *Trie[V]) Get(key string) (v V, ok bool) {
 	if len(key) == 0 {
 		return v, false
 	}
-	x := t.get(t.root, key, 0)
+	x := get(t.root, key, 0)
+	if x == nil || !x.valid {
+		return v, false
+	}
+	return x.val, true
+}
+
+// GetBytes is like Get, but takes the key as a byte slice, for example a token
+// produced by a parser, without converting it to a string.
+func (t *Trie[V]) GetBytes(key []byte) (v V, ok bool) {
+	if len(key) == 0 {
+		return v, false
+	}
+	x := get(t.root, key, 0)
 	if x == nil || !x.valid {
 		return v, false
 	}
 	return x.val, true
 }
 
-func (t *Trie[V]) get(x *node[V], key string, d int) *node[V] {
+func get[V any, S string | []byte](x *node[V], key S, d int) *node[V] {
 	if x == nil || len(key) == 0 {
 		return nil
 	}
 	c := key[d]
 	if c < x.c {
-		return t.get(x.left, key, d)
+		return get(x.left, key, d)
 	} else if c > x.c {
-		return t.get(x.right, key, d)
+		return get(x.right, key, d)
 	} else if d < len(key)-1 {
-		return t.get(x.mid, key, d+1)
+		return get(x.mid, key, d+1)
 	} else {
 		return x
 	}
@@ -125,9 +142,7 @@ func (t *Trie[V]) Put(key string, val V) {
 func (t *Trie[V]) put(x *node[V], key string, val V, d int) *node[V] {
 	c := key[d]
 	if x == nil {
-		x = &node[V]{
-			c: c,
-		}
+		x = t.newNode(c)
 	}
 	if c < x.c {
 		x.left = t.put(x.left, key, val, d)
@@ -142,6 +157,53 @@ func (t *Trie[V]) put(x *node[V], key string, val V, d int) *node[V] {
 	return x
 }
 
+func (t *Trie[V]) newNode(c byte) *node[V] {
+	x := t.free
+	if x == nil {
+		return &node[V]{
+			c: c,
+		}
+	}
+	t.free = x.mid
+	x.mid = nil
+	x.c = c
+	return x
+}
+
+// Clear removes all keys from the trie, and releases any nodes kept by Reuse.
+func (t *Trie[V]) Clear() {
+	t.n = 0
+	t.root = nil
+	t.free = nil
+}
+
+// Reuse removes all keys from the trie, but keeps its nodes so that later
+// calls to Put can use them instead of allocating. This makes it cheap to
+// build, discard and rebuild a trie repeatedly, for example once per request.
+// The values stored in the nodes are cleared, so they can be garbage
+// collected, but the nodes themselves are held until they are reused or Clear
+// is called. Complexity: O(m), where 'm' is the number of nodes.
+func (t *Trie[V]) Reuse() {
+	t.recycle(t.root)
+	t.n = 0
+	t.root = nil
+}
+
+// recycle zeroes every node in the subtree rooted at 'x' and adds it to the
+// free list.
+func (t *Trie[V]) recycle(x *node[V]) {
+	if x == nil {
+		return
+	}
+	t.recycle(x.left)
+	t.recycle(x.mid)
+	t.recycle(x.right)
+	*x = node[V]{
+		mid: t.free,
+	}
+	t.free = x
+}
+
 // Remove removes the value associated with 'key', along with any nodes of the key that are no
 // longer used.
 func (t *Trie[V]) Remove(key string) {
@@ -178,6 +240,21 @@ func (t *Trie[V]) remove(x *node[V], key string, d int) *node[V] {
 	return x
 }
 
+// Merge inserts all keys of 'other' into 't'. If a key exists in both tries,
+// the stored value becomes combine(a, b), where 'a' is the value in 't' and
+// 'b' is the value in 'other'. If 'combine' is nil, the value from 'other'
+// overwrites the value in 't'.
+func (t *Trie[V]) Merge(other *Trie[V], combine func(a, b V) V) {
+	other.each(other.root, nil, func(key string, val V) {
+		if combine != nil {
+			if old, ok := t.Get(key); ok {
+				val = combine(old, val)
+			}
+		}
+		t.Put(key, val)
+	})
+}
+
 // LongestPrefix returns the key that is the longest prefix of 'query'.
 func (t *Trie[V]) LongestPrefix(query string) string {
 	if len(query) == 0 {
@@ -213,7 +290,7 @@ func (t *Trie[V]) KeysWithPrefix(prefix string) (queue []string) {
 	if len(prefix) == 0 {
 		return t.Keys()
 	}
-	x := t.get(t.root, prefix, 0)
+	x := get(t.root, prefix, 0)
 	if x == nil {
 		return nil
 	}
@@ -234,3 +311,60 @@ func (t *Trie[V]) collect(x *node[V], prefix []byte, queue []string) []string {
 	queue = t.collect(x.mid, append(prefix, x.c), queue)
 	return t.collect(x.right, prefix, queue)
 }
+
+// each calls 'fn' on every key-value pair in the subtree rooted at 'x', in
+// sorted order.
+func (t *Trie[V]) each(x *node[V], prefix []byte, fn func(key string, val V)) {
+	if x == nil {
+		return
+	}
+	t.each(x.left, prefix, fn)
+	if x.valid {
+		fn(string(append(prefix, x.c)), x.val)
+	}
+	t.each(x.mid, append(prefix, x.c), fn)
+	t.each(x.right, prefix, fn)
+}
+
+// DepthHistogram returns, for each depth d, the number of keys that end at
+// depth d, which is the number of keys of length d. The slice is as long as
+// the longest key plus one, so index 0 is always 0; it is nil for an empty
+// trie.
+func (t *Trie[V]) DepthHistogram() []int {
+	var hist []int
+	t.depths(t.root, 1, func(depth int) {
+		for len(hist) <= depth {
+			hist = append(hist, 0)
+		}
+		hist[depth]++
+	})
+	return hist
+}
+
+// AverageDepth returns the average depth at which keys end, which is their
+// average length, or 0 for an empty trie.
+func (t *Trie[V]) AverageDepth() float64 {
+	total, n := 0, 0
+	t.depths(t.root, 1, func(depth int) {
+		total += depth
+		n++
+	})
+	if n == 0 {
+		return 0
+	}
+	return float64(total) / float64(n)
+}
+
+// depths calls 'fn' with the depth of every key in the subtree rooted at 'x',
+// whose characters are at depth 'd'.
+func (t *Trie[V]) depths(x *node[V], d int, fn func(depth int)) {
+	if x == nil {
+		return
+	}
+	t.depths(x.left, d, fn)
+	if x.valid {
+		fn(d)
+	}
+	t.depths(x.mid, d+1, fn)
+	t.depths(x.right, d, fn)
+}
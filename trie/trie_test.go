@@ -3,8 +3,10 @@ package trie_test
 import (
 	"fmt"
 	"math/rand"
+	"runtime"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/zyedidia/generic/trie"
 )
@@ -80,6 +82,160 @@ func TestKeys(t *testing.T) {
 	}
 }
 
+func TestMerge(t *testing.T) {
+	t1 := trie.New[int]()
+	t1.Put("foo", 1)
+	t1.Put("foobar", 2)
+	t1.Put("baz", 3)
+
+	t2 := trie.New[int]()
+	t2.Put("foo", 10)
+	t2.Put("fo", 20)
+	t2.Put("quux", 30)
+
+	t1.Merge(t2, func(a, b int) int {
+		return a + b
+	})
+
+	expected := map[string]int{
+		"foo":    11,
+		"foobar": 2,
+		"baz":    3,
+		"fo":     20,
+		"quux":   30,
+	}
+	if t1.Size() != len(expected) {
+		t.Fatalf("size mismatch: %d != %d", t1.Size(), len(expected))
+	}
+	checkeq(t1, expected, t)
+
+	// 'other' is left unchanged
+	checkeq(t2, map[string]int{"foo": 10, "fo": 20, "quux": 30}, t)
+}
+
+func TestMergeRandom(t *testing.T) {
+	stdm := make(map[string]int)
+	t1 := trie.New[int]()
+	t2 := trie.New[int]()
+	for i := 0; i < 500; i++ {
+		key := randstring(rand.Intn(5) + 1)
+		if rand.Intn(2) == 0 {
+			t1.Put(key, 1)
+		} else {
+			t2.Put(key, 2)
+		}
+	}
+	for _, k := range t1.Keys() {
+		v, _ := t1.Get(k)
+		stdm[k] = v
+	}
+	for _, k := range t2.Keys() {
+		v, _ := t2.Get(k)
+		stdm[k] = v
+	}
+
+	t1.Merge(t2, nil)
+	if t1.Size() != len(stdm) {
+		t.Fatalf("size mismatch: %d != %d", t1.Size(), len(stdm))
+	}
+	checkeq(t1, stdm, t)
+}
+
+func TestReuse(t *testing.T) {
+	tr := trie.New[int]()
+	for round := 0; round < 5; round++ {
+		fresh := trie.New[int]()
+		stdm := make(map[string]int)
+		for i := 0; i < 500; i++ {
+			key := randstring(rand.Intn(8) + 1)
+			stdm[key] = i
+			tr.Put(key, i)
+			fresh.Put(key, i)
+		}
+		for k := range stdm {
+			if rand.Intn(4) == 0 {
+				delete(stdm, k)
+				tr.Remove(k)
+				fresh.Remove(k)
+			}
+		}
+
+		checkeq(tr, stdm, t)
+		if tr.Size() != fresh.Size() || fmt.Sprint(tr.Keys()) != fmt.Sprint(fresh.Keys()) {
+			t.Fatalf("round %d: reused trie differs from a fresh one", round)
+		}
+		for i := 0; i < 100; i++ {
+			q := randstring(10)
+			if a, b := tr.LongestPrefix(q), fresh.LongestPrefix(q); a != b {
+				t.Fatalf("LongestPrefix(%q) = %q, want %q", q, a, b)
+			}
+		}
+		tr.Reuse()
+		if tr.Size() != 0 || len(tr.Keys()) != 0 {
+			t.Fatal("trie not empty after Reuse")
+		}
+	}
+	tr.Clear()
+	if tr.Size() != 0 || tr.Contains("a") {
+		t.Fatal("trie not empty after Clear")
+	}
+}
+
+func TestReuseReleasesValues(t *testing.T) {
+	tr := trie.New[*[1024]byte]()
+	finalized := make(chan bool, 1)
+	v := new([1024]byte)
+	runtime.SetFinalizer(v, func(*[1024]byte) { finalized <- true })
+	tr.Put("key", v)
+	v = nil
+	tr.Reuse()
+
+	runtime.GC()
+	select {
+	case <-finalized:
+	case <-time.After(time.Second):
+		t.Fatal("value still referenced after Reuse")
+	}
+	runtime.KeepAlive(tr)
+}
+
+func BenchmarkRebuild(b *testing.B) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = randstring(rand.Intn(10) + 1)
+	}
+	run := func(b *testing.B, reset func(tr *trie.Trie[int])) {
+		b.ReportAllocs()
+		tr := trie.New[int]()
+		for i := 0; i < b.N; i++ {
+			for j, k := range keys {
+				tr.Put(k, j)
+			}
+			reset(tr)
+		}
+	}
+	b.Run("Clear", func(b *testing.B) { run(b, (*trie.Trie[int]).Clear) })
+	b.Run("Reuse", func(b *testing.B) { run(b, (*trie.Trie[int]).Reuse) })
+}
+
+func TestDepthHistogram(t *testing.T) {
+	tr := trie.New[int]()
+	if tr.DepthHistogram() != nil || tr.AverageDepth() != 0 {
+		t.Fatal("empty trie has depths")
+	}
+	for i, k := range []string{"a", "ab", "abc", "abd", "b", "bcde", "xyzw", "abcd"} {
+		tr.Put(k, i)
+	}
+	tr.Remove("ab")
+
+	if got, want := tr.DepthHistogram(), []int{0, 2, 0, 2, 3}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("histogram = %v, want %v", got, want)
+	}
+	if got, want := tr.AverageDepth(), 20.0/7; got != want {
+		t.Fatalf("average depth = %v, want %v", got, want)
+	}
+}
+
 func Example() {
 	tr := trie.New[int]()
 	tr.Put("f§oo", 1)
@@ -94,3 +250,26 @@ func Example() {
 	// [bar f§o f§oo]
 	// [f§o f§oo]
 }
+
+func TestGetBytes(t *testing.T) {
+	tr := trie.New[int]()
+	keys := []string{"a", "ab", "abc", "\xff\xfe", "\xff", "b"}
+	for i, k := range keys {
+		tr.Put(k, i)
+	}
+	for i, k := range keys {
+		if v, ok := tr.GetBytes([]byte(k)); !ok || v != i {
+			t.Errorf("GetBytes(%q) = %d, %v, want %d", k, v, ok, i)
+		}
+	}
+	for _, k := range []string{"", "abcd", "ac", "\xfe", "\xff\xfe\x00"} {
+		if v, ok := tr.GetBytes([]byte(k)); ok {
+			t.Errorf("GetBytes(%q) = %d for an absent key", k, v)
+		}
+	}
+
+	key := []byte("abc")
+	if allocs := testing.AllocsPerRun(100, func() { tr.GetBytes(key) }); allocs != 0 {
+		t.Errorf("GetBytes made %v allocations", allocs)
+	}
+}
@@ -1,6 +1,9 @@
 package ulist
 
 import (
+	"unsafe"
+
+	g "github.com/zyedidia/generic"
 	"github.com/zyedidia/generic/list"
 )
 
@@ -16,11 +19,18 @@ import (
 // Type alias for a block of entries.
 type ulistBlk[V any] []V
 
+// defaultSparePool is the default number of emptied blocks kept for reuse.
+const defaultSparePool = 2
+
 // UList implements a doubly-linked unolled list.
 type UList[V any] struct {
 	ll              list.List[ulistBlk[V]]
 	entriesPerBlock int
 	size            int
+
+	// spare holds emptied blocks that are reused before allocating new ones.
+	spare    []ulistBlk[V]
+	maxSpare int
 }
 
 // New returns an empty unrolled linked list.
@@ -32,6 +42,24 @@ func New[V any](entriesPerBlock int) *UList[V] {
 		ll:              *list.New[ulistBlk[V]](),
 		entriesPerBlock: entriesPerBlock,
 		size:            0,
+		maxSpare:        defaultSparePool,
+	}
+}
+
+// SetSparePool sets the maximum number of emptied blocks that 'ul' keeps
+// around for reuse. Keeping spare blocks avoids allocating a new block each
+// time one fills up in queue-like workloads, at the cost of holding on to up
+// to 'n' unused blocks. A value of 0 disables reuse.
+func (ul *UList[V]) SetSparePool(n int) {
+	if n < 0 {
+		n = 0
+	}
+	ul.maxSpare = n
+	if len(ul.spare) > n {
+		for i := n; i < len(ul.spare); i++ {
+			ul.spare[i] = nil
+		}
+		ul.spare = ul.spare[:n]
 	}
 }
 
@@ -120,15 +148,87 @@ func (ul *UList[V]) AddBefore(iter *UListIter[V], v V) {
 // that occurs after the deleted entry.
 func (ul *UList[V]) Remove(iter *UListIter[V]) {
 	ul.size--
-	iter.node.Value = append(iter.node.Value[:iter.index], iter.node.Value[iter.index+1:]...)
+	blk := iter.node.Value
+	copy(blk[iter.index:], blk[iter.index+1:])
+	// Zero the vacated slot so the block does not retain a reference to it.
+	var zero V
+	blk[len(blk)-1] = zero
+	iter.node.Value = blk[:len(blk)-1]
 	if len(iter.node.Value) == 0 {
 		// Block got emptied.
 		ul.ll.Remove(iter.node)
+		iter.node.Value = nil
+		ul.recycleBlock(blk)
 		iter.Next()
 		return
 	}
 }
 
+// ToSlice returns a new slice containing the entries of 'ul' in order.
+func (ul *UList[V]) ToSlice() []V {
+	s := make([]V, 0, ul.size)
+	for n := ul.ll.Front; n != nil; n = n.Next {
+		s = append(s, n.Value...)
+	}
+	return s
+}
+
+// Equal returns true if 'ul' and 'other' have the same entries in the same
+// order, comparing entries with 'eq'. The way the entries are divided into
+// blocks does not matter.
+func (ul *UList[V]) Equal(other *UList[V], eq g.EqualsFn[V]) bool {
+	if ul.size != other.size {
+		return false
+	}
+	a, b := ul.ll.Front, other.ll.Front
+	i, j := 0, 0
+	for {
+		// skip past exhausted (or empty) blocks
+		for a != nil && i == len(a.Value) {
+			a, i = a.Next, 0
+		}
+		for b != nil && j == len(b.Value) {
+			b, j = b.Next, 0
+		}
+		if a == nil || b == nil {
+			return a == nil && b == nil
+		}
+		if !eq(a.Value[i], b.Value[j]) {
+			return false
+		}
+		i++
+		j++
+	}
+}
+
+// Memory returns an estimate of the number of bytes used by the list: its
+// blocks, including spare blocks kept for reuse. Memory referenced by pointers
+// in the elements is not counted; use MemoryFunc to include it.
+func (ul *UList[V]) Memory() int64 {
+	var v V
+	blockSize := int64(ul.entriesPerBlock) * int64(unsafe.Sizeof(v))
+	nodeSize := int64(unsafe.Sizeof(list.Node[ulistBlk[V]]{}))
+
+	total := int64(unsafe.Sizeof(*ul)) + int64(cap(ul.spare))*int64(unsafe.Sizeof(ulistBlk[V]{}))
+	total += int64(len(ul.spare)) * blockSize
+	for n := ul.ll.Front; n != nil; n = n.Next {
+		total += nodeSize + blockSize
+	}
+	return total
+}
+
+// MemoryFunc is like Memory, but also adds 'sizeOf' for every element, to
+// account for memory referenced by the elements.
+func (ul *UList[V]) MemoryFunc(sizeOf func(v V) int64) int64 {
+	total := ul.Memory()
+	for n := ul.ll.Front; n != nil; n = n.Next {
+		for _, v := range n.Value {
+			total += sizeOf(v)
+		}
+	}
+	return total
+}
+
 func hasCapacity[V any](llNode *list.Node[ulistBlk[V]]) bool {
 	if llNode == nil {
 		return false
@@ -137,22 +237,44 @@ func hasCapacity[V any](llNode *list.Node[ulistBlk[V]]) bool {
 }
 
 func (ul *UList[V]) newBlock() ulistBlk[V] {
+	if n := len(ul.spare); n > 0 {
+		blk := ul.spare[n-1]
+		ul.spare[n-1] = nil
+		ul.spare = ul.spare[:n-1]
+		return blk
+	}
 	return make([]V, 0, ul.entriesPerBlock)
 }
 
+// recycleBlock adds 'blk' to the spare pool if there is room. The contents of
+// the block are zeroed so that the pool does not retain any references.
+func (ul *UList[V]) recycleBlock(blk ulistBlk[V]) {
+	if len(ul.spare) >= ul.maxSpare {
+		return
+	}
+	blk = blk[:cap(blk)]
+	var zero V
+	for i := range blk {
+		blk[i] = zero
+	}
+	ul.spare = append(ul.spare, blk[:0])
+}
+
 func (ul *UList[V]) prependToBlock(v V, blkPtr *ulistBlk[V]) {
 	tmp := ul.newBlock()
 	tmp = append(tmp, v)
 	// 'append' returns a slice with capacity of the first variable.
 	// To maintain the propoer capacity, we use 'tmp' with an explicitly defined capacity.
-	*blkPtr = append(tmp, *blkPtr...)
+	old := *blkPtr
+	*blkPtr = append(tmp, old...)
+	ul.recycleBlock(old)
 }
 
 func (iter *UListIter[V]) addOverflowToNextBlock(ul *UList[V], v V) {
 	if hasCapacity(iter.node.Next) {
 		ul.prependToBlock(v, &iter.node.Next.Value)
 	} else {
-		newBlk := make([]V, 0, ul.entriesPerBlock)
+		newBlk := ul.newBlock()
 		newBlk = append(newBlk, v)
 		ul.ll.InsertAfter(iter.node, &list.Node[ulistBlk[V]]{
 			Value: newBlk,
@@ -183,7 +183,7 @@ func TestUListIterAdd(t *testing.T) {
 	checkEq(t, ul.Size(), expectedNumEntries)
 	checkEq(t, getNumUListEntries(ul), expectedNumEntries)
 	checkEq(t, getNumUListBlocks(ul), expectedNumBlocks)
-	checkEq(t, getSlice(ul), []int{0, 1, 2, 3, 4, 5, 6})
+	checkEq(t, ul.ToSlice(), []int{0, 1, 2, 3, 4, 5, 6})
 
 	// Add entries.
 	ul.AddAfter(iter, 7)
@@ -197,7 +197,7 @@ func TestUListIterAdd(t *testing.T) {
 	checkEq(t, ul.Size(), expectedNumEntries)
 	checkEq(t, getNumUListEntries(ul), expectedNumEntries)
 	checkEq(t, getNumUListBlocks(ul), expectedNumBlocks)
-	checkEq(t, getSlice(ul), []int{0, 1, 2, 3, 4, 5, 6, 7, 8})
+	checkEq(t, ul.ToSlice(), []int{0, 1, 2, 3, 4, 5, 6, 7, 8})
 
 	// Add to an already full block.
 	// ul: [0,-1,1,...,6] -> [7,8]
@@ -208,7 +208,7 @@ func TestUListIterAdd(t *testing.T) {
 	checkEq(t, ul.Size(), expectedNumEntries)
 	checkEq(t, getNumUListEntries(ul), expectedNumEntries)
 	checkEq(t, getNumUListBlocks(ul), expectedNumBlocks)
-	checkEq(t, getSlice(ul), []int{0, -1, 1, 2, 3, 4, 5, 6, 7, 8})
+	checkEq(t, ul.ToSlice(), []int{0, -1, 1, 2, 3, 4, 5, 6, 7, 8})
 
 	// Add to an already full block, such that the new element overflows.
 	// ul: [0,-1,1,...,6] -> [-6,7,8]
@@ -222,7 +222,7 @@ func TestUListIterAdd(t *testing.T) {
 	checkEq(t, ul.Size(), expectedNumEntries)
 	checkEq(t, getNumUListEntries(ul), expectedNumEntries)
 	checkEq(t, getNumUListBlocks(ul), expectedNumBlocks)
-	checkEq(t, getSlice(ul), []int{0, -1, 1, 2, 3, 4, 5, 6, -6, 7, 8})
+	checkEq(t, ul.ToSlice(), []int{0, -1, 1, 2, 3, 4, 5, 6, -6, 7, 8})
 
 	validateBlockCapacities(t, ul)
 
@@ -246,7 +246,7 @@ func TestUListIterAdd(t *testing.T) {
 	checkEq(t, ul.Size(), expectedNumEntries)
 	checkEq(t, getNumUListEntries(ul), expectedNumEntries)
 	checkEq(t, getNumUListBlocks(ul), expectedNumBlocks)
-	checkEq(t, getSlice(ul), []int{100, 0, -1, 111, 1, 2, 3, 4, 5, 6, -6, 7, 8})
+	checkEq(t, ul.ToSlice(), []int{100, 0, -1, 111, 1, 2, 3, 4, 5, 6, -6, 7, 8})
 
 	validateBlockCapacities(t, ul)
 }
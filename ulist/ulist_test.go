@@ -6,8 +6,42 @@ import (
 	"runtime/debug"
 	"testing"
 	"unsafe"
+
+	g "github.com/zyedidia/generic"
+	"github.com/zyedidia/generic/internal/testutil"
+	"github.com/zyedidia/generic/list"
 )
 
+func buildList(n int) *UList[int] {
+	ul := New[int](16)
+	for i := 0; i < n; i++ {
+		ul.PushBack(i)
+	}
+	return ul
+}
+
+func TestMemory(t *testing.T) {
+	nodeSize := int64(unsafe.Sizeof(list.Node[ulistBlk[int]]{}))
+	blockSize := 16 * int64(unsafe.Sizeof(0))
+	for _, n := range []int{0, 1, 1000, 100000} {
+		ul := buildList(n)
+		blocks := ul.ll.Len()
+		if blocks < (n+15)/16 || len(ul.spare) != 0 {
+			t.Fatalf("%d blocks and %d spare for %d elements", blocks, len(ul.spare), n)
+		}
+		want := int64(unsafe.Sizeof(*ul)) + int64(blocks)*(nodeSize+blockSize)
+		if got := ul.Memory(); got != want {
+			t.Fatalf("Memory() = %d for %d elements in %d blocks, want %d", got, n, blocks, want)
+		}
+	}
+}
+
+func BenchmarkMemory(b *testing.B) {
+	testutil.ReportMemory(b, func() *UList[int] {
+		return buildList(100000)
+	}, (*UList[int]).Memory)
+}
+
 func Example() {
 	// Ideally 'entriesPerBlock' is the size of a cache line (64) or multiples there of.
 	entriesPerBlock := int(64 / unsafe.Sizeof(int(0)))
@@ -65,8 +99,66 @@ func TestUList(t *testing.T) {
 	}
 
 	// Validate entries.
-	checkEq(t, getSlice(ul), []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	checkEq(t, ul.ToSlice(), []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	validateBlockCapacities(t, ul)
+}
+
+func TestUListSparePool(t *testing.T) {
+	entriesPerBlock := 4
+	ul := New[*int](entriesPerBlock)
+
+	old := make([]*int, 3*entriesPerBlock)
+	for i := range old {
+		old[i] = new(int)
+		ul.PushBack(old[i])
+	}
+	for iter := ul.Begin(); iter.IsValid(); {
+		ul.Remove(iter)
+	}
+	checkEq(t, ul.Size(), 0)
+	checkEq(t, len(ul.spare), defaultSparePool)
+
+	// Recycled blocks must not retain the removed pointers.
+	for _, blk := range ul.spare {
+		for _, p := range blk[:cap(blk)] {
+			checkEq(t, p, (*int)(nil))
+		}
+	}
+
+	for i := 0; i < 2*entriesPerBlock; i++ {
+		ul.PushBack(nil)
+	}
+	checkEq(t, len(ul.spare), 0)
 	validateBlockCapacities(t, ul)
+	for iter := ul.Begin(); iter.IsValid(); iter.Next() {
+		checkEq(t, iter.Get(), (*int)(nil))
+	}
+	ul.ll.Front.Each(func(blk ulistBlk[*int]) {
+		for _, p := range blk[:cap(blk)] {
+			checkEq(t, p, (*int)(nil))
+		}
+	})
+
+	ul.SetSparePool(0)
+	for iter := ul.Begin(); iter.IsValid(); {
+		ul.Remove(iter)
+	}
+	checkEq(t, len(ul.spare), 0)
+}
+
+func BenchmarkUListQueue(b *testing.B) {
+	entriesPerBlock := int(64 / unsafe.Sizeof(int(0)))
+	ul := New[int](entriesPerBlock)
+	for i := 0; i < 4*entriesPerBlock; i++ {
+		ul.PushBack(i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ul.PushBack(i)
+		ul.Remove(ul.Begin())
+	}
 }
 
 func checkEq[V any](t *testing.T, a V, b V) {
@@ -95,16 +187,6 @@ func getNumUListBlocks[V any](ul *UList[V]) int {
 	return ret
 }
 
-// Helper function to return ulist as a slice.
-func getSlice[V any](ul *UList[V]) []V {
-	ret := make([]V, 0)
-	mapper := func(val ulistBlk[V]) {
-		ret = append(ret, val...)
-	}
-	ul.ll.Front.Each(mapper)
-	return ret
-}
-
 // Helper function to check if all blocks in 'ul' are of the expected size.
 func validateBlockCapacities[V any](t *testing.T, ul *UList[V]) {
 	mapper := func(val ulistBlk[V]) {
@@ -112,3 +194,43 @@ func validateBlockCapacities[V any](t *testing.T, ul *UList[V]) {
 	}
 	ul.ll.Front.Each(mapper)
 }
+
+func TestEqual(t *testing.T) {
+	build := func(entriesPerBlock int) *UList[int] {
+		ul := New[int](entriesPerBlock)
+		for i := 0; i < 50; i++ {
+			if i%7 != 3 {
+				ul.PushBack(i)
+			}
+		}
+		// pushing to the front leaves some blocks partially full
+		for i := 0; i < 10; i++ {
+			ul.PushFront(-i)
+		}
+		return ul
+	}
+
+	a, b := build(3), build(16)
+	if getNumUListBlocks(a) == getNumUListBlocks(b) {
+		t.Fatal("the lists should have different block structures")
+	}
+	if !a.Equal(b, g.Equals[int]) || !b.Equal(a, g.Equals[int]) {
+		t.Fatalf("%v and %v should be equal", a.ToSlice(), b.ToSlice())
+	}
+	checkEq(t, a.ToSlice(), b.ToSlice())
+
+	// change a single element
+	c := build(5)
+	c.PushBack(1000)
+	a.PushBack(49)
+	if a.Equal(c, g.Equals[int]) || c.Equal(a, g.Equals[int]) {
+		t.Fatal("lists with a differing element should not be equal")
+	}
+
+	if a.Equal(b, g.Equals[int]) {
+		t.Fatal("lists of different sizes should not be equal")
+	}
+	if !New[int](4).Equal(New[int](8), g.Equals[int]) || len(New[int](4).ToSlice()) != 0 {
+		t.Fatal("empty lists should be equal")
+	}
+}
@@ -0,0 +1,88 @@
+// Package unionfind provides a disjoint-set data structure, which keeps track
+// of a partition of elements into sets, for example the connected components
+// of a graph as its edges are added. With union by rank and path compression,
+// each operation takes nearly constant amortized time.
+package unionfind
+
+// A DisjointSet partitions its elements into disjoint sets. Each set is
+// identified by one of its elements, its representative.
+type DisjointSet[T comparable] struct {
+	parent map[T]T
+	rank   map[T]int
+	sets   int
+}
+
+// New returns an empty disjoint set.
+func New[T comparable]() *DisjointSet[T] {
+	return &DisjointSet[T]{
+		parent: make(map[T]T),
+		rank:   make(map[T]int),
+	}
+}
+
+// MakeSet adds 'x' as the only element of a new set. It does nothing if 'x'
+// is already in a set.
+func (d *DisjointSet[T]) MakeSet(x T) {
+	if _, ok := d.parent[x]; ok {
+		return
+	}
+	d.parent[x] = x
+	d.sets++
+}
+
+// Find returns the representative of the set containing 'x', so two elements
+// are in the same set exactly when Find returns the same representative for
+// them. It panics if 'x' is not in a set.
+func (d *DisjointSet[T]) Find(x T) T {
+	root, ok := d.parent[x]
+	if !ok {
+		panic("unionfind: Find of an element that is not in a set")
+	}
+	for {
+		p := d.parent[root]
+		if p == root {
+			break
+		}
+		root = p
+	}
+	// path compression: point every element on the path at the root
+	for x != root {
+		x, d.parent[x] = d.parent[x], root
+	}
+	return root
+}
+
+// Union merges the sets containing 'a' and 'b', adding each of them as a new
+// set first if it is not in one.
+func (d *DisjointSet[T]) Union(a, b T) {
+	d.MakeSet(a)
+	d.MakeSet(b)
+	ra, rb := d.Find(a), d.Find(b)
+	if ra == rb {
+		return
+	}
+	// union by rank: attach the shallower tree under the deeper one
+	switch {
+	case d.rank[ra] < d.rank[rb]:
+		d.parent[ra] = rb
+	case d.rank[ra] > d.rank[rb]:
+		d.parent[rb] = ra
+	default:
+		d.parent[rb] = ra
+		d.rank[ra]++
+	}
+	d.sets--
+}
+
+// Connected returns whether 'a' and 'b' are in the same set. It returns false
+// if either is not in a set.
+func (d *DisjointSet[T]) Connected(a, b T) bool {
+	_, okA := d.parent[a]
+	_, okB := d.parent[b]
+	return okA && okB && d.Find(a) == d.Find(b)
+}
+
+// SetCount returns the number of disjoint sets.
+func (d *DisjointSet[T]) SetCount() int {
+	return d.sets
+}
@@ -0,0 +1,105 @@
+package unionfind_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/zyedidia/generic/unionfind"
+)
+
+func TestComponents(t *testing.T) {
+	d := unionfind.New[int]()
+	for i := 0; i < 12; i++ {
+		d.MakeSet(i)
+	}
+	d.MakeSet(0) // already in a set
+	if d.SetCount() != 12 {
+		t.Fatalf("SetCount() = %d, want 12", d.SetCount())
+	}
+
+	// components {0, 3, 6, 9}, {1, 4, 7, 10} and {2, 5, 8, 11}
+	for i := 3; i < 12; i++ {
+		d.Union(i, i-3)
+	}
+	if d.SetCount() != 3 {
+		t.Fatalf("SetCount() = %d, want 3", d.SetCount())
+	}
+	for a := 0; a < 12; a++ {
+		for b := 0; b < 12; b++ {
+			if got := d.Connected(a, b); got != (a%3 == b%3) {
+				t.Fatalf("Connected(%d, %d) = %v", a, b, got)
+			}
+		}
+		if d.Find(a) != d.Find(a%3) {
+			t.Fatalf("Find(%d) != Find(%d)", a, a%3)
+		}
+	}
+
+	d.Union(9, 10)
+	d.Union(0, 1) // already connected
+	if d.SetCount() != 2 || !d.Connected(3, 7) || d.Connected(3, 5) {
+		t.Fatal("wrong components after merging two of them")
+	}
+
+	// Union adds new elements, and unknown elements are not connected
+	d.Union(20, 21)
+	if d.SetCount() != 3 || !d.Connected(21, 20) || d.Connected(20, 0) || d.Connected(30, 30) {
+		t.Fatal("wrong components after adding new elements")
+	}
+}
+
+func TestRandom(t *testing.T) {
+	const n = 1000
+	d := unionfind.New[int]()
+	// label[i] is the component of i, maintained naively
+	label := make([]int, n)
+	for i := range label {
+		label[i] = i
+		d.MakeSet(i)
+	}
+	sets := n
+	for op := 0; op < 2000; op++ {
+		a, b := rand.Intn(n), rand.Intn(n)
+		if d.Connected(a, b) != (label[a] == label[b]) {
+			t.Fatalf("Connected(%d, %d) = %v", a, b, !(label[a] == label[b]))
+		}
+		d.Union(a, b)
+		if la, lb := label[a], label[b]; la != lb {
+			for i := range label {
+				if label[i] == lb {
+					label[i] = la
+				}
+			}
+			sets--
+		}
+		if d.SetCount() != sets {
+			t.Fatalf("SetCount() = %d, want %d", d.SetCount(), sets)
+		}
+	}
+}
+
+func TestFindPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Find of an unknown element did not panic")
+		}
+	}()
+	unionfind.New[string]().Find("a")
+}
+
+func Example() {
+	d := unionfind.New[string]()
+	d.Union("a", "b")
+	d.Union("c", "d")
+	d.Union("b", "c")
+	d.MakeSet("e")
+
+	fmt.Println(d.Connected("a", "d"))
+	fmt.Println(d.Connected("a", "e"))
+	fmt.Println(d.SetCount())
+	// Output:
+	// true
+	// false
+	// 2
+}
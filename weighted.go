@@ -0,0 +1,115 @@
+package generic
+
+import "math/rand"
+
+func checkWeights(nitems int, weights []float64) float64 {
+	if nitems != len(weights) {
+		panic("generic: items and weights have different lengths")
+	}
+	total := 0.0
+	for _, w := range weights {
+		if w < 0 {
+			panic("generic: negative weight")
+		}
+		total += w
+	}
+	return total
+}
+
+// WeightedChoice returns an element of 'items' chosen at random using 'rng',
+// where items[i] is chosen with probability proportional to weights[i]. It
+// returns false if there are no items or all weights are zero. It panics if
+// the slices have different lengths or a weight is negative. Complexity: O(n);
+// use an AliasSampler to draw repeatedly from the same items.
+func WeightedChoice[T any](items []T, weights []float64, rng *rand.Rand) (T, bool) {
+	total := checkWeights(len(items), weights)
+	if total <= 0 {
+		var t T
+		return t, false
+	}
+	r := rng.Float64() * total
+	last := 0
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		if r < w {
+			return items[i], true
+		}
+		r -= w
+		last = i
+	}
+	// rounding error can leave r slightly above the last weight
+	return items[last], true
+}
+
+// An AliasSampler draws elements from a fixed set of items with given weights
+// in O(1) time, using Vose's alias method.
+type AliasSampler[T any] struct {
+	items []T
+	// prob[i] is the probability of keeping column i rather than taking
+	// its alias.
+	prob  []float64
+	alias []int
+}
+
+// NewAliasSampler returns a sampler that draws items[i] with probability
+// proportional to weights[i]. It panics if the slices have different lengths,
+// a weight is negative, or there are no items with a positive weight.
+// Complexity: O(n).
+func NewAliasSampler[T any](items []T, weights []float64) *AliasSampler[T] {
+	total := checkWeights(len(items), weights)
+	if total <= 0 {
+		panic("generic: no items with a positive weight")
+	}
+
+	n := len(items)
+	s := &AliasSampler[T]{
+		items: items,
+		prob:  make([]float64, n),
+		alias: make([]int, n),
+	}
+
+	// scale the weights so that their average is 1, and split the columns
+	// into those below and above the average
+	scaled := make([]float64, n)
+	var small, large []int
+	for i, w := range weights {
+		scaled[i] = w * float64(n) / total
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+
+	// fill each small column up to 1 with part of a large column
+	for len(small) > 0 && len(large) > 0 {
+		l, g := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		s.prob[l] = scaled[l]
+		s.alias[l] = g
+		scaled[g] -= 1 - scaled[l]
+		if scaled[g] < 1 {
+			large = large[:len(large)-1]
+			small = append(small, g)
+		}
+	}
+	// the remaining columns are full, up to rounding error
+	for _, i := range large {
+		s.prob[i] = 1
+	}
+	for _, i := range small {
+		s.prob[i] = 1
+	}
+	return s
+}
+
+// Sample returns an item chosen at random using 'rng'.
+func (s *AliasSampler[T]) Sample(rng *rand.Rand) T {
+	i := rng.Intn(len(s.items))
+	if rng.Float64() < s.prob[i] {
+		return s.items[i]
+	}
+	return s.items[s.alias[i]]
+}
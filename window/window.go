@@ -0,0 +1,108 @@
+// Package window provides a counter of events per key over a rolling window
+// of time, for use in rate accounting and rate limiting.
+package window
+
+import "time"
+
+// A SlidingWindow counts events per key over the most recent window of time.
+// The window is divided into a ring of buckets of equal width. Events are
+// counted in the bucket covering the time they were added, and a bucket's
+// events age out all at once when the window moves past it, so counts are
+// accurate to the width of one bucket.
+type SlidingWindow[K comparable] struct {
+	buckets []bucket[K]
+	width   time.Duration
+	now     func() time.Time
+}
+
+type bucket[K comparable] struct {
+	// epoch is the index of the bucket-width interval since the Unix epoch
+	// that this bucket holds counts for.
+	epoch  int64
+	counts map[K]int
+}
+
+// An Option configures a SlidingWindow.
+type Option func(o *options)
+
+type options struct {
+	now func() time.Time
+}
+
+// WithClock sets the function the window uses to get the current time. The
+// default is time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// New returns a SlidingWindow that counts events over the last 'window' of
+// time, using 'buckets' buckets. More buckets make counts more precise at the
+// cost of a slower Count. It panics if 'buckets' is not positive or 'window'
+// is shorter than 'buckets' nanoseconds.
+func New[K comparable](window time.Duration, buckets int, opts ...Option) *SlidingWindow[K] {
+	if buckets <= 0 {
+		panic("window: number of buckets must be positive")
+	}
+	width := window / time.Duration(buckets)
+	if width <= 0 {
+		panic("window: window is too short for the number of buckets")
+	}
+	o := options{
+		now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &SlidingWindow[K]{
+		buckets: make([]bucket[K], buckets),
+		width:   width,
+		now:     o.now,
+	}
+}
+
+func (w *SlidingWindow[K]) epoch() int64 {
+	return w.now().UnixNano() / int64(w.width)
+}
+
+// live returns whether bucket 'b' is within the window ending at 'epoch'.
+func (w *SlidingWindow[K]) live(b *bucket[K], epoch int64) bool {
+	return b.counts != nil && b.epoch > epoch-int64(len(w.buckets))
+}
+
+// Add records an event for 'k' at the current time.
+func (w *SlidingWindow[K]) Add(k K) {
+	epoch := w.epoch()
+	n := int64(len(w.buckets))
+	b := &w.buckets[(epoch%n+n)%n]
+	if b.counts == nil || b.epoch != epoch {
+		b.epoch = epoch
+		b.counts = make(map[K]int)
+	}
+	b.counts[k]++
+}
+
+// Count returns the number of events recorded for 'k' within the window.
+func (w *SlidingWindow[K]) Count(k K) int {
+	epoch := w.epoch()
+	n := 0
+	for i := range w.buckets {
+		if b := &w.buckets[i]; w.live(b, epoch) {
+			n += b.counts[k]
+		}
+	}
+	return n
+}
+
+// Prune releases the memory held by buckets that have aged out of the window.
+// Aged-out buckets never contribute to Count, so calling Prune is only needed
+// to reclaim memory for keys that are no longer being added.
+func (w *SlidingWindow[K]) Prune() {
+	epoch := w.epoch()
+	for i := range w.buckets {
+		if b := &w.buckets[i]; !w.live(b, epoch) {
+			b.counts = nil
+		}
+	}
+}
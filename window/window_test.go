@@ -0,0 +1,82 @@
+package window_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zyedidia/generic/window"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func checkCount(t *testing.T, w *window.SlidingWindow[string], k string, want int) {
+	t.Helper()
+	if got := w.Count(k); got != want {
+		t.Fatalf("Count(%q) = %d, want %d", k, got, want)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	// one minute window, in buckets of 10 seconds
+	w := window.New[string](time.Minute, 6, window.WithClock(clock.now))
+
+	w.Add("a")
+	w.Add("a")
+	w.Add("b")
+	checkCount(t, w, "a", 2)
+	checkCount(t, w, "b", 1)
+	checkCount(t, w, "c", 0)
+
+	clock.advance(25 * time.Second)
+	w.Add("a")
+	checkCount(t, w, "a", 3)
+
+	// the first bucket ages out once the window has moved a full minute
+	// past it
+	clock.advance(30 * time.Second)
+	checkCount(t, w, "a", 3)
+	clock.advance(5 * time.Second)
+	checkCount(t, w, "a", 1)
+	checkCount(t, w, "b", 0)
+
+	// a bucket that is reused after wrapping around the ring starts empty
+	w.Add("b")
+	checkCount(t, w, "b", 1)
+
+	clock.advance(time.Hour)
+	checkCount(t, w, "a", 0)
+	checkCount(t, w, "b", 0)
+	w.Prune()
+	w.Add("a")
+	checkCount(t, w, "a", 1)
+}
+
+func TestNewPanics(t *testing.T) {
+	for _, tc := range []struct {
+		window  time.Duration
+		buckets int
+	}{
+		{time.Minute, 0},
+		{5, 10},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("New(%v, %d) did not panic", tc.window, tc.buckets)
+				}
+			}()
+			window.New[int](tc.window, tc.buckets)
+		}()
+	}
+}
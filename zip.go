@@ -0,0 +1,32 @@
+package generic
+
+// KV is a key-value pair.
+type KV[K, V any] struct {
+	Key K
+	Val V
+}
+
+// Zip pairs up keys[i] with vals[i]. It panics if 'keys' and 'vals' have
+// different lengths, since that is almost always a bug in the caller.
+func Zip[K, V any](keys []K, vals []V) []KV[K, V] {
+	if len(keys) != len(vals) {
+		panic("generic: keys and values have different lengths")
+	}
+	pairs := make([]KV[K, V], len(keys))
+	for i := range keys {
+		pairs[i] = KV[K, V]{keys[i], vals[i]}
+	}
+	return pairs
+}
+
+// Unzip splits 'pairs' into parallel slices of keys and values. It is the
+// inverse of Zip.
+func Unzip[K, V any](pairs []KV[K, V]) ([]K, []V) {
+	keys := make([]K, len(pairs))
+	vals := make([]V, len(pairs))
+	for i, p := range pairs {
+		keys[i] = p.Key
+		vals[i] = p.Val
+	}
+	return keys, vals
+}